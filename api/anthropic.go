@@ -0,0 +1,373 @@
+package api
+
+import (
+	"asione-agent/types"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// anthropicVersion est la version de l'API Messages utilisée
+const anthropicVersion = "2023-06-01"
+
+// anthropicProvider est l'adaptateur de l'API Messages d'Anthropic : clé dans
+// l'en-tête x-api-key, prompt système séparé des messages et contenu en blocs
+type anthropicProvider struct{}
+
+// anthropicRequest représente le corps d'une requête /messages
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	System      string             `json:"system,omitempty"`
+	Messages    []anthropicMessage `json:"messages"`
+	MaxTokens   int                `json:"max_tokens"`
+	Temperature *float64           `json:"temperature,omitempty"`
+	TopP        *float64           `json:"top_p,omitempty"`
+	Stop        []string           `json:"stop_sequences,omitempty"`
+	Stream      bool               `json:"stream,omitempty"`
+	Tools       []anthropicTool    `json:"tools,omitempty"`
+}
+
+// anthropicMessage représente un message composé de blocs de contenu
+type anthropicMessage struct {
+	Role    string           `json:"role"`
+	Content []anthropicBlock `json:"content"`
+}
+
+// anthropicBlock représente un bloc de contenu (text, image, tool_use ou tool_result)
+type anthropicBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	Source    *anthropicImage `json:"source,omitempty"`
+}
+
+// anthropicImage représente la source d'un bloc image (base64 ou adresse)
+type anthropicImage struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// anthropicTool représente la déclaration d'un outil
+type anthropicTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+// anthropicUsage représente la consommation de tokens
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// anthropicResponse représente la réponse de /messages
+type anthropicResponse struct {
+	ID         string           `json:"id"`
+	Model      string           `json:"model"`
+	Content    []anthropicBlock `json:"content"`
+	StopReason string           `json:"stop_reason"`
+	Usage      anthropicUsage   `json:"usage"`
+}
+
+// Name implémente Provider
+func (anthropicProvider) Name() string {
+	return ProviderAnthropic
+}
+
+// NewChatRequest implémente Provider
+func (anthropicProvider) NewChatRequest(ctx context.Context, target Target, request *types.ChatRequest) (*http.Request, error) {
+	body := anthropicRequest{
+		Model:       request.Model,
+		MaxTokens:   request.MaxTokens,
+		Temperature: request.Temperature,
+		TopP:        request.TopP,
+		Stop:        request.Stop,
+		Stream:      request.Stream,
+	}
+	body.System, body.Messages = toAnthropicMessages(request.Messages)
+	for _, tool := range request.Tools {
+		schema := tool.Function.Parameters
+		if len(schema) == 0 {
+			schema = json.RawMessage(`{"type":"object","properties":{}}`)
+		}
+		body.Tools = append(body.Tools, anthropicTool{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			InputSchema: schema,
+		})
+	}
+
+	req, err := newJSONRequest(ctx, "POST", target.BaseURL+"/messages", body)
+	if err != nil {
+		return nil, err
+	}
+	setAnthropicHeaders(req, target)
+	return req, nil
+}
+
+// setAnthropicHeaders ajoute l'authentification et la version de l'API
+func setAnthropicHeaders(req *http.Request, target Target) {
+	req.Header.Set("x-api-key", target.APIKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+}
+
+// toAnthropicMessages traduit l'historique : les messages système sont regroupés
+// dans le prompt système, les résultats d'outils deviennent des blocs tool_result
+// d'un message utilisateur et les messages consécutifs d'un même rôle sont fusionnés
+func toAnthropicMessages(messages []types.Message) (string, []anthropicMessage) {
+	var system []string
+	var result []anthropicMessage
+
+	appendBlocks := func(role string, blocks ...anthropicBlock) {
+		if len(blocks) == 0 {
+			return
+		}
+		if n := len(result); n > 0 && result[n-1].Role == role {
+			result[n-1].Content = append(result[n-1].Content, blocks...)
+			return
+		}
+		result = append(result, anthropicMessage{Role: role, Content: blocks})
+	}
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case "system":
+			system = append(system, msg.Content)
+		case "tool":
+			appendBlocks("user", anthropicBlock{Type: "tool_result", ToolUseID: msg.ToolCallID, Content: msg.Content})
+		case "assistant":
+			var blocks []anthropicBlock
+			if strings.TrimSpace(msg.Content) != "" {
+				blocks = append(blocks, anthropicBlock{Type: "text", Text: msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				input := json.RawMessage(call.Function.Arguments)
+				if !json.Valid(input) {
+					input = json.RawMessage(`{}`)
+				}
+				blocks = append(blocks, anthropicBlock{Type: "tool_use", ID: call.ID, Name: call.Function.Name, Input: input})
+			}
+			appendBlocks("assistant", blocks...)
+		default:
+			if len(msg.Parts) > 0 {
+				appendBlocks("user", anthropicParts(msg.Parts)...)
+			} else if strings.TrimSpace(msg.Content) != "" {
+				appendBlocks("user", anthropicBlock{Type: "text", Text: msg.Content})
+			}
+		}
+	}
+
+	return strings.Join(system, "\n\n"), result
+}
+
+// anthropicParts traduit les parties d'un message multimodal en blocs
+func anthropicParts(parts []types.ContentPart) []anthropicBlock {
+	var blocks []anthropicBlock
+	for _, part := range parts {
+		switch {
+		case part.Type == types.PartText && strings.TrimSpace(part.Text) != "":
+			blocks = append(blocks, anthropicBlock{Type: "text", Text: part.Text})
+		case part.Type == types.PartImage && part.ImageURL != nil:
+			source := &anthropicImage{Type: "url", URL: part.ImageURL.URL}
+			if mediaType, data, ok := parseDataURL(part.ImageURL.URL); ok {
+				source = &anthropicImage{Type: "base64", MediaType: mediaType, Data: data}
+			}
+			blocks = append(blocks, anthropicBlock{Type: "image", Source: source})
+		}
+	}
+	return blocks
+}
+
+// DecodeResponse implémente Provider
+func (anthropicProvider) DecodeResponse(body []byte) (*types.ChatResponse, error) {
+	var resp anthropicResponse
+	if err := decodeJSON(body, &resp); err != nil {
+		return nil, err
+	}
+
+	message := types.Message{Role: "assistant"}
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "text":
+			message.Content += block.Text
+		case "tool_use":
+			message.ToolCalls = append(message.ToolCalls, types.ToolCall{
+				ID:       block.ID,
+				Type:     "function",
+				Function: types.FunctionCall{Name: block.Name, Arguments: string(block.Input)},
+			})
+		}
+	}
+
+	return &types.ChatResponse{
+		ID:      resp.ID,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   resp.Model,
+		Choices: []types.Choice{{
+			Message:      message,
+			FinishReason: anthropicFinishReason(resp.StopReason),
+		}},
+		Usage: types.Usage{
+			PromptTokens:     resp.Usage.InputTokens,
+			CompletionTokens: resp.Usage.OutputTokens,
+			TotalTokens:      resp.Usage.InputTokens + resp.Usage.OutputTokens,
+		},
+	}, nil
+}
+
+// anthropicFinishReason traduit la raison d'arrêt au format OpenAI
+func anthropicFinishReason(reason string) string {
+	switch reason {
+	case "end_turn", "stop_sequence":
+		return "stop"
+	case "tool_use":
+		return "tool_calls"
+	case "max_tokens":
+		return "length"
+	}
+	return reason
+}
+
+// anthropicEvent représente un événement du flux de /messages
+type anthropicEvent struct {
+	Type         string            `json:"type"`
+	Index        int               `json:"index"`
+	Message      anthropicResponse `json:"message"`
+	ContentBlock anthropicBlock    `json:"content_block"`
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage anthropicUsage `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// DecodeStream implémente Provider. Chaque événement est converti en fragment
+// au format OpenAI pour réutiliser l'accumulateur commun.
+func (anthropicProvider) DecodeStream(resp *http.Response, onDelta func(string)) (*types.ChatResponse, error) {
+	acc := newStreamAccumulator()
+	var usage types.Usage
+
+	// Les blocs tool_use sont numérotés parmi tous les blocs de contenu ;
+	// les appels d'outils sont renumérotés à partir de 0
+	toolIndex := make(map[int]int)
+
+	err := readEventStream(resp.Body, func(data []byte) error {
+		var event anthropicEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return fmt.Errorf("erreur lors de la désérialisation du flux: %w - %s", err, string(data))
+		}
+
+		chunk := types.ChatStreamChunk{Choices: []types.StreamChoice{{}}}
+		delta := &chunk.Choices[0].Delta
+
+		switch event.Type {
+		case "message_start":
+			chunk.ID = event.Message.ID
+			chunk.Model = event.Message.Model
+			chunk.Created = time.Now().Unix()
+			usage.PromptTokens = event.Message.Usage.InputTokens
+		case "content_block_start":
+			if event.ContentBlock.Type != "tool_use" {
+				return nil
+			}
+			idx := len(toolIndex)
+			toolIndex[event.Index] = idx
+			delta.ToolCalls = []types.ToolCall{{
+				Index:    &idx,
+				ID:       event.ContentBlock.ID,
+				Type:     "function",
+				Function: types.FunctionCall{Name: event.ContentBlock.Name},
+			}}
+		case "content_block_delta":
+			switch event.Delta.Type {
+			case "text_delta":
+				delta.Content = event.Delta.Text
+			case "input_json_delta":
+				idx, ok := toolIndex[event.Index]
+				if !ok {
+					return nil
+				}
+				delta.ToolCalls = []types.ToolCall{{
+					Index:    &idx,
+					Function: types.FunctionCall{Arguments: event.Delta.PartialJSON},
+				}}
+			}
+		case "message_delta":
+			chunk.Choices[0].FinishReason = anthropicFinishReason(event.Delta.StopReason)
+			usage.CompletionTokens = event.Usage.OutputTokens
+		case "error":
+			e := &APIError{Type: event.Error.Type, Message: event.Error.Message}
+			e.Kind = classify(e)
+			return e
+		default:
+			// ping, content_block_stop, message_stop
+			return nil
+		}
+
+		text, err := acc.add(&chunk)
+		if err != nil {
+			return err
+		}
+		if text != "" {
+			onDelta(text)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := acc.response()
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	result.Usage = usage
+	return result, nil
+}
+
+// NewModelsRequest implémente Provider
+func (anthropicProvider) NewModelsRequest(ctx context.Context, target Target) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", target.BaseURL+"/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("erreur lors de la création de la requête: %w", err)
+	}
+	setAnthropicHeaders(req, target)
+	return req, nil
+}
+
+// DecodeModels implémente Provider
+func (anthropicProvider) DecodeModels(body []byte) (*types.ModelsResponse, error) {
+	var resp struct {
+		Data []struct {
+			ID        string    `json:"id"`
+			CreatedAt time.Time `json:"created_at"`
+		} `json:"data"`
+	}
+	if err := decodeJSON(body, &resp); err != nil {
+		return nil, err
+	}
+
+	models := &types.ModelsResponse{Object: "list"}
+	for _, m := range resp.Data {
+		model := types.Model{ID: m.ID, Object: "model", OwnedBy: "anthropic"}
+		if !m.CreatedAt.IsZero() {
+			model.Created = m.CreatedAt.Unix()
+		}
+		models.Data = append(models.Data, model)
+	}
+	return models, nil
+}
@@ -0,0 +1,235 @@
+package api
+
+import (
+	"asione-agent/types"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAnthropicChatRequest(t *testing.T) {
+	srv, got := newTestServer(t, http.StatusOK, "application/json", `{
+		"id": "msg_1",
+		"type": "message",
+		"role": "assistant",
+		"model": "modele-test",
+		"content": [
+			{"type": "text", "text": "Je regarde."},
+			{"type": "tool_use", "id": "toolu_1", "name": "run", "input": {"cmd": "ls"}}
+		],
+		"stop_reason": "tool_use",
+		"usage": {"input_tokens": 20, "output_tokens": 7}
+	}`)
+
+	c := newTestClient(t, srv.URL, ProviderAnthropic)
+	messages := []types.Message{
+		{Role: "system", Content: "Tu es un assistant."},
+		{Role: "system", Content: "Réponds en français."},
+		types.NewMultipartMessage("user", []types.ContentPart{
+			{Type: types.PartText, Text: "Que montre cette image ?"},
+			{Type: types.PartImage, ImageURL: &types.ImageURL{URL: "data:image/png;base64,iVBORw0KGgo="}},
+		}),
+		{Role: "assistant", ToolCalls: []types.ToolCall{{ID: "toolu_0", Type: "function",
+			Function: types.FunctionCall{Name: "run", Arguments: `{"cmd":"pwd"}`}}}},
+		{Role: "tool", ToolCallID: "toolu_0", Content: "/home"},
+		{Role: "user", Content: "Et maintenant ?"},
+	}
+	opts := ChatOptions{MaxTokens: 512, Stop: []string{"FIN"}, Tools: []types.Tool{{Type: "function",
+		Function: types.FunctionDefinition{Name: "run", Description: "Exécute une commande"}}}}
+	resp, err := c.ChatCompletion(context.Background(), messages, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.path != "/messages" {
+		t.Errorf("chemin %s, /messages attendu", got.path)
+	}
+	if key := got.header.Get("x-api-key"); key != "sk-test" {
+		t.Errorf("x-api-key = %q", key)
+	}
+	if got.header.Get("anthropic-version") == "" {
+		t.Errorf("en-tête anthropic-version manquant")
+	}
+	if auth := got.header.Get("Authorization"); auth != "" {
+		t.Errorf("Authorization ne doit pas être envoyé : %q", auth)
+	}
+	if got.body["system"] != "Tu es un assistant.\n\nRéponds en français." {
+		t.Errorf("system = %q", got.body["system"])
+	}
+	if got.body["max_tokens"] != float64(512) {
+		t.Errorf("max_tokens = %v", got.body["max_tokens"])
+	}
+	if stop := got.body["stop_sequences"].([]interface{}); len(stop) != 1 || stop[0] != "FIN" {
+		t.Errorf("stop_sequences = %v", stop)
+	}
+	tools := got.body["tools"].([]interface{})
+	if schema := tools[0].(map[string]interface{})["input_schema"]; schema == nil {
+		t.Errorf("input_schema manquant pour un outil sans paramètres")
+	}
+
+	// Rôles alternés : le résultat d'outil et la question suivante forment un seul message utilisateur
+	sent := got.body["messages"].([]interface{})
+	roles := make([]string, len(sent))
+	for i, m := range sent {
+		roles[i] = m.(map[string]interface{})["role"].(string)
+	}
+	if strings.Join(roles, ",") != "user,assistant,user" {
+		t.Fatalf("rôles = %v", roles)
+	}
+
+	blockTypes := func(i int) string {
+		var kinds []string
+		for _, b := range sent[i].(map[string]interface{})["content"].([]interface{}) {
+			kinds = append(kinds, b.(map[string]interface{})["type"].(string))
+		}
+		return strings.Join(kinds, ",")
+	}
+	if kinds := blockTypes(0); kinds != "text,image" {
+		t.Errorf("blocs du premier message = %s", kinds)
+	}
+	if kinds := blockTypes(1); kinds != "tool_use" {
+		t.Errorf("blocs du deuxième message = %s", kinds)
+	}
+	if kinds := blockTypes(2); kinds != "tool_result,text" {
+		t.Errorf("blocs du troisième message = %s", kinds)
+	}
+
+	image := sent[0].(map[string]interface{})["content"].([]interface{})[1].(map[string]interface{})["source"].(map[string]interface{})
+	if image["type"] != "base64" || image["media_type"] != "image/png" || image["data"] != "iVBORw0KGgo=" {
+		t.Errorf("source de l'image = %v", image)
+	}
+	toolUse := sent[1].(map[string]interface{})["content"].([]interface{})[0].(map[string]interface{})
+	if input, _ := json.Marshal(toolUse["input"]); string(input) != `{"cmd":"pwd"}` {
+		t.Errorf("input = %s", input)
+	}
+	toolResult := sent[2].(map[string]interface{})["content"].([]interface{})[0].(map[string]interface{})
+	if toolResult["tool_use_id"] != "toolu_0" || toolResult["content"] != "/home" {
+		t.Errorf("tool_result = %v", toolResult)
+	}
+
+	// Réponse traduite au format OpenAI
+	msg := resp.Choices[0].Message
+	if msg.Content != "Je regarde." || len(msg.ToolCalls) != 1 {
+		t.Fatalf("message = %+v", msg)
+	}
+	if call := msg.ToolCalls[0]; call.ID != "toolu_1" || call.Function.Name != "run" || call.Function.Arguments != `{"cmd": "ls"}` {
+		t.Errorf("appel d'outil = %+v", call)
+	}
+	if resp.Choices[0].FinishReason != "tool_calls" {
+		t.Errorf("finish_reason = %q", resp.Choices[0].FinishReason)
+	}
+	if resp.Usage.PromptTokens != 20 || resp.Usage.CompletionTokens != 7 || resp.Usage.TotalTokens != 27 {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+}
+
+func TestAnthropicStream(t *testing.T) {
+	stream := strings.Join([]string{
+		`event: message_start`,
+		`data: {"type":"message_start","message":{"id":"msg_1","model":"modele-test","content":[],"usage":{"input_tokens":15,"output_tokens":1}}}`,
+		``,
+		`event: content_block_start`,
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		``,
+		`event: ping`,
+		`data: {"type":"ping"}`,
+		``,
+		`event: content_block_delta`,
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Bon"}}`,
+		``,
+		`event: content_block_delta`,
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"jour"}}`,
+		``,
+		`event: content_block_stop`,
+		`data: {"type":"content_block_stop","index":0}`,
+		``,
+		`event: content_block_start`,
+		`data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"run","input":{}}}`,
+		``,
+		`event: content_block_delta`,
+		`data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"cmd\":"}}`,
+		``,
+		`event: content_block_delta`,
+		`data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"ls\"}"}}`,
+		``,
+		`event: message_delta`,
+		`data: {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":9}}`,
+		``,
+		`event: message_stop`,
+		`data: {"type":"message_stop"}`,
+		``,
+	}, "\n")
+	srv, got := newTestServer(t, http.StatusOK, "text/event-stream", stream)
+
+	c := newTestClient(t, srv.URL, ProviderAnthropic)
+	var deltas []string
+	resp, err := c.ChatCompletionStream(context.Background(), []types.Message{{Role: "user", Content: "Salut"}}, ChatOptions{},
+		func(text string) { deltas = append(deltas, text) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.body["stream"] != true {
+		t.Errorf("stream = %v, true attendu", got.body["stream"])
+	}
+	if _, ok := got.body["stream_options"]; ok {
+		t.Errorf("stream_options n'existe pas dans l'API Messages")
+	}
+	if strings.Join(deltas, "|") != "Bon|jour" {
+		t.Errorf("fragments = %q", deltas)
+	}
+
+	msg := resp.Choices[0].Message
+	if msg.Content != "Bonjour" {
+		t.Errorf("contenu = %q", msg.Content)
+	}
+	if len(msg.ToolCalls) != 1 || msg.ToolCalls[0].ID != "toolu_1" || msg.ToolCalls[0].Function.Arguments != `{"cmd":"ls"}` {
+		t.Errorf("appels d'outils = %+v", msg.ToolCalls)
+	}
+	if resp.Choices[0].FinishReason != "tool_calls" {
+		t.Errorf("finish_reason = %q", resp.Choices[0].FinishReason)
+	}
+	if resp.Usage.PromptTokens != 15 || resp.Usage.CompletionTokens != 9 || resp.Usage.TotalTokens != 24 {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+}
+
+func TestAnthropicErrors(t *testing.T) {
+	t.Run("clé invalide", func(t *testing.T) {
+		srv, _ := newTestServer(t, http.StatusUnauthorized, "application/json",
+			`{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`)
+		c := newTestClient(t, srv.URL, ProviderAnthropic)
+		_, err := c.ChatCompletion(context.Background(), []types.Message{{Role: "user", Content: "Salut"}}, ChatOptions{})
+		apiErr := asAPIError(t, err)
+		if apiErr.Kind != KindAuth || apiErr.Type != "authentication_error" || apiErr.Message != "invalid x-api-key" {
+			t.Errorf("erreur = %+v", apiErr)
+		}
+	})
+
+	t.Run("surcharge", func(t *testing.T) {
+		srv, _ := newTestServer(t, 529, "application/json",
+			`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`)
+		c := newTestClient(t, srv.URL, ProviderAnthropic)
+		_, err := c.ChatCompletion(context.Background(), []types.Message{{Role: "user", Content: "Salut"}}, ChatOptions{})
+		apiErr := asAPIError(t, err)
+		if apiErr.Kind != KindOverloaded || !apiErr.Retryable() {
+			t.Errorf("erreur = %+v", apiErr)
+		}
+	})
+
+	t.Run("erreur dans le flux", func(t *testing.T) {
+		stream := "event: message_start\n" +
+			`data: {"type":"message_start","message":{"id":"msg_1","model":"modele-test","usage":{"input_tokens":3}}}` + "\n\n" +
+			"event: error\n" +
+			`data: {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}` + "\n\n"
+		srv, _ := newTestServer(t, http.StatusOK, "text/event-stream", stream)
+		c := newTestClient(t, srv.URL, ProviderAnthropic)
+		_, err := c.ChatCompletionStream(context.Background(), []types.Message{{Role: "user", Content: "Salut"}}, ChatOptions{}, nil)
+		apiErr := asAPIError(t, err)
+		if apiErr.Kind != KindOverloaded || apiErr.Message != "Overloaded" {
+			t.Errorf("erreur = %+v", apiErr)
+		}
+	})
+}
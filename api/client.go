@@ -2,119 +2,197 @@ package api
 
 import (
 	"asione-agent/types"
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
-	"strconv"
 )
 
-// Client gère les appels à l'API OpenAI compatible
+// Client gère les appels à l'API du fournisseur de modèles. Les appels sont
+// adressés à la cible principale puis, en cas d'échec passager persistant, aux
+// cibles de repli ; chaque cible est traduite par l'adaptateur de son fournisseur.
 type Client struct {
 	httpClient *http.Client
-	baseURL    string
-	apiKey     string
-	model      string
+	primary    Target
+	fallbacks  []Target
+	last       Target
+	retry      RetryPolicy
+
+	// Adresses des API compatibles OpenAI qui refusent stream_options
+	noStreamOptions map[string]bool
 }
 
 // NewClient crée un nouveau client API
 func NewClient(baseURL, apiKey, model string) *Client {
+	primary := Target{BaseURL: baseURL, APIKey: apiKey, Model: model}
 	return &Client{
 		httpClient: &http.Client{},
-		baseURL:    baseURL,
-		apiKey:     apiKey,
-		model:      model,
+		primary:    primary,
+		last:       primary,
+		retry:      DefaultRetryPolicy(),
 	}
 }
 
-// SetCredentials met à jour les informations d'authentification
+// SetRetryPolicy définit la politique de nouvelles tentatives
+func (c *Client) SetRetryPolicy(policy RetryPolicy) {
+	c.retry = policy
+}
+
+// SetCredentials met à jour les informations d'authentification de la cible principale
 func (c *Client) SetCredentials(baseURL, apiKey, model string) {
-	c.baseURL = baseURL
-	c.apiKey = apiKey
-	c.model = model
+	c.primary.BaseURL = baseURL
+	c.primary.APIKey = apiKey
+	c.primary.Model = model
+	c.last = c.primary
 }
 
-// ChatCompletion effectue un appel de complétion de chat
-func (c *Client) ChatCompletion(ctx context.Context, messages []types.Message) (*types.ChatResponse, error) {
-	// Utiliser la valeur MAX_TOKENS depuis .env comme limite supérieure
-	// mais permettre au modèle de retourner des réponses complètes sans troncature
-	maxTokens := 8192
-	if val, exists := os.LookupEnv("MAX_TOKENS"); exists {
-		if parsedVal, err := strconv.Atoi(val); err == nil {
-			maxTokens = parsedVal
-		}
+// SetProvider définit le fournisseur de la cible principale (openai, anthropic, ollama)
+func (c *Client) SetProvider(name string) error {
+	if _, err := ProviderFor(name); err != nil {
+		return err
 	}
+	c.primary.Provider = name
+	c.last = c.primary
+	return nil
+}
 
-	// Construction de la requête
-	requestBody := types.ChatRequest{
-		Model:       c.model,
-		Messages:    messages,
-		MaxTokens:   maxTokens,
-		Temperature: 0.7,
-	}
+// ChatCompletion effectue un appel de complétion de chat avec les paramètres de génération donnés.
+// Les outils des options sont déclarés au modèle, qui peut alors répondre par des appels d'outils
+func (c *Client) ChatCompletion(ctx context.Context, messages []types.Message, opts ChatOptions) (*types.ChatResponse, error) {
+	var provider Provider
 
-	// Sérialisation du corps de la requête
-	jsonBody, err := json.Marshal(requestBody)
+	// Envoi de la requête, renouvelée en cas d'échec passager
+	resp, err := c.sendChain(ctx, func(target Target) (*http.Request, error) {
+		p, err := ProviderFor(target.Provider)
+		if err != nil {
+			return nil, err
+		}
+		provider = p
+		return p.NewChatRequest(ctx, target, newChatRequest(target, messages, opts, false))
+	})
 	if err != nil {
-		return nil, fmt.Errorf("erreur lors de la sérialisation de la requête: %w", err)
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	// Création de la requête HTTP
-	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/chat/completions", bytes.NewBuffer(jsonBody))
+	// Lecture du corps de la réponse
+	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("erreur lors de la création de la requête: %w", err)
+		return nil, fmt.Errorf("erreur lors de la lecture de la réponse: %w", err)
 	}
 
-	// Définition des headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	// Désérialisation de la réponse
+	return provider.DecodeResponse(respBody)
+}
 
-	// Envoi de la requête
-	resp, err := c.httpClient.Do(req)
+// ChatCompletionStream effectue un appel de complétion de chat en streaming.
+// onDelta est appelé pour chaque fragment de texte reçu ; la réponse complète
+// est reconstituée (y compris les appels d'outils) et retournée une fois le flux terminé.
+func (c *Client) ChatCompletionStream(ctx context.Context, messages []types.Message, opts ChatOptions, onDelta func(string)) (*types.ChatResponse, error) {
+	var provider Provider
+	var sent Target
+
+	// Envoi de la requête, renouvelée en cas d'échec passager. Une fois le flux
+	// commencé, une interruption n'est plus rattrapable sans dupliquer la réponse.
+	build := func(target Target) (*http.Request, error) {
+		p, err := ProviderFor(target.Provider)
+		if err != nil {
+			return nil, err
+		}
+		provider, sent = p, target
+		request := newChatRequest(target, messages, opts, true)
+		if c.noStreamOptions[target.BaseURL] {
+			request.StreamOptions = nil
+		}
+		return p.NewChatRequest(ctx, target, request)
+	}
+	resp, err := c.sendChain(ctx, build)
+
+	// Certaines API compatibles OpenAI refusent stream_options : la requête est
+	// renouvelée une fois sans, et l'adresse est mémorisée si cela réussit
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest &&
+		provider != nil && provider.Name() == ProviderOpenAI && !c.noStreamOptions[sent.BaseURL] {
+		if c.noStreamOptions == nil {
+			c.noStreamOptions = make(map[string]bool)
+		}
+		baseURL := sent.BaseURL
+		c.noStreamOptions[baseURL] = true
+		if resp, err = c.sendChain(ctx, build); err != nil {
+			delete(c.noStreamOptions, baseURL)
+		}
+	}
 	if err != nil {
-		return nil, fmt.Errorf("erreur lors de l'envoi de la requête: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	// Lecture du corps de la réponse
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("erreur lors de la lecture de la réponse: %w", err)
+	if onDelta == nil {
+		onDelta = func(string) {}
 	}
+	return provider.DecodeStream(resp, onDelta)
+}
 
-	// Vérification du statut HTTP
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("erreur API: %s (code: %d) - %s", resp.Status, resp.StatusCode, string(respBody))
+// newChatRequest construit la requête de complétion de chat, au format OpenAI,
+// que l'adaptateur du fournisseur traduit ensuite
+func newChatRequest(target Target, messages []types.Message, opts ChatOptions, stream bool) *types.ChatRequest {
+	maxTokens := opts.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = DefaultMaxTokens
 	}
 
-	// Désérialisation de la réponse
-	var chatResp types.ChatResponse
-	if err := json.Unmarshal(respBody, &chatResp); err != nil {
-		return nil, fmt.Errorf("erreur lors de la désérialisation de la réponse: %w - %s", err, string(respBody))
+	// Construction de la requête
+	request := &types.ChatRequest{
+		Model:            target.Model,
+		Messages:         withoutSources(messages),
+		MaxTokens:        maxTokens,
+		Temperature:      opts.Temperature,
+		TopP:             opts.TopP,
+		Stop:             opts.Stop,
+		Seed:             opts.Seed,
+		PresencePenalty:  opts.PresencePenalty,
+		FrequencyPenalty: opts.FrequencyPenalty,
+		ResponseFormat:   opts.ResponseFormat,
+		Stream:           stream,
+		Tools:            opts.Tools,
+	}
+	if stream {
+		// Demander le décompte des tokens dans le dernier fragment du flux
+		request.StreamOptions = &types.StreamOptions{IncludeUsage: true}
 	}
+	return request
+}
 
-	return &chatResp, nil
+// withoutSources retire les sources des messages, propres à l'historique de la
+// session et inconnues des fournisseurs
+func withoutSources(messages []types.Message) []types.Message {
+	for i, msg := range messages {
+		if len(msg.Sources) == 0 {
+			continue
+		}
+		stripped := append([]types.Message{}, messages...)
+		for j := i; j < len(stripped); j++ {
+			stripped[j].Sources = nil
+		}
+		return stripped
+	}
+	return messages
 }
 
-// ListModels récupère la liste des modèles disponibles
+// ListModels récupère la liste des modèles disponibles auprès de la cible principale
 func (c *Client) ListModels(ctx context.Context) (*types.ModelsResponse, error) {
-	// Création de la requête HTTP
-	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/models", nil)
+	provider, err := ProviderFor(c.primary.Provider)
 	if err != nil {
-		return nil, fmt.Errorf("erreur lors de la création de la requête: %w", err)
+		return nil, err
 	}
 
-	// Définition des headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
-
-	// Envoi de la requête
-	resp, err := c.httpClient.Do(req)
+	// Envoi de la requête, renouvelée en cas d'échec passager
+	resp, err := c.send(ctx, func() (*http.Request, error) {
+		return provider.NewModelsRequest(ctx, c.primary)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("erreur lors de l'envoi de la requête: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
@@ -124,16 +202,6 @@ func (c *Client) ListModels(ctx context.Context) (*types.ModelsResponse, error)
 		return nil, fmt.Errorf("erreur lors de la lecture de la réponse: %w", err)
 	}
 
-	// Vérification du statut HTTP
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("erreur API: %s (code: %d) - %s", resp.Status, resp.StatusCode, string(respBody))
-	}
-
 	// Désérialisation de la réponse
-	var modelsResp types.ModelsResponse
-	if err := json.Unmarshal(respBody, &modelsResp); err != nil {
-		return nil, fmt.Errorf("erreur lors de la désérialisation de la réponse: %w - %s", err, string(respBody))
-	}
-
-	return &modelsResp, nil
+	return provider.DecodeModels(respBody)
 }
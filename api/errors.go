@@ -0,0 +1,233 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrorKind classe les erreurs d'API selon la conduite à tenir
+type ErrorKind string
+
+const (
+	// KindRateLimited : trop de requêtes (HTTP 429)
+	KindRateLimited ErrorKind = "rate_limited"
+	// KindOverloaded : service temporairement surchargé ou indisponible (HTTP 503, 529)
+	KindOverloaded ErrorKind = "overloaded"
+	// KindAuth : clé API absente, invalide ou sans les droits nécessaires (HTTP 401, 403)
+	KindAuth ErrorKind = "auth"
+	// KindBadRequest : requête refusée par le fournisseur (autres codes 4xx)
+	KindBadRequest ErrorKind = "bad_request"
+	// KindServer : erreur interne du fournisseur (autres codes 5xx)
+	KindServer ErrorKind = "server"
+	// KindNetwork : connexion impossible, interrompue ou expirée
+	KindNetwork ErrorKind = "network"
+	// KindQuota : crédit ou quota du compte épuisé
+	KindQuota ErrorKind = "quota"
+)
+
+// APIError représente l'échec d'un appel à l'API. Les champs Code, Type, Param
+// et Message proviennent de l'enveloppe d'erreur OpenAI lorsqu'elle est présente :
+// {"error": {"message": "...", "type": "...", "param": "...", "code": "..."}}
+type APIError struct {
+	// Code HTTP de la réponse (0 pour une erreur réseau)
+	StatusCode int
+
+	// Catégorie de l'erreur
+	Kind ErrorKind
+
+	// Code, type et paramètre en cause indiqués par le fournisseur
+	Code  string
+	Type  string
+	Param string
+
+	// Message d'erreur du fournisseur (ou corps brut de la réponse)
+	Message string
+
+	// Identifiant de la requête, à communiquer au support du fournisseur
+	RequestID string
+
+	// Délai d'attente demandé par le fournisseur (en-tête Retry-After)
+	RetryAfter time.Duration
+
+	// Erreur d'origine pour les erreurs réseau
+	Err error
+}
+
+// Error implémente l'interface error
+func (e *APIError) Error() string {
+	if e.Kind == KindNetwork {
+		return fmt.Sprintf("erreur lors de l'envoi de la requête: %v", e.Err)
+	}
+	msg := fmt.Sprintf("erreur API: %d %s - %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+	if e.Code != "" {
+		msg += " [" + e.Code + "]"
+	}
+	if e.RequestID != "" {
+		msg += " (requête " + e.RequestID + ")"
+	}
+	return msg
+}
+
+// Unwrap retourne l'erreur d'origine
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable indique si une nouvelle tentative a une chance d'aboutir. Les erreurs
+// d'authentification, de quota et les requêtes invalides ne le sont jamais.
+func (e *APIError) Retryable() bool {
+	switch e.Kind {
+	case KindRateLimited, KindOverloaded, KindServer:
+		return true
+	case KindNetwork:
+		return transientNetworkError(e.Err)
+	}
+	return false
+}
+
+// errorEnvelope représente le corps d'erreur au format OpenAI. Certains
+// fournisseurs renvoient une simple chaîne ou un champ message/detail à la racine.
+type errorEnvelope struct {
+	Error   json.RawMessage `json:"error"`
+	Message string          `json:"message"`
+	Detail  string          `json:"detail"`
+}
+
+// errorBody représente le détail de l'enveloppe d'erreur
+type errorBody struct {
+	Message string          `json:"message"`
+	Type    string          `json:"type"`
+	Param   json.RawMessage `json:"param"`
+	Code    json.RawMessage `json:"code"`
+}
+
+// requestIDHeaders liste les en-têtes portant l'identifiant de la requête
+var requestIDHeaders = []string{"X-Request-Id", "Request-Id", "Openai-Request-Id", "Cf-Ray"}
+
+// newStatusError construit l'erreur correspondant à une réponse HTTP en échec
+func newStatusError(resp *http.Response, body []byte) *APIError {
+	e := &APIError{
+		StatusCode: resp.StatusCode,
+		Message:    strings.TrimSpace(string(body)),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+	parseEnvelope(e, body)
+	if e.Message == "" {
+		e.Message = resp.Status
+	}
+	for _, header := range requestIDHeaders {
+		if id := resp.Header.Get(header); id != "" {
+			e.RequestID = id
+			break
+		}
+	}
+	e.Kind = classify(e)
+	return e
+}
+
+// parseEnvelope complète l'erreur avec le contenu de l'enveloppe d'erreur, si le corps en contient une
+func parseEnvelope(e *APIError, body []byte) {
+	var env errorEnvelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		return
+	}
+
+	var detail errorBody
+	var text string
+	switch {
+	case json.Unmarshal(env.Error, &detail) == nil && detail.Message != "":
+		e.Message = detail.Message
+		e.Type = detail.Type
+		e.Code = rawString(detail.Code)
+		e.Param = rawString(detail.Param)
+	case json.Unmarshal(env.Error, &text) == nil && text != "":
+		e.Message = text
+	case env.Message != "":
+		e.Message = env.Message
+	case env.Detail != "":
+		e.Message = env.Detail
+	}
+}
+
+// rawString convertit un champ JSON chaîne ou nombre en texte (vide si null)
+func rawString(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	return string(raw)
+}
+
+// classify détermine la catégorie d'une erreur à partir de son code HTTP et du
+// code d'erreur du fournisseur
+func classify(e *APIError) ErrorKind {
+	code := strings.ToLower(e.Code + " " + e.Type)
+	switch {
+	case strings.Contains(code, "insufficient_quota") || strings.Contains(code, "billing") ||
+		e.StatusCode == http.StatusPaymentRequired:
+		return KindQuota
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden ||
+		strings.Contains(code, "invalid_api_key") || strings.Contains(code, "authentication"):
+		return KindAuth
+	case e.StatusCode == http.StatusTooManyRequests || strings.Contains(code, "rate_limit"):
+		return KindRateLimited
+	case e.StatusCode == http.StatusServiceUnavailable || e.StatusCode == 529 ||
+		strings.Contains(code, "overloaded") || strings.Contains(strings.ToLower(e.Message), "overloaded"):
+		return KindOverloaded
+	case e.StatusCode >= 500:
+		return KindServer
+	}
+	return KindBadRequest
+}
+
+// newNetworkError construit l'erreur correspondant à un échec de connexion
+func newNetworkError(err error) *APIError {
+	return &APIError{Kind: KindNetwork, Message: err.Error(), Err: err}
+}
+
+// parseRetryAfter interprète l'en-tête Retry-After (secondes ou date HTTP)
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+// transientNetworkError indique si une erreur réseau est passagère : connexion
+// réinitialisée, refusée ou interrompue, délai dépassé
+func transientNetworkError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		// Le délai global de l'appel est écoulé ou l'appel a été annulé
+		return false
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return false
+}
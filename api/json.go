@@ -0,0 +1,183 @@
+package api
+
+import (
+	"asione-agent/schema"
+	"asione-agent/types"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultJSONAttempts est le nombre de demandes adressées au modèle avant
+// d'abandonner une réponse JSON non conforme
+const DefaultJSONAttempts = 3
+
+// JSONRequest décrit la réponse structurée attendue du modèle
+type JSONRequest struct {
+	// Schéma JSON de la réponse ; vide pour accepter tout objet JSON
+	Schema json.RawMessage
+
+	// Nom du schéma transmis au fournisseur ("response" par défaut)
+	Name string
+
+	// Nombre maximal de demandes (DefaultJSONAttempts si nul)
+	MaxAttempts int
+}
+
+// JSONResult représente une réponse structurée et la consommation cumulée des
+// demandes qui ont été nécessaires pour l'obtenir
+type JSONResult struct {
+	Data     json.RawMessage
+	Attempts int
+	Model    string
+	Usage    types.Usage
+}
+
+// ChatCompletionJSON demande au modèle une réponse JSON et la valide contre le
+// schéma fourni. Une réponse non conforme est renvoyée au modèle avec l'erreur
+// de validation pour qu'il la corrige. Le résultat est retourné même en cas
+// d'échec, pour que la consommation des demandes puisse être comptabilisée.
+func (c *Client) ChatCompletionJSON(ctx context.Context, messages []types.Message, opts ChatOptions, req JSONRequest) (*JSONResult, error) {
+	result := &JSONResult{}
+
+	var validator *schema.Schema
+	if len(req.Schema) > 0 {
+		s, err := schema.Parse(req.Schema)
+		if err != nil {
+			return result, err
+		}
+		validator = s
+	}
+	if req.Name == "" {
+		req.Name = "response"
+	}
+	if req.MaxAttempts <= 0 {
+		req.MaxAttempts = DefaultJSONAttempts
+	}
+
+	// Le format est imposé par le fournisseur quand il le permet et rappelé
+	// dans les instructions pour les autres
+	opts.Tools = nil
+	opts.ResponseFormat = &types.ResponseFormat{Type: "json_object"}
+	if validator != nil {
+		opts.ResponseFormat = &types.ResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &types.JSONSchemaFormat{Name: req.Name, Schema: req.Schema},
+		}
+	}
+	conversation := append(append([]types.Message{}, messages...), types.Message{
+		Role:    "system",
+		Content: jsonInstructions(req.Schema),
+	})
+
+	var lastErr error
+	for result.Attempts < req.MaxAttempts {
+		result.Attempts++
+
+		resp, err := c.ChatCompletion(ctx, conversation, opts)
+		if err != nil && opts.ResponseFormat != nil && isResponseFormatUnsupported(err) {
+			// Format non pris en charge : se rabattre sur json_object puis sur les seules instructions
+			if opts.ResponseFormat.Type == "json_schema" {
+				opts.ResponseFormat = &types.ResponseFormat{Type: "json_object"}
+			} else {
+				opts.ResponseFormat = nil
+			}
+			result.Attempts--
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+
+		result.Model = resp.Model
+		result.Usage.PromptTokens += resp.Usage.PromptTokens
+		result.Usage.CompletionTokens += resp.Usage.CompletionTokens
+		result.Usage.TotalTokens += resp.Usage.TotalTokens
+
+		content := ""
+		if len(resp.Choices) > 0 {
+			content = resp.Choices[0].Message.Content
+		}
+		data := ExtractJSON(content)
+		if lastErr = validateJSON(validator, data); lastErr == nil {
+			result.Data = data
+			return result, nil
+		}
+
+		// Renvoyer l'erreur au modèle pour qu'il corrige sa réponse
+		conversation = append(conversation,
+			types.Message{Role: "assistant", Content: content},
+			types.Message{
+				Role: "user",
+				Content: fmt.Sprintf("Votre réponse n'est pas valide : %v\n"+
+					"Répondez uniquement avec le JSON corrigé, sans texte autour.", lastErr),
+			},
+		)
+	}
+	return result, fmt.Errorf("réponse JSON invalide après %d tentative(s): %w", result.Attempts, lastErr)
+}
+
+// jsonInstructions rédige la consigne de format ajoutée à la conversation
+func jsonInstructions(schemaJSON json.RawMessage) string {
+	instructions := "Répondez uniquement avec un document JSON valide, sans texte avant ou après " +
+		"et sans bloc de code Markdown."
+	if len(schemaJSON) > 0 {
+		instructions += "\nLe document doit être conforme à ce schéma JSON :\n" + string(schemaJSON)
+	}
+	return instructions
+}
+
+// validateJSON vérifie un document JSON, contre le schéma s'il est fourni
+func validateJSON(validator *schema.Schema, data []byte) error {
+	if validator != nil {
+		return validator.Validate(data)
+	}
+	if !json.Valid(data) {
+		return &schema.ValidationError{Problems: []string{"le contenu n'est pas un document JSON valide"}}
+	}
+	return nil
+}
+
+// ExtractJSON isole le document JSON d'une réponse : les blocs de code Markdown
+// et le texte qui entoure le premier objet ou tableau sont retirés
+func ExtractJSON(content string) json.RawMessage {
+	content = strings.TrimSpace(content)
+	if strings.HasPrefix(content, "```") {
+		content = strings.TrimPrefix(content, "```json")
+		content = strings.TrimPrefix(content, "```")
+		content = strings.TrimSuffix(strings.TrimSpace(content), "```")
+		content = strings.TrimSpace(content)
+	}
+	if json.Valid([]byte(content)) {
+		return json.RawMessage(content)
+	}
+
+	start := strings.IndexAny(content, "{[")
+	if start < 0 {
+		return json.RawMessage(content)
+	}
+	closing := "}"
+	if content[start] == '[' {
+		closing = "]"
+	}
+	if end := strings.LastIndex(content, closing); end > start {
+		return json.RawMessage(content[start : end+1])
+	}
+	return json.RawMessage(content)
+}
+
+// isResponseFormatUnsupported indique si le fournisseur a refusé le paramètre response_format
+func isResponseFormatUnsupported(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if apiErr.StatusCode != 400 && apiErr.StatusCode != 422 {
+		return false
+	}
+	return strings.HasPrefix(apiErr.Param, "response_format") ||
+		strings.Contains(strings.ToLower(apiErr.Message), "response_format") ||
+		strings.Contains(strings.ToLower(apiErr.Message), "json_schema")
+}
@@ -0,0 +1,257 @@
+package api
+
+import (
+	"asione-agent/types"
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ollamaProvider est l'adaptateur de l'API native d'Ollama (/api/chat), dont
+// le flux est au format JSON par ligne et non Server-Sent Events
+type ollamaProvider struct{}
+
+// ollamaRequest représente le corps d'une requête /api/chat
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Tools    []types.Tool    `json:"tools,omitempty"`
+	Format   json.RawMessage `json:"format,omitempty"`
+	Options  ollamaOptions   `json:"options"`
+}
+
+// ollamaOptions représente les paramètres de génération
+type ollamaOptions struct {
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"top_p,omitempty"`
+	NumPredict       int      `json:"num_predict,omitempty"`
+	Stop             []string `json:"stop,omitempty"`
+	Seed             *int     `json:"seed,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+}
+
+// ollamaMessage représente un message ; les arguments des appels d'outils sont des objets JSON
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+	Images    []string         `json:"images,omitempty"`
+}
+
+// ollamaToolCall représente un appel d'outil
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+// ollamaResponse représente une réponse (ou une ligne du flux) de /api/chat
+type ollamaResponse struct {
+	Model           string        `json:"model"`
+	CreatedAt       time.Time     `json:"created_at"`
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+// Name implémente Provider
+func (ollamaProvider) Name() string {
+	return ProviderOllama
+}
+
+// NewChatRequest implémente Provider
+func (ollamaProvider) NewChatRequest(ctx context.Context, target Target, request *types.ChatRequest) (*http.Request, error) {
+	body := ollamaRequest{
+		Model:  request.Model,
+		Stream: request.Stream,
+		Tools:  request.Tools,
+		Options: ollamaOptions{
+			Temperature:      request.Temperature,
+			TopP:             request.TopP,
+			NumPredict:       request.MaxTokens,
+			Stop:             request.Stop,
+			Seed:             request.Seed,
+			PresencePenalty:  request.PresencePenalty,
+			FrequencyPenalty: request.FrequencyPenalty,
+		},
+	}
+	if format := request.ResponseFormat; format != nil {
+		// Ollama accepte "json" ou directement le schéma attendu
+		switch {
+		case format.Type == "json_schema" && format.JSONSchema != nil && len(format.JSONSchema.Schema) > 0:
+			body.Format = format.JSONSchema.Schema
+		case format.Type == "json_object" || format.Type == "json_schema":
+			body.Format = json.RawMessage(`"json"`)
+		}
+	}
+	for _, msg := range request.Messages {
+		m := ollamaMessage{Role: msg.Role, Content: msg.Content, ToolName: msg.Name}
+		for _, image := range msg.Images() {
+			// Ollama n'accepte que des images en base64, sans préfixe data URL
+			if _, data, ok := parseDataURL(image.URL); ok {
+				m.Images = append(m.Images, data)
+			}
+		}
+		for _, call := range msg.ToolCalls {
+			var tc ollamaToolCall
+			tc.Function.Name = call.Function.Name
+			tc.Function.Arguments = json.RawMessage(call.Function.Arguments)
+			if !json.Valid(tc.Function.Arguments) {
+				tc.Function.Arguments = json.RawMessage(`{}`)
+			}
+			m.ToolCalls = append(m.ToolCalls, tc)
+		}
+		body.Messages = append(body.Messages, m)
+	}
+
+	req, err := newJSONRequest(ctx, "POST", target.BaseURL+"/api/chat", body)
+	if err != nil {
+		return nil, err
+	}
+	setOllamaHeaders(req, target)
+	return req, nil
+}
+
+// setOllamaHeaders ajoute l'authentification, utile derrière un proxy
+func setOllamaHeaders(req *http.Request, target Target) {
+	if target.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+target.APIKey)
+	}
+}
+
+// DecodeResponse implémente Provider
+func (p ollamaProvider) DecodeResponse(body []byte) (*types.ChatResponse, error) {
+	var resp ollamaResponse
+	if err := decodeJSON(body, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, &APIError{Kind: KindBadRequest, Message: resp.Error}
+	}
+
+	acc := newStreamAccumulator()
+	if _, err := acc.add(ollamaChunk(&resp, 0)); err != nil {
+		return nil, err
+	}
+	return acc.response(), nil
+}
+
+// DecodeStream implémente Provider (un objet JSON par ligne)
+func (ollamaProvider) DecodeStream(resp *http.Response, onDelta func(string)) (*types.ChatResponse, error) {
+	acc := newStreamAccumulator()
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), maxEventSize)
+
+	toolCalls := 0
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var chunk ollamaResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return nil, fmt.Errorf("erreur lors de la désérialisation du flux: %w - %s", err, string(line))
+		}
+		if chunk.Error != "" {
+			return nil, &APIError{Kind: KindServer, Message: chunk.Error}
+		}
+
+		text, err := acc.add(ollamaChunk(&chunk, toolCalls))
+		if err != nil {
+			return nil, err
+		}
+		if text != "" {
+			onDelta(text)
+		}
+		toolCalls += len(chunk.Message.ToolCalls)
+		if chunk.Done {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("erreur lors de la lecture du flux: %w", err)
+	}
+	return acc.response(), nil
+}
+
+// ollamaChunk convertit une réponse Ollama en fragment au format OpenAI.
+// Les appels d'outils arrivent complets ; firstTool est l'index du premier.
+func ollamaChunk(resp *ollamaResponse, firstTool int) *types.ChatStreamChunk {
+	choice := types.StreamChoice{
+		Delta: types.Message{Role: "assistant", Content: resp.Message.Content},
+	}
+	for i, call := range resp.Message.ToolCalls {
+		idx := firstTool + i
+		choice.Delta.ToolCalls = append(choice.Delta.ToolCalls, types.ToolCall{
+			Index: &idx,
+			Type:  "function",
+			Function: types.FunctionCall{
+				Name:      call.Function.Name,
+				Arguments: string(call.Function.Arguments),
+			},
+		})
+	}
+
+	chunk := &types.ChatStreamChunk{
+		Model:   resp.Model,
+		Choices: []types.StreamChoice{choice},
+	}
+	if !resp.CreatedAt.IsZero() {
+		chunk.Created = resp.CreatedAt.Unix()
+	}
+	if resp.Done {
+		chunk.Choices[0].FinishReason = resp.DoneReason
+		if len(resp.Message.ToolCalls) > 0 || firstTool > 0 {
+			chunk.Choices[0].FinishReason = "tool_calls"
+		}
+		chunk.Usage = &types.Usage{
+			PromptTokens:     resp.PromptEvalCount,
+			CompletionTokens: resp.EvalCount,
+			TotalTokens:      resp.PromptEvalCount + resp.EvalCount,
+		}
+	}
+	return chunk
+}
+
+// NewModelsRequest implémente Provider
+func (ollamaProvider) NewModelsRequest(ctx context.Context, target Target) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", target.BaseURL+"/api/tags", nil)
+	if err != nil {
+		return nil, fmt.Errorf("erreur lors de la création de la requête: %w", err)
+	}
+	setOllamaHeaders(req, target)
+	return req, nil
+}
+
+// DecodeModels implémente Provider
+func (ollamaProvider) DecodeModels(body []byte) (*types.ModelsResponse, error) {
+	var resp struct {
+		Models []struct {
+			Name       string    `json:"name"`
+			ModifiedAt time.Time `json:"modified_at"`
+		} `json:"models"`
+	}
+	if err := decodeJSON(body, &resp); err != nil {
+		return nil, err
+	}
+
+	models := &types.ModelsResponse{Object: "list"}
+	for _, m := range resp.Models {
+		model := types.Model{ID: m.Name, Object: "model", OwnedBy: "ollama"}
+		if !m.ModifiedAt.IsZero() {
+			model.Created = m.ModifiedAt.Unix()
+		}
+		models.Data = append(models.Data, model)
+	}
+	return models, nil
+}
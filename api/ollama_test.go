@@ -0,0 +1,161 @@
+package api
+
+import (
+	"asione-agent/types"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestOllamaChatRequest(t *testing.T) {
+	srv, got := newTestServer(t, http.StatusOK, "application/json", `{
+		"model": "modele-test",
+		"created_at": "2024-05-01T10:00:00Z",
+		"message": {
+			"role": "assistant",
+			"content": "",
+			"tool_calls": [{"function": {"name": "run", "arguments": {"cmd": "ls"}}}]
+		},
+		"done": true,
+		"done_reason": "stop",
+		"prompt_eval_count": 30,
+		"eval_count": 6
+	}`)
+
+	c := newTestClient(t, srv.URL, ProviderOllama)
+	messages := []types.Message{
+		{Role: "system", Content: "Tu es un assistant."},
+		types.NewMultipartMessage("user", []types.ContentPart{
+			{Type: types.PartText, Text: "Décris l'image"},
+			{Type: types.PartImage, ImageURL: &types.ImageURL{URL: "data:image/jpeg;base64,/9j/4AAQ"}},
+		}),
+		{Role: "assistant", ToolCalls: []types.ToolCall{{ID: "call_0", Type: "function",
+			Function: types.FunctionCall{Name: "run", Arguments: "pas du JSON"}}}},
+		{Role: "tool", Name: "run", ToolCallID: "call_0", Content: "ok"},
+	}
+	format := &types.ResponseFormat{Type: "json_schema", JSONSchema: &types.JSONSchemaFormat{
+		Name: "response", Schema: json.RawMessage(`{"type":"object"}`)}}
+	opts := ChatOptions{MaxTokens: 256, Temperature: Float(0), Seed: Int(7), ResponseFormat: format}
+	resp, err := c.ChatCompletion(context.Background(), messages, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.path != "/api/chat" {
+		t.Errorf("chemin %s, /api/chat attendu", got.path)
+	}
+	if auth := got.header.Get("Authorization"); auth != "Bearer sk-test" {
+		t.Errorf("Authorization = %q", auth)
+	}
+	if got.body["stream"] != false {
+		t.Errorf("stream = %v, false attendu (Ollama diffuse par défaut)", got.body["stream"])
+	}
+	options := got.body["options"].(map[string]interface{})
+	if options["num_predict"] != float64(256) || options["temperature"] != float64(0) || options["seed"] != float64(7) {
+		t.Errorf("options = %v", options)
+	}
+	if format, _ := json.Marshal(got.body["format"]); string(format) != `{"type":"object"}` {
+		t.Errorf("format = %s", format)
+	}
+
+	sent := got.body["messages"].([]interface{})
+	if len(sent) != 4 {
+		t.Fatalf("%d messages envoyés, 4 attendus", len(sent))
+	}
+	user := sent[1].(map[string]interface{})
+	if user["content"] != "Décris l'image" {
+		t.Errorf("contenu = %q", user["content"])
+	}
+	if images := user["images"].([]interface{}); len(images) != 1 || images[0] != "/9j/4AAQ" {
+		t.Errorf("images = %v", images)
+	}
+	call := sent[2].(map[string]interface{})["tool_calls"].([]interface{})[0].(map[string]interface{})["function"].(map[string]interface{})
+	if args, _ := json.Marshal(call["arguments"]); string(args) != `{}` {
+		t.Errorf("des arguments invalides doivent être remplacés par {} : %s", args)
+	}
+	if name := sent[3].(map[string]interface{})["tool_name"]; name != "run" {
+		t.Errorf("tool_name = %v", name)
+	}
+
+	msg := resp.Choices[0].Message
+	if len(msg.ToolCalls) != 1 || msg.ToolCalls[0].Function.Name != "run" || msg.ToolCalls[0].Function.Arguments != `{"cmd": "ls"}` {
+		t.Errorf("appels d'outils = %+v", msg.ToolCalls)
+	}
+	if resp.Choices[0].FinishReason != "tool_calls" {
+		t.Errorf("finish_reason = %q", resp.Choices[0].FinishReason)
+	}
+	if resp.Usage.PromptTokens != 30 || resp.Usage.CompletionTokens != 6 || resp.Usage.TotalTokens != 36 {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+}
+
+func TestOllamaStream(t *testing.T) {
+	stream := strings.Join([]string{
+		`{"model":"modele-test","created_at":"2024-05-01T10:00:00Z","message":{"role":"assistant","content":"Bon"},"done":false}`,
+		``,
+		`{"model":"modele-test","created_at":"2024-05-01T10:00:01Z","message":{"role":"assistant","content":"jour"},"done":false}`,
+		`{"model":"modele-test","created_at":"2024-05-01T10:00:02Z","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":4,"eval_count":2}`,
+		``,
+	}, "\n")
+	srv, got := newTestServer(t, http.StatusOK, "application/x-ndjson", stream)
+
+	c := newTestClient(t, srv.URL, ProviderOllama)
+	var deltas []string
+	resp, err := c.ChatCompletionStream(context.Background(), []types.Message{{Role: "user", Content: "Salut"}}, ChatOptions{},
+		func(text string) { deltas = append(deltas, text) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.body["stream"] != true {
+		t.Errorf("stream = %v, true attendu", got.body["stream"])
+	}
+	if _, ok := got.body["stream_options"]; ok {
+		t.Errorf("stream_options n'existe pas dans l'API d'Ollama")
+	}
+	if strings.Join(deltas, "|") != "Bon|jour" {
+		t.Errorf("fragments = %q", deltas)
+	}
+	if resp.Choices[0].Message.Content != "Bonjour" || resp.Choices[0].FinishReason != "stop" {
+		t.Errorf("réponse = %+v", resp.Choices[0])
+	}
+	if resp.Usage.TotalTokens != 6 {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+}
+
+func TestOllamaErrors(t *testing.T) {
+	t.Run("modèle absent", func(t *testing.T) {
+		srv, _ := newTestServer(t, http.StatusNotFound, "application/json", `{"error":"model \"modele-test\" not found, try pulling it first"}`)
+		c := newTestClient(t, srv.URL, ProviderOllama)
+		_, err := c.ChatCompletion(context.Background(), []types.Message{{Role: "user", Content: "Salut"}}, ChatOptions{})
+		apiErr := asAPIError(t, err)
+		if apiErr.StatusCode != http.StatusNotFound || apiErr.Kind != KindBadRequest || !strings.Contains(apiErr.Message, "not found") {
+			t.Errorf("erreur = %+v", apiErr)
+		}
+	})
+
+	t.Run("erreur dans une réponse 200", func(t *testing.T) {
+		srv, _ := newTestServer(t, http.StatusOK, "application/json", `{"error":"invalid format"}`)
+		c := newTestClient(t, srv.URL, ProviderOllama)
+		_, err := c.ChatCompletion(context.Background(), []types.Message{{Role: "user", Content: "Salut"}}, ChatOptions{})
+		apiErr := asAPIError(t, err)
+		if apiErr.Kind != KindBadRequest || apiErr.Message != "invalid format" {
+			t.Errorf("erreur = %+v", apiErr)
+		}
+	})
+
+	t.Run("erreur dans le flux", func(t *testing.T) {
+		stream := `{"model":"modele-test","message":{"role":"assistant","content":"Bon"},"done":false}` + "\n" +
+			`{"error":"out of memory"}` + "\n"
+		srv, _ := newTestServer(t, http.StatusOK, "application/x-ndjson", stream)
+		c := newTestClient(t, srv.URL, ProviderOllama)
+		_, err := c.ChatCompletionStream(context.Background(), []types.Message{{Role: "user", Content: "Salut"}}, ChatOptions{}, nil)
+		apiErr := asAPIError(t, err)
+		if apiErr.Kind != KindServer || apiErr.Message != "out of memory" {
+			t.Errorf("erreur = %+v", apiErr)
+		}
+	})
+}
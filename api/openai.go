@@ -0,0 +1,104 @@
+package api
+
+import (
+	"asione-agent/types"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// openAIProvider est l'adaptateur de l'API compatible OpenAI (/chat/completions,
+// authentification Bearer), utilisée notamment par ASI Cloud
+type openAIProvider struct{}
+
+// Name implémente Provider
+func (openAIProvider) Name() string {
+	return ProviderOpenAI
+}
+
+// NewChatRequest implémente Provider
+func (openAIProvider) NewChatRequest(ctx context.Context, target Target, request *types.ChatRequest) (*http.Request, error) {
+	req, err := newJSONRequest(ctx, "POST", target.BaseURL+"/chat/completions", request)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+target.APIKey)
+	if request.Stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+	return req, nil
+}
+
+// DecodeResponse implémente Provider
+func (openAIProvider) DecodeResponse(body []byte) (*types.ChatResponse, error) {
+	var chatResp types.ChatResponse
+	if err := decodeJSON(body, &chatResp); err != nil {
+		return nil, err
+	}
+	return &chatResp, nil
+}
+
+// DecodeStream implémente Provider (Server-Sent Events)
+func (p openAIProvider) DecodeStream(resp *http.Response, onDelta func(string)) (*types.ChatResponse, error) {
+	// Certains fournisseurs ignorent "stream" et renvoient une réponse JSON classique
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("erreur lors de la lecture de la réponse: %w", err)
+		}
+		chatResp, err := p.DecodeResponse(respBody)
+		if err != nil {
+			return nil, err
+		}
+		if len(chatResp.Choices) > 0 {
+			onDelta(chatResp.Choices[0].Message.Content)
+		}
+		return chatResp, nil
+	}
+
+	acc := newStreamAccumulator()
+	err := readEventStream(resp.Body, func(data []byte) error {
+		var chunk types.ChatStreamChunk
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return fmt.Errorf("erreur lors de la désérialisation du flux: %w - %s", err, string(data))
+		}
+		delta, err := acc.add(&chunk)
+		if err != nil {
+			return err
+		}
+		if delta != "" {
+			onDelta(delta)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return acc.response(), nil
+}
+
+// NewModelsRequest implémente Provider
+func (openAIProvider) NewModelsRequest(ctx context.Context, target Target) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", target.BaseURL+"/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("erreur lors de la création de la requête: %w", err)
+	}
+
+	// Définition des headers
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+target.APIKey)
+	return req, nil
+}
+
+// DecodeModels implémente Provider
+func (openAIProvider) DecodeModels(body []byte) (*types.ModelsResponse, error) {
+	var modelsResp types.ModelsResponse
+	if err := decodeJSON(body, &modelsResp); err != nil {
+		return nil, err
+	}
+	return &modelsResp, nil
+}
@@ -0,0 +1,348 @@
+package api
+
+import (
+	"asione-agent/types"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestClient crée un client adressé au serveur de test, sans nouvelle tentative
+func newTestClient(t *testing.T, baseURL, provider string) *Client {
+	t.Helper()
+	c := NewClient(baseURL, "sk-test", "modele-test")
+	if err := c.SetProvider(provider); err != nil {
+		t.Fatal(err)
+	}
+	c.SetRetryPolicy(RetryPolicy{MaxAttempts: 1})
+	return c
+}
+
+// captured conserve la dernière requête reçue par le serveur de test
+type captured struct {
+	method string
+	path   string
+	header http.Header
+	body   map[string]interface{}
+}
+
+// newTestServer démarre un serveur qui enregistre la requête reçue puis répond
+// avec le statut, le type de contenu et le corps donnés
+func newTestServer(t *testing.T, status int, contentType, body string) (*httptest.Server, *captured) {
+	t.Helper()
+	got := &captured{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.method = r.Method
+		got.path = r.URL.Path
+		got.header = r.Header.Clone()
+		if data, _ := io.ReadAll(r.Body); len(data) > 0 {
+			if err := json.Unmarshal(data, &got.body); err != nil {
+				t.Errorf("corps de requête invalide: %v - %s", err, data)
+			}
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, got
+}
+
+// asAPIError vérifie que l'erreur est une *APIError et la retourne
+func asAPIError(t *testing.T, err error) *APIError {
+	t.Helper()
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("erreur %T (%v), *APIError attendue", err, err)
+	}
+	return apiErr
+}
+
+func TestOpenAIChatCompletion(t *testing.T) {
+	srv, got := newTestServer(t, http.StatusOK, "application/json", `{
+		"id": "chatcmpl-1",
+		"object": "chat.completion",
+		"model": "modele-test",
+		"choices": [{
+			"index": 0,
+			"message": {
+				"role": "assistant",
+				"content": "",
+				"tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "run", "arguments": "{\"cmd\":\"ls\"}"}}]
+			},
+			"finish_reason": "tool_calls"
+		}],
+		"usage": {"prompt_tokens": 12, "completion_tokens": 5, "total_tokens": 17}
+	}`)
+
+	c := newTestClient(t, srv.URL, ProviderOpenAI)
+	messages := []types.Message{
+		{Role: "system", Content: "Tu es un assistant."},
+		{Role: "user", Content: "Liste les fichiers", Sources: []types.Source{{Index: 1, URL: "https://example.com"}}},
+	}
+	resp, err := c.ChatCompletion(context.Background(), messages, ChatOptions{Temperature: Float(0.2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.method != "POST" || got.path != "/chat/completions" {
+		t.Errorf("requête %s %s, POST /chat/completions attendu", got.method, got.path)
+	}
+	if auth := got.header.Get("Authorization"); auth != "Bearer sk-test" {
+		t.Errorf("Authorization = %q", auth)
+	}
+	if got.body["model"] != "modele-test" || got.body["temperature"] != 0.2 {
+		t.Errorf("modèle ou température incorrects: %v", got.body)
+	}
+	if got.body["max_tokens"] != float64(DefaultMaxTokens) {
+		t.Errorf("max_tokens = %v, %d attendu", got.body["max_tokens"], DefaultMaxTokens)
+	}
+	if _, ok := got.body["stream"]; ok {
+		t.Errorf("stream ne doit pas être envoyé hors streaming")
+	}
+	sent := got.body["messages"].([]interface{})
+	if len(sent) != 2 {
+		t.Fatalf("%d messages envoyés, 2 attendus", len(sent))
+	}
+	if _, ok := sent[1].(map[string]interface{})["sources"]; ok {
+		t.Errorf("les sources ne doivent pas être transmises au fournisseur")
+	}
+
+	if len(resp.Choices) != 1 || len(resp.Choices[0].Message.ToolCalls) != 1 {
+		t.Fatalf("réponse inattendue: %+v", resp)
+	}
+	call := resp.Choices[0].Message.ToolCalls[0]
+	if call.ID != "call_1" || call.Function.Name != "run" || call.Function.Arguments != `{"cmd":"ls"}` {
+		t.Errorf("appel d'outil inattendu: %+v", call)
+	}
+	if resp.Usage.TotalTokens != 17 {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+}
+
+func TestOpenAIChatCompletionStream(t *testing.T) {
+	stream := strings.Join([]string{
+		`data: {"id":"c1","model":"modele-test","choices":[{"index":0,"delta":{"role":"assistant","content":"Bon"}}]}`,
+		``,
+		`: commentaire ignoré`,
+		`data: {"id":"c1","choices":[{"index":0,"delta":{"content":"jour"}}]}`,
+		``,
+		`data: {"id":"c1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"run","arguments":"{\"cmd\":"}}]}}]}`,
+		``,
+		`data: {"id":"c1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"ls\"}"}}]},"finish_reason":"tool_calls"}]}`,
+		``,
+		`data: {"id":"c1","choices":[],"usage":{"prompt_tokens":8,"completion_tokens":3,"total_tokens":11}}`,
+		``,
+		`data: [DONE]`,
+		``,
+	}, "\n")
+	srv, got := newTestServer(t, http.StatusOK, "text/event-stream", stream)
+
+	c := newTestClient(t, srv.URL, ProviderOpenAI)
+	var deltas []string
+	resp, err := c.ChatCompletionStream(context.Background(), []types.Message{{Role: "user", Content: "Salut"}}, ChatOptions{},
+		func(text string) { deltas = append(deltas, text) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.body["stream"] != true {
+		t.Errorf("stream = %v, true attendu", got.body["stream"])
+	}
+	if accept := got.header.Get("Accept"); accept != "text/event-stream" {
+		t.Errorf("Accept = %q", accept)
+	}
+	if strings.Join(deltas, "|") != "Bon|jour" {
+		t.Errorf("fragments = %q", deltas)
+	}
+
+	msg := resp.Choices[0].Message
+	if msg.Content != "Bonjour" {
+		t.Errorf("contenu = %q", msg.Content)
+	}
+	if len(msg.ToolCalls) != 1 || msg.ToolCalls[0].Function.Arguments != `{"cmd":"ls"}` {
+		t.Errorf("appels d'outils = %+v", msg.ToolCalls)
+	}
+	if resp.Choices[0].FinishReason != "tool_calls" {
+		t.Errorf("finish_reason = %q", resp.Choices[0].FinishReason)
+	}
+	if resp.Usage.TotalTokens != 11 {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+}
+
+func TestOpenAIStreamIgnoredByProvider(t *testing.T) {
+	// Réponse JSON classique malgré "stream": true
+	srv, _ := newTestServer(t, http.StatusOK, "application/json",
+		`{"id":"c1","choices":[{"index":0,"message":{"role":"assistant","content":"Réponse complète"},"finish_reason":"stop"}]}`)
+
+	c := newTestClient(t, srv.URL, ProviderOpenAI)
+	var deltas []string
+	resp, err := c.ChatCompletionStream(context.Background(), []types.Message{{Role: "user", Content: "Salut"}}, ChatOptions{},
+		func(text string) { deltas = append(deltas, text) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deltas) != 1 || deltas[0] != "Réponse complète" || resp.Choices[0].Message.Content != "Réponse complète" {
+		t.Errorf("fragments = %q, réponse = %+v", deltas, resp)
+	}
+}
+
+func TestOpenAIStreamInvalidToolCallIndex(t *testing.T) {
+	// Un index hors de 0..len(appels) est une erreur du flux, pas une panique
+	for _, index := range []int{-1, 1, 1000000} {
+		t.Run(fmt.Sprint(index), func(t *testing.T) {
+			stream := fmt.Sprintf(`data: {"id":"c1","choices":[{"index":0,"delta":{"tool_calls":[{"index":%d,"id":"call_1","function":{"name":"run"}}]}}]}`, index) +
+				"\n\ndata: [DONE]\n\n"
+			srv, _ := newTestServer(t, http.StatusOK, "text/event-stream", stream)
+
+			c := newTestClient(t, srv.URL, ProviderOpenAI)
+			_, err := c.ChatCompletionStream(context.Background(), []types.Message{{Role: "user", Content: "Salut"}}, ChatOptions{},
+				func(string) {})
+			if err == nil || !strings.Contains(err.Error(), "index d'appel d'outil invalide") {
+				t.Errorf("erreur = %v", err)
+			}
+		})
+	}
+}
+
+func TestOpenAIStreamOptionsRejected(t *testing.T) {
+	// Serveur compatible OpenAI qui refuse le paramètre stream_options
+	var withOptions, requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if _, ok := body["stream_options"]; ok {
+			withOptions++
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error": {"message": "Unrecognized request argument supplied: stream_options", "type": "invalid_request_error"}}`)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"id\":\"c1\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"ok\"},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n")
+	}))
+	t.Cleanup(srv.Close)
+
+	c := newTestClient(t, srv.URL, ProviderOpenAI)
+	messages := []types.Message{{Role: "user", Content: "Salut"}}
+	for i := 0; i < 2; i++ {
+		resp, err := c.ChatCompletionStream(context.Background(), messages, ChatOptions{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Choices[0].Message.Content != "ok" {
+			t.Errorf("contenu = %q", resp.Choices[0].Message.Content)
+		}
+	}
+	// Le paramètre n'est plus envoyé une fois le refus constaté
+	if requests != 3 || withOptions != 1 {
+		t.Errorf("%d requêtes dont %d avec stream_options, 3 et 1 attendues", requests, withOptions)
+	}
+}
+
+func TestOpenAIBadRequestNotMasked(t *testing.T) {
+	// Un refus sans rapport avec stream_options reste une erreur
+	srv, _ := newTestServer(t, http.StatusBadRequest, "application/json",
+		`{"error": {"message": "Invalid model", "type": "invalid_request_error"}}`)
+
+	c := newTestClient(t, srv.URL, ProviderOpenAI)
+	_, err := c.ChatCompletionStream(context.Background(), []types.Message{{Role: "user", Content: "Salut"}}, ChatOptions{}, nil)
+	if apiErr := asAPIError(t, err); apiErr.Message != "Invalid model" {
+		t.Errorf("message = %q", apiErr.Message)
+	}
+	// La nouvelle tentative sans stream_options a échoué : le paramètre sera renvoyé
+	if c.noStreamOptions[srv.URL] {
+		t.Errorf("adresse mémorisée à tort")
+	}
+}
+
+func TestOpenAIErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		header  string
+		body    string
+		kind    ErrorKind
+		message string
+		code    string
+	}{
+		{
+			name:    "clé invalide",
+			status:  http.StatusUnauthorized,
+			body:    `{"error":{"message":"Incorrect API key provided","type":"invalid_request_error","code":"invalid_api_key"}}`,
+			kind:    KindAuth,
+			message: "Incorrect API key provided",
+			code:    "invalid_api_key",
+		},
+		{
+			name:    "quota épuisé",
+			status:  http.StatusTooManyRequests,
+			body:    `{"error":{"message":"You exceeded your current quota","type":"insufficient_quota","code":"insufficient_quota"}}`,
+			kind:    KindQuota,
+			message: "You exceeded your current quota",
+			code:    "insufficient_quota",
+		},
+		{
+			name:    "limite de requêtes",
+			status:  http.StatusTooManyRequests,
+			header:  "7",
+			body:    `{"error":{"message":"Rate limit reached","type":"requests","code":"rate_limit_exceeded"}}`,
+			kind:    KindRateLimited,
+			message: "Rate limit reached",
+			code:    "rate_limit_exceeded",
+		},
+		{
+			name:    "corps brut",
+			status:  http.StatusBadGateway,
+			body:    `upstream unavailable`,
+			kind:    KindServer,
+			message: "upstream unavailable",
+		},
+		{
+			name:    "erreur en chaîne",
+			status:  http.StatusBadRequest,
+			body:    `{"error":"model not found"}`,
+			kind:    KindBadRequest,
+			message: "model not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Request-Id", "req-42")
+				if tt.header != "" {
+					w.Header().Set("Retry-After", tt.header)
+				}
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer srv.Close()
+
+			c := newTestClient(t, srv.URL, ProviderOpenAI)
+			_, err := c.ChatCompletion(context.Background(), []types.Message{{Role: "user", Content: "Salut"}}, ChatOptions{})
+			apiErr := asAPIError(t, err)
+			if apiErr.StatusCode != tt.status || apiErr.Kind != tt.kind {
+				t.Errorf("statut %d, catégorie %s ; %d, %s attendus", apiErr.StatusCode, apiErr.Kind, tt.status, tt.kind)
+			}
+			if apiErr.Message != tt.message || apiErr.Code != tt.code {
+				t.Errorf("message %q, code %q", apiErr.Message, apiErr.Code)
+			}
+			if apiErr.RequestID != "req-42" {
+				t.Errorf("identifiant de requête = %q", apiErr.RequestID)
+			}
+			if tt.header != "" && apiErr.RetryAfter != 7*time.Second {
+				t.Errorf("Retry-After = %v", apiErr.RetryAfter)
+			}
+		})
+	}
+}
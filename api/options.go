@@ -0,0 +1,66 @@
+package api
+
+import "asione-agent/types"
+
+// DefaultMaxTokens est la limite de tokens générés utilisée si aucune n'est précisée
+const DefaultMaxTokens = 8192
+
+// ChatOptions regroupe les paramètres de génération d'un appel de complétion.
+// Un champ nul (pointeur nil, tranche vide, zéro) laisse la valeur par défaut
+// du fournisseur, sauf MaxTokens qui vaut alors DefaultMaxTokens.
+type ChatOptions struct {
+	Temperature      *float64              `json:"temperature,omitempty"`
+	TopP             *float64              `json:"top_p,omitempty"`
+	MaxTokens        int                   `json:"max_tokens,omitempty"`
+	Stop             []string              `json:"stop,omitempty"`
+	Seed             *int                  `json:"seed,omitempty"`
+	PresencePenalty  *float64              `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64              `json:"frequency_penalty,omitempty"`
+	ResponseFormat   *types.ResponseFormat `json:"response_format,omitempty"`
+
+	// Outils déclarés au modèle, qui peut alors répondre par des appels d'outils
+	Tools []types.Tool `json:"-"`
+}
+
+// Float retourne un pointeur vers une valeur flottante (paramètres facultatifs)
+func Float(v float64) *float64 {
+	return &v
+}
+
+// Int retourne un pointeur vers une valeur entière (paramètres facultatifs)
+func Int(v int) *int {
+	return &v
+}
+
+// Merge retourne les options complétées par celles de override : chaque champ
+// renseigné dans override remplace la valeur courante
+func (o ChatOptions) Merge(override ChatOptions) ChatOptions {
+	if override.Temperature != nil {
+		o.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		o.TopP = override.TopP
+	}
+	if override.MaxTokens > 0 {
+		o.MaxTokens = override.MaxTokens
+	}
+	if len(override.Stop) > 0 {
+		o.Stop = override.Stop
+	}
+	if override.Seed != nil {
+		o.Seed = override.Seed
+	}
+	if override.PresencePenalty != nil {
+		o.PresencePenalty = override.PresencePenalty
+	}
+	if override.FrequencyPenalty != nil {
+		o.FrequencyPenalty = override.FrequencyPenalty
+	}
+	if override.ResponseFormat != nil {
+		o.ResponseFormat = override.ResponseFormat
+	}
+	if override.Tools != nil {
+		o.Tools = override.Tools
+	}
+	return o
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"asione-agent/types"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Provider traduit les appels de complétion vers l'API d'un fournisseur. Les
+// requêtes et réponses sont exprimées au format OpenAI, utilisé dans tout l'agent.
+type Provider interface {
+	// Name retourne le nom du fournisseur
+	Name() string
+
+	// NewChatRequest construit la requête HTTP de complétion de chat
+	NewChatRequest(ctx context.Context, target Target, request *types.ChatRequest) (*http.Request, error)
+
+	// DecodeResponse décode une réponse complète
+	DecodeResponse(body []byte) (*types.ChatResponse, error)
+
+	// DecodeStream lit une réponse en streaming, appelle onDelta pour chaque
+	// fragment de texte et retourne la réponse reconstituée
+	DecodeStream(resp *http.Response, onDelta func(string)) (*types.ChatResponse, error)
+
+	// NewModelsRequest construit la requête HTTP listant les modèles disponibles
+	NewModelsRequest(ctx context.Context, target Target) (*http.Request, error)
+
+	// DecodeModels décode la liste des modèles disponibles
+	DecodeModels(body []byte) (*types.ModelsResponse, error)
+}
+
+// Noms des fournisseurs pris en charge
+const (
+	ProviderOpenAI    = "openai"
+	ProviderAnthropic = "anthropic"
+	ProviderOllama    = "ollama"
+)
+
+// providers associe chaque nom de fournisseur à son adaptateur
+var providers = map[string]Provider{
+	ProviderOpenAI:    openAIProvider{},
+	ProviderAnthropic: anthropicProvider{},
+	ProviderOllama:    ollamaProvider{},
+}
+
+// ProviderFor retourne l'adaptateur d'un fournisseur ; un nom vide désigne
+// l'API compatible OpenAI
+func ProviderFor(name string) (Provider, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = ProviderOpenAI
+	}
+	p, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("fournisseur inconnu: %s (openai, anthropic, ollama)", name)
+	}
+	return p, nil
+}
+
+// newJSONRequest construit une requête HTTP dont le corps est sérialisé en JSON
+func newJSONRequest(ctx context.Context, method, url string, body interface{}) (*http.Request, error) {
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("erreur lors de la sérialisation de la requête: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("erreur lors de la création de la requête: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// decodeJSON désérialise une réponse en conservant le corps dans le message d'erreur
+func decodeJSON(body []byte, v interface{}) error {
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("erreur lors de la désérialisation de la réponse: %w - %s", err, string(body))
+	}
+	return nil
+}
+
+// parseDataURL décompose une data URL base64 (data:<type>;base64,<données>)
+func parseDataURL(url string) (mediaType, data string, ok bool) {
+	if !strings.HasPrefix(url, "data:") {
+		return "", "", false
+	}
+	comma := strings.Index(url, ",")
+	if comma < 0 {
+		return "", "", false
+	}
+	header := url[len("data:"):comma]
+	if !strings.HasSuffix(header, ";base64") {
+		return "", "", false
+	}
+	return strings.TrimSuffix(header, ";base64"), url[comma+1:], true
+}
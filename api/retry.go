@@ -0,0 +1,111 @@
+package api
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// maxErrorBody est la taille maximale du corps d'une réponse d'erreur conservée
+const maxErrorBody = 64 * 1024
+
+// RetryPolicy décrit les nouvelles tentatives en cas d'échec passager
+type RetryPolicy struct {
+	// Nombre total de tentatives (1 = aucune nouvelle tentative)
+	MaxAttempts int
+
+	// Délai avant la deuxième tentative, doublé à chaque échec
+	InitialBackoff time.Duration
+
+	// Délai maximal entre deux tentatives
+	MaxBackoff time.Duration
+
+	// Appelé avant chaque nouvelle tentative (peut être nil)
+	OnRetry func(attempt int, wait time.Duration, err *APIError)
+
+	// Appelé avant de passer à la cible de repli suivante (peut être nil)
+	OnFallback func(next Target, err *APIError)
+}
+
+// DefaultRetryPolicy retourne la politique de nouvelles tentatives par défaut
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
+	}
+}
+
+// backoff retourne l'attente avant la tentative suivante : croissance
+// exponentielle plafonnée, avec une part aléatoire pour étaler les tentatives
+func (p RetryPolicy) backoff(attempt int, err *APIError) time.Duration {
+	if err.RetryAfter > 0 {
+		// Le délai imposé par le fournisseur est respecté tel quel
+		return err.RetryAfter
+	}
+
+	wait := p.InitialBackoff
+	for i := 1; i < attempt && wait < p.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > p.MaxBackoff {
+		wait = p.MaxBackoff
+	}
+	if wait <= 0 {
+		return 0
+	}
+	// Attente aléatoire entre la moitié et la totalité du délai
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+// send envoie la requête construite par build, en la renouvelant selon la
+// politique de nouvelles tentatives. En cas de succès, l'appelant doit fermer
+// le corps de la réponse ; en cas d'échec, l'erreur retournée est une *APIError.
+func (c *Client) send(ctx context.Context, build func() (*http.Request, error)) (*http.Response, error) {
+	policy := c.retry
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		req, err := build()
+		if err != nil {
+			return nil, err
+		}
+
+		var apiErr *APIError
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			apiErr = newNetworkError(err)
+		} else if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+			resp.Body.Close()
+			apiErr = newStatusError(resp, body)
+		} else {
+			return resp, nil
+		}
+
+		if attempt >= policy.MaxAttempts || !apiErr.Retryable() {
+			return nil, apiErr
+		}
+
+		wait := policy.backoff(attempt, apiErr)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			// Le délai imposé dépasse le temps restant pour l'appel
+			return nil, apiErr
+		}
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt+1, wait, apiErr)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, apiErr
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"asione-agent/types"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxEventSize est la taille maximale d'une ligne d'événement SSE
+const maxEventSize = 1024 * 1024
+
+// readEventStream lit un flux Server-Sent Events et appelle onData pour chaque
+// charge utile "data:". La lecture s'arrête au marqueur [DONE] ou en fin de flux.
+func readEventStream(r io.Reader, onData func([]byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxEventSize)
+
+	var data bytes.Buffer
+	flush := func() (bool, error) {
+		if data.Len() == 0 {
+			return false, nil
+		}
+		payload := bytes.TrimSpace(data.Bytes())
+		data.Reset()
+		if string(payload) == "[DONE]" {
+			return true, nil
+		}
+		return false, onData(payload)
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Une ligne vide termine l'événement courant
+		if line == "" {
+			done, err := flush()
+			if err != nil || done {
+				return err
+			}
+			continue
+		}
+
+		// Les commentaires (":") et les champs event/id/retry sont ignorés
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		if data.Len() > 0 {
+			data.WriteByte('\n')
+		}
+		data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("erreur lors de la lecture du flux: %w", err)
+	}
+
+	// Dernier événement sans ligne vide finale
+	_, err := flush()
+	return err
+}
+
+// streamAccumulator reconstitue une réponse complète à partir des fragments reçus
+type streamAccumulator struct {
+	resp      types.ChatResponse
+	content   strings.Builder
+	toolCalls []types.ToolCall
+}
+
+// newStreamAccumulator crée un nouvel accumulateur de flux
+func newStreamAccumulator() *streamAccumulator {
+	return &streamAccumulator{}
+}
+
+// add intègre un fragment et retourne le texte nouvellement reçu
+func (a *streamAccumulator) add(chunk *types.ChatStreamChunk) (string, error) {
+	if a.resp.ID == "" {
+		a.resp.ID = chunk.ID
+		a.resp.Created = chunk.Created
+		a.resp.Model = chunk.Model
+	}
+	if chunk.Usage != nil {
+		a.resp.Usage = *chunk.Usage
+	}
+
+	var delta string
+	for _, choice := range chunk.Choices {
+		// Seul le premier choix est affiché et conservé
+		if choice.Index != 0 {
+			continue
+		}
+		delta += choice.Delta.Content
+		for _, call := range choice.Delta.ToolCalls {
+			if err := a.addToolCall(call); err != nil {
+				return "", err
+			}
+		}
+		if choice.FinishReason != "" {
+			a.ensureChoice().FinishReason = choice.FinishReason
+		}
+	}
+	a.content.WriteString(delta)
+	return delta, nil
+}
+
+// addToolCall fusionne un fragment d'appel d'outil : les arguments arrivent
+// morceau par morceau, rattachés à l'appel par leur index. Un index ne peut
+// désigner qu'un appel déjà reçu ou le suivant.
+func (a *streamAccumulator) addToolCall(call types.ToolCall) error {
+	idx := len(a.toolCalls)
+	if call.Index != nil {
+		idx = *call.Index
+	}
+	if idx < 0 || idx > len(a.toolCalls) {
+		return fmt.Errorf("index d'appel d'outil invalide dans le flux: %d (%d appel(s) reçu(s))", idx, len(a.toolCalls))
+	}
+	if idx == len(a.toolCalls) {
+		a.toolCalls = append(a.toolCalls, types.ToolCall{Type: "function"})
+	}
+
+	tc := &a.toolCalls[idx]
+	if call.ID != "" {
+		tc.ID = call.ID
+	}
+	if call.Type != "" {
+		tc.Type = call.Type
+	}
+	tc.Function.Name += call.Function.Name
+	tc.Function.Arguments += call.Function.Arguments
+	return nil
+}
+
+// ensureChoice retourne le choix principal de la réponse, en le créant si besoin
+func (a *streamAccumulator) ensureChoice() *types.Choice {
+	if len(a.resp.Choices) == 0 {
+		a.resp.Choices = append(a.resp.Choices, types.Choice{
+			Message: types.Message{Role: "assistant"},
+		})
+	}
+	return &a.resp.Choices[0]
+}
+
+// response retourne la réponse reconstituée
+func (a *streamAccumulator) response() *types.ChatResponse {
+	a.resp.Object = "chat.completion"
+	choice := a.ensureChoice()
+	choice.Message.Content = a.content.String()
+	for i := range a.toolCalls {
+		// Certains fournisseurs n'attribuent pas d'identifiant aux appels
+		if a.toolCalls[i].ID == "" {
+			a.toolCalls[i].ID = fmt.Sprintf("call_%d", i)
+		}
+	}
+	choice.Message.ToolCalls = a.toolCalls
+	return &a.resp
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// Target représente un point d'accès au modèle : fournisseur, URL, clé et modèle
+type Target struct {
+	BaseURL string
+	APIKey  string
+	Model   string
+
+	// Fournisseur (openai, anthropic, ollama) ; vide pour l'API compatible OpenAI
+	Provider string
+}
+
+// String retourne une description courte de la cible (modèle @ hôte)
+func (t Target) String() string {
+	host := t.BaseURL
+	if u, err := url.Parse(t.BaseURL); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	return t.Model + " @ " + host
+}
+
+// SetFallbacks définit les cibles de repli, essayées dans l'ordre lorsque la
+// cible principale reste indisponible après les nouvelles tentatives
+func (c *Client) SetFallbacks(targets []Target) {
+	c.fallbacks = targets
+}
+
+// Targets retourne la cible principale suivie des cibles de repli
+func (c *Client) Targets() []Target {
+	return append([]Target{c.primary}, c.fallbacks...)
+}
+
+// LastTarget retourne la cible qui a répondu au dernier appel réussi
+func (c *Client) LastTarget() Target {
+	return c.last
+}
+
+// sendChain envoie la requête à chaque cible tour à tour, tant que l'échec est
+// passager (surcharge, limite de requêtes, erreur serveur ou réseau). Les autres
+// erreurs (authentification, requête invalide) sont retournées immédiatement.
+func (c *Client) sendChain(ctx context.Context, build func(Target) (*http.Request, error)) (*http.Response, error) {
+	targets := c.Targets()
+	for i, target := range targets {
+		resp, err := c.send(ctx, func() (*http.Request, error) {
+			return build(target)
+		})
+		if err == nil {
+			c.last = target
+			return resp, nil
+		}
+
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || !apiErr.Retryable() || i == len(targets)-1 || ctx.Err() != nil {
+			return nil, err
+		}
+		if c.retry.OnFallback != nil {
+			c.retry.OnFallback(targets[i+1], apiErr)
+		}
+	}
+	return nil, errors.New("aucune cible configurée")
+}
@@ -0,0 +1,169 @@
+package attachment
+
+import (
+	"asione-agent/types"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limites de taille des pièces jointes
+const (
+	// MaxImageSize est la taille maximale d'une image (limite courante des fournisseurs)
+	MaxImageSize = 5 * 1024 * 1024
+
+	// MaxTextSize est la quantité de texte insérée dans la demande ; au-delà,
+	// le fichier est tronqué
+	MaxTextSize = 64 * 1024
+)
+
+// Types de pièces jointes
+const (
+	KindImage = "image"
+	KindText  = "texte"
+)
+
+// imageTypes liste les formats d'image acceptés par les fournisseurs
+var imageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// Attachment représente un fichier joint à la prochaine demande
+type Attachment struct {
+	Path      string
+	Kind      string
+	MediaType string
+	Size      int64
+
+	// Data URL base64 pour une image, contenu (éventuellement tronqué) pour un texte
+	Data      string
+	Truncated bool
+}
+
+// Load lit un fichier et prépare sa pièce jointe : les images sont encodées en
+// base64, les fichiers texte insérés tels quels dans la limite de MaxTextSize.
+// Les autres fichiers binaires sont refusés.
+func Load(path string) (*Attachment, error) {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("chemin invalide: %w", err)
+	}
+
+	info, err := os.Stat(abs)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("fichier introuvable: %s", path)
+		}
+		return nil, fmt.Errorf("erreur lors de la lecture du fichier: %w", err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s est un répertoire", path)
+	}
+
+	f, err := os.Open(abs)
+	if err != nil {
+		return nil, fmt.Errorf("erreur lors de la lecture du fichier: %w", err)
+	}
+	defer f.Close()
+
+	// Détecter le type à partir des premiers octets
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	head = head[:n]
+	mediaType := strings.SplitN(http.DetectContentType(head), ";", 2)[0]
+
+	att := &Attachment{Path: abs, MediaType: mediaType, Size: info.Size()}
+
+	if imageTypes[mediaType] {
+		if info.Size() > MaxImageSize {
+			return nil, fmt.Errorf("image trop volumineuse (%d octets, maximum %d)", info.Size(), MaxImageSize)
+		}
+		data, err := os.ReadFile(abs)
+		if err != nil {
+			return nil, fmt.Errorf("erreur lors de la lecture du fichier: %w", err)
+		}
+		att.Kind = KindImage
+		att.Data = "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(data)
+		return att, nil
+	}
+
+	// Lire une tranche de plus pour savoir si le fichier doit être tronqué
+	buf := make([]byte, MaxTextSize+1)
+	copy(buf, head)
+	rest, _ := io.ReadFull(f, buf[n:])
+	data := buf[:n+rest]
+	if len(data) > MaxTextSize {
+		data = data[:MaxTextSize]
+		att.Truncated = true
+	}
+	// Ne pas couper un caractère multi-octets en fin de tranche
+	for att.Truncated && len(data) > 0 && !utf8.Valid(data) {
+		data = data[:len(data)-1]
+	}
+	if !isText(data) {
+		return nil, fmt.Errorf("type de fichier non pris en charge (%s) : seuls les images et les fichiers texte peuvent être joints", mediaType)
+	}
+
+	att.Kind = KindText
+	att.MediaType = "text/plain"
+	att.Data = string(data)
+	return att, nil
+}
+
+// isText indique si des données ressemblent à du texte (UTF-8 valide, sans octet nul)
+func isText(data []byte) bool {
+	return utf8.Valid(data) && !bytes.Contains(data, []byte{0})
+}
+
+// Name retourne le nom du fichier
+func (a *Attachment) Name() string {
+	return filepath.Base(a.Path)
+}
+
+// Part retourne la partie de message correspondant à la pièce jointe
+func (a *Attachment) Part() types.ContentPart {
+	if a.Kind == KindImage {
+		return types.ContentPart{Type: types.PartImage, ImageURL: &types.ImageURL{URL: a.Data}}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Fichier joint : %s\n```\n%s", a.Path, a.Data)
+	if !strings.HasSuffix(a.Data, "\n") {
+		sb.WriteString("\n")
+	}
+	sb.WriteString("```")
+	if a.Truncated {
+		fmt.Fprintf(&sb, "\n(fichier tronqué : %d premiers octets sur %d)", len(a.Data), a.Size)
+	}
+	return types.ContentPart{Type: types.PartText, Text: sb.String()}
+}
+
+// Message compose le message utilisateur d'une demande accompagnée de pièces
+// jointes. Sans image, le message reste une simple chaîne, comprise par tous
+// les fournisseurs.
+func Message(prompt string, attachments []*Attachment) types.Message {
+	parts := []types.ContentPart{{Type: types.PartText, Text: prompt}}
+	hasImage := false
+	for _, a := range attachments {
+		parts = append(parts, a.Part())
+		hasImage = hasImage || a.Kind == KindImage
+	}
+	if !hasImage {
+		return types.Message{Role: "user", Content: types.PartsText(parts)}
+	}
+	return types.NewMultipartMessage("user", parts)
+}
@@ -0,0 +1,187 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxOutput est la taille maximale de la sortie conservée dans le journal
+const MaxOutput = 4 * 1024
+
+// Décisions de confirmation enregistrées
+const (
+	DecisionApproved     = "approved"
+	DecisionAutoApproved = "auto-approved"
+	DecisionConfirmed    = "typed-confirmed"
+	DecisionCancelled    = "cancelled"
+	DecisionSkipped      = "skipped"
+	DecisionDenied       = "denied"
+	DecisionDryRun       = "dry-run"
+)
+
+// Entry représente une commande proposée par le modèle et son devenir
+type Entry struct {
+	Timestamp  time.Time `json:"timestamp"`
+	SessionID  string    `json:"session_id"`
+	Prompt     string    `json:"prompt"`
+	Model      string    `json:"model"`
+	Response   string    `json:"response"`
+	Command    string    `json:"command"`
+	Risk       string    `json:"risk,omitempty"`
+	Decision   string    `json:"decision"`
+	Sudo       bool      `json:"sudo"`
+	Backend    string    `json:"backend,omitempty"`
+	Executed   bool      `json:"executed"`
+	ExitCode   int       `json:"exit_code"`
+	DurationMs int64     `json:"duration_ms"`
+	Output     string    `json:"output,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Logger écrit le journal d'audit au format JSON Lines, un fichier par jour,
+// en mode ajout uniquement
+type Logger struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// DefaultDir retourne le répertoire par défaut du journal d'audit
+func DefaultDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = "/home/user"
+	}
+	return filepath.Join(homeDir, ".cline", "audit")
+}
+
+// NewLogger crée un journal d'audit dans le répertoire donné
+func NewLogger(dir string) (*Logger, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("impossible de créer le répertoire d'audit: %w", err)
+	}
+	return &Logger{dir: dir}, nil
+}
+
+// Dir retourne le répertoire du journal
+func (l *Logger) Dir() string {
+	return l.dir
+}
+
+// Log ajoute une entrée au journal du jour
+func (l *Logger) Log(entry Entry) error {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	entry.Output = Truncate(entry.Output, MaxOutput)
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("erreur lors de la sérialisation de l'entrée d'audit: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	path := filepath.Join(l.dir, entry.Timestamp.Format("2006-01-02")+".jsonl")
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("impossible d'ouvrir le journal d'audit: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("erreur lors de l'écriture du journal d'audit: %w", err)
+	}
+	return nil
+}
+
+// Query décrit les critères de recherche dans le journal
+type Query struct {
+	// Jour recherché (zéro = tous les jours)
+	Date time.Time
+
+	// Uniquement les commandes exécutées en échec
+	Failed bool
+
+	// Code de sortie recherché (nil = indifférent)
+	ExitCode *int
+
+	// Texte recherché dans la commande, la demande ou la sortie
+	Text string
+
+	// Nombre maximal d'entrées retournées (les plus récentes)
+	Limit int
+}
+
+// Search retourne les entrées correspondant aux critères, de la plus ancienne à la plus récente
+func (l *Logger) Search(q Query) ([]Entry, error) {
+	pattern := "*.jsonl"
+	if !q.Date.IsZero() {
+		pattern = q.Date.Format("2006-01-02") + ".jsonl"
+	}
+	files, err := filepath.Glob(filepath.Join(l.dir, pattern))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	text := strings.ToLower(q.Text)
+	var entries []Entry
+	for _, path := range files {
+		err := readEntries(path, func(e Entry) {
+			if q.Failed && (!e.Executed || e.ExitCode == 0) {
+				return
+			}
+			if q.ExitCode != nil && (!e.Executed || e.ExitCode != *q.ExitCode) {
+				return
+			}
+			if text != "" && !strings.Contains(strings.ToLower(e.Command+"\n"+e.Prompt+"\n"+e.Output), text) {
+				return
+			}
+			entries = append(entries, e)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if q.Limit > 0 && len(entries) > q.Limit {
+		entries = entries[len(entries)-q.Limit:]
+	}
+	return entries, nil
+}
+
+// readEntries lit un fichier JSON Lines ; les lignes illisibles sont ignorées
+func readEntries(path string, fn func(Entry)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err == nil {
+			fn(e)
+		}
+	}
+	return scanner.Err()
+}
+
+// Truncate limite une chaîne à max octets en conservant le début et la fin
+func Truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	half := max / 2
+	return s[:half] + "\n[...tronqué...]\n" + s[len(s)-half:]
+}
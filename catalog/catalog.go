@@ -0,0 +1,132 @@
+package catalog
+
+import (
+	"asione-agent/types"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxSuggestionDistance est la distance d'édition au-delà de laquelle un modèle
+// n'est plus proposé comme correction
+const maxSuggestionDistance = 4
+
+// Sort trie les modèles par identifiant
+func Sort(models []types.Model) {
+	sort.Slice(models, func(i, j int) bool {
+		return models[i].ID < models[j].ID
+	})
+}
+
+// Filter retourne les modèles dont l'identifiant ou le propriétaire contient
+// tous les mots du filtre (sans tenir compte de la casse)
+func Filter(models []types.Model, query string) []types.Model {
+	words := strings.Fields(strings.ToLower(query))
+	if len(words) == 0 {
+		return models
+	}
+
+	var filtered []types.Model
+	for _, m := range models {
+		text := strings.ToLower(m.ID + " " + m.OwnedBy)
+		match := true
+		for _, w := range words {
+			if !strings.Contains(text, w) {
+				match = false
+				break
+			}
+		}
+		if match {
+			filtered = append(filtered, m)
+		}
+	}
+	return filtered
+}
+
+// Find retourne le modèle portant exactement ce nom ; à défaut, un modèle dont
+// le nom ne diffère que par la casse
+func Find(models []types.Model, name string) (types.Model, bool) {
+	for _, m := range models {
+		if m.ID == name {
+			return m, true
+		}
+	}
+	for _, m := range models {
+		if strings.EqualFold(m.ID, name) {
+			return m, true
+		}
+	}
+	return types.Model{}, false
+}
+
+// Suggest retourne au plus max noms de modèles proches de name, du plus proche
+// au plus éloigné. Un modèle dont le nom contient name (ou l'inverse) est
+// toujours proposé, avant ceux retenus pour leur seule distance d'édition.
+func Suggest(models []types.Model, name string, max int) []string {
+	type candidate struct {
+		id       string
+		distance int
+	}
+
+	name = strings.ToLower(strings.TrimSpace(name))
+	var candidates []candidate
+	for _, m := range models {
+		id := strings.ToLower(m.ID)
+		distance := levenshtein(name, id)
+		switch {
+		case strings.Contains(id, name) || strings.Contains(name, id):
+			// Sous-chaîne : classée avant toute correction de faute de frappe
+			distance = -1
+		case distance > maxSuggestionDistance || distance > utf8.RuneCountInString(name)/2+1:
+			continue
+		}
+		candidates = append(candidates, candidate{m.ID, distance})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].id < candidates[j].id
+	})
+
+	var suggestions []string
+	for _, c := range candidates {
+		if len(suggestions) == max {
+			break
+		}
+		suggestions = append(suggestions, c.id)
+	}
+	return suggestions
+}
+
+// levenshtein calcule la distance d'édition entre deux chaînes (en runes)
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, minInt(curr[j-1]+1, prev[j-1]+cost))
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// minInt retourne le plus petit de deux entiers
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
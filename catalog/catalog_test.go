@@ -0,0 +1,98 @@
+package catalog
+
+import (
+	"asione-agent/types"
+	"reflect"
+	"testing"
+)
+
+// testModels est le catalogue utilisé par les tests
+var testModels = []types.Model{
+	{ID: "gpt-4o", OwnedBy: "openai"},
+	{ID: "gpt-4o-mini", OwnedBy: "openai"},
+	{ID: "claude-3-5-sonnet", OwnedBy: "anthropic"},
+	{ID: "llama3", OwnedBy: "meta"},
+	{ID: "mistral-large", OwnedBy: "mistral"},
+	{ID: "Mistral-Large", OwnedBy: "mistral"},
+}
+
+// ids retourne les identifiants des modèles
+func ids(models []types.Model) []string {
+	var out []string
+	for _, m := range models {
+		out = append(out, m.ID)
+	}
+	return out
+}
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string // vide si aucun modèle ne doit être trouvé
+	}{
+		{name: "exact", query: "gpt-4o", want: "gpt-4o"},
+		{name: "casse différente", query: "GPT-4O-Mini", want: "gpt-4o-mini"},
+		{name: "exact avant la casse", query: "Mistral-Large", want: "Mistral-Large"},
+		{name: "sous-chaîne refusée", query: "gpt-4", want: ""},
+		{name: "inconnu", query: "gpt-5", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, ok := Find(testModels, tt.query)
+			if ok != (tt.want != "") || m.ID != tt.want {
+				t.Errorf("Find(%q) = %q, %v ; %q attendu", tt.query, m.ID, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		max   int
+		want  []string
+	}{
+		{name: "faute de frappe", query: "gpt4o", max: 5, want: []string{"gpt-4o"}},
+		{name: "casse ignorée", query: "LLAMA", max: 5, want: []string{"llama3"}},
+		{name: "sous-chaîne", query: "4o", max: 5, want: []string{"gpt-4o", "gpt-4o-mini"}},
+		{name: "sous-chaîne avant faute de frappe", query: "gpt-4o-mino", max: 5, want: []string{"gpt-4o", "gpt-4o-mini"}},
+		{name: "limite", query: "4o", max: 1, want: []string{"gpt-4o"}},
+		{name: "distance relative à la longueur", query: "lxa", max: 5, want: nil},
+		{name: "distance juste sous la limite", query: "lama", max: 5, want: []string{"llama3"}},
+		{name: "distance maximale dépassée", query: "claude-3-opus-x", max: 5, want: nil},
+		{name: "aucun modèle proche", query: "xyz", max: 5, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Suggest(testModels, tt.query, tt.max); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Suggest(%q, %d) = %q, %q attendu", tt.query, tt.max, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{name: "vide", query: "  ", want: ids(testModels)},
+		{name: "identifiant", query: "sonnet", want: []string{"claude-3-5-sonnet"}},
+		{name: "propriétaire et casse", query: "OpenAI", want: []string{"gpt-4o", "gpt-4o-mini"}},
+		{name: "tous les mots", query: "gpt mini", want: []string{"gpt-4o-mini"}},
+		{name: "aucun", query: "meta 4o", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ids(Filter(testModels, tt.query)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Filter(%q) = %q, %q attendu", tt.query, got, tt.want)
+			}
+		})
+	}
+}
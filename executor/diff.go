@@ -0,0 +1,178 @@
+package executor
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxDiffSize est la taille maximale d'un fichier pour l'affichage de son diff
+const maxDiffSize = 64 * 1024
+
+// diffContext est le nombre de lignes de contexte autour de chaque modification
+const diffContext = 2
+
+// Diff retourne un diff lisible de la modification d'un fichier, au format unifié
+func (r *SandboxRun) Diff(change Change) string {
+	var before, after []byte
+	var err error
+
+	if change.Kind != Added {
+		if before, err = readText(filepath.Join(r.WorkDir, change.Path)); err != nil {
+			return fmt.Sprintf("(%v)", err)
+		}
+	}
+	if change.Kind != Deleted {
+		if after, err = readText(filepath.Join(r.ScratchDir, change.Path)); err != nil {
+			return fmt.Sprintf("(%v)", err)
+		}
+	}
+
+	return unifiedDiff(change.Path, splitLines(before), splitLines(after))
+}
+
+// readText lit un fichier texte de taille raisonnable
+func readText(path string) ([]byte, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("répertoire")
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("fichier spécial")
+	}
+	if info.Size() > maxDiffSize {
+		return nil, fmt.Errorf("fichier trop volumineux pour afficher le diff")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !utf8.Valid(data) || bytes.IndexByte(data, 0) != -1 {
+		return nil, fmt.Errorf("fichier binaire")
+	}
+	return data, nil
+}
+
+// splitLines découpe un contenu en lignes
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+// diffOp représente une ligne du diff : ' ' commune, '-' supprimée, '+' ajoutée
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// unifiedDiff calcule le diff ligne à ligne (plus longue sous-séquence commune)
+// et le formate par blocs entourés de quelques lignes de contexte
+func unifiedDiff(name string, a, b []string) string {
+	ops := lineDiff(a, b)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("--- a/%s\n+++ b/%s\n", name, name))
+
+	for i := 0; i < len(ops); i++ {
+		if ops[i].kind == ' ' {
+			continue
+		}
+
+		// Début du bloc : quelques lignes de contexte avant la modification
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		for start < i && ops[start].kind != ' ' {
+			start++
+		}
+
+		// Fin du bloc : prolonger tant que les modifications sont proches
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != ' ' {
+				end = j
+			} else if j-end > 2*diffContext {
+				break
+			}
+		}
+		stop := end + diffContext + 1
+		if stop > len(ops) {
+			stop = len(ops)
+		}
+
+		sb.WriteString("@@\n")
+		for _, op := range ops[start:stop] {
+			sb.WriteByte(op.kind)
+			sb.WriteString(op.line)
+			sb.WriteByte('\n')
+		}
+		i = stop - 1
+	}
+
+	return sb.String()
+}
+
+// lineDiff retourne la suite d'opérations transformant a en b
+func lineDiff(a, b []string) []diffOp {
+	// Au-delà d'une certaine taille, afficher l'ancien puis le nouveau contenu
+	if len(a)*len(b) > 4000000 {
+		ops := make([]diffOp, 0, len(a)+len(b))
+		for _, line := range a {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, diffOp{'+', line})
+		}
+		return ops
+	}
+
+	// lcs[i][j] = longueur de la plus longue sous-séquence commune de a[i:] et b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
@@ -0,0 +1,169 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// DefaultMaxOutput est la taille maximale conservée pour chaque flux de sortie
+const DefaultMaxOutput = 16 * 1024
+
+// Backend exécute une commande shell (directement ou dans un bac à sable)
+type Backend interface {
+	// Name retourne le nom du mode d'exécution
+	Name() string
+
+	// Run exécute la commande et retourne son résultat
+	Run(ctx context.Context, command string, opts Options) (*Result, error)
+}
+
+// Result représente le résultat d'une commande exécutée
+type Result struct {
+	Command   string
+	Stdout    string
+	Stderr    string
+	ExitCode  int
+	Duration  time.Duration
+	Truncated bool
+
+	// Renseigné lorsque la commande a été exécutée dans un bac à sable
+	Sandbox *SandboxRun
+}
+
+// Success indique si la commande s'est terminée avec le code 0
+func (r *Result) Success() bool {
+	return r.ExitCode == 0
+}
+
+// Options contrôle l'exécution d'une commande
+type Options struct {
+	// Exécuter la commande via sudo -S (saisie du mot de passe sur stdin)
+	Sudo bool
+
+	// Utilisateur et groupe cibles de sudo (-u, -g) ; root par défaut
+	SudoUser  string
+	SudoGroup string
+
+	// Flux reliés au processus ; par défaut ceux du terminal
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Taille maximale conservée par flux (DefaultMaxOutput si nul)
+	MaxOutput int
+}
+
+// Local exécute les commandes directement sur le système
+type Local struct{}
+
+// Name implémente Backend
+func (Local) Name() string {
+	return "local"
+}
+
+// Run implémente Backend
+func (Local) Run(ctx context.Context, command string, opts Options) (*Result, error) {
+	return Run(ctx, command, opts)
+}
+
+// Run exécute une commande via sh -c. La sortie est affichée en direct et
+// capturée en parallèle (tee) pour pouvoir être renvoyée au modèle.
+// Un code de sortie non nul n'est pas une erreur : seule l'impossibilité
+// de lancer la commande en est une.
+func Run(ctx context.Context, command string, opts Options) (*Result, error) {
+	var cmd *exec.Cmd
+	if opts.Sudo {
+		args := []string{"-S"}
+		if opts.SudoUser != "" {
+			args = append(args, "-u", opts.SudoUser)
+		}
+		if opts.SudoGroup != "" {
+			args = append(args, "-g", opts.SudoGroup)
+		}
+		args = append(args, "--", "sh", "-c", command)
+		cmd = exec.CommandContext(ctx, "sudo", args...)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+
+	result, err := runCmd(cmd, opts)
+	if err != nil {
+		return nil, err
+	}
+	result.Command = command
+	return result, nil
+}
+
+// runCmd lance le processus en capturant ses sorties
+func runCmd(cmd *exec.Cmd, opts Options) (*Result, error) {
+	if opts.Stdin == nil {
+		opts.Stdin = os.Stdin
+	}
+	if opts.Stdout == nil {
+		opts.Stdout = os.Stdout
+	}
+	if opts.Stderr == nil {
+		opts.Stderr = os.Stderr
+	}
+	if opts.MaxOutput <= 0 {
+		opts.MaxOutput = DefaultMaxOutput
+	}
+
+	stdout := newTailBuffer(opts.MaxOutput)
+	stderr := newTailBuffer(opts.MaxOutput)
+	cmd.Stdin = opts.Stdin
+	cmd.Stdout = io.MultiWriter(opts.Stdout, stdout)
+	cmd.Stderr = io.MultiWriter(opts.Stderr, stderr)
+
+	start := time.Now()
+	err := cmd.Run()
+	result := &Result{
+		Stdout:    stdout.String(),
+		Stderr:    stderr.String(),
+		Duration:  time.Since(start),
+		Truncated: stdout.truncated || stderr.truncated,
+	}
+
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("impossible de lancer la commande: %w", err)
+		}
+		result.ExitCode = exitErr.ExitCode()
+	}
+
+	return result, nil
+}
+
+// tailBuffer conserve les derniers octets écrits, là où se trouvent
+// généralement les messages d'erreur
+type tailBuffer struct {
+	buf       []byte
+	max       int
+	truncated bool
+}
+
+// newTailBuffer crée un tampon limité à max octets
+func newTailBuffer(max int) *tailBuffer {
+	return &tailBuffer{max: max}
+}
+
+// Write implémente io.Writer
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > t.max {
+		t.buf = t.buf[len(t.buf)-t.max:]
+		t.truncated = true
+	}
+	return len(p), nil
+}
+
+// String retourne le contenu conservé
+func (t *tailBuffer) String() string {
+	return string(t.buf)
+}
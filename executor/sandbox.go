@@ -0,0 +1,416 @@
+package executor
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultMaxCopySize est la taille maximale du répertoire copié dans le bac à sable
+const DefaultMaxCopySize = 200 * 1024 * 1024
+
+// ChangeKind représente la nature d'une modification de fichier
+type ChangeKind string
+
+const (
+	// Added : fichier créé par la commande
+	Added ChangeKind = "ajouté"
+	// Modified : fichier modifié par la commande
+	Modified ChangeKind = "modifié"
+	// Deleted : fichier supprimé par la commande
+	Deleted ChangeKind = "supprimé"
+	// Replaced : entrée remplacée par une entrée d'un autre type (lien
+	// symbolique remplacé par un répertoire, répertoire par un fichier...)
+	Replaced ChangeKind = "remplacé"
+)
+
+// Change représente la modification d'un fichier du répertoire de travail
+type Change struct {
+	Path string
+	Kind ChangeKind
+}
+
+// SandboxRun représente l'exécution d'une commande dans le bac à sable :
+// les modifications restent dans la copie de travail tant qu'elles ne sont pas appliquées
+type SandboxRun struct {
+	WorkDir    string
+	ScratchDir string
+	Changes    []Change
+	Applied    bool
+}
+
+// Sandbox exécute les commandes dans un environnement jetable : racine en lecture
+// seule, copie modifiable du répertoire de travail et réseau coupé par défaut.
+// bubblewrap (bwrap) est utilisé s'il est installé, unshare sinon.
+type Sandbox struct {
+	WorkDir     string
+	Network     bool
+	MaxCopySize int64
+}
+
+// NewSandbox crée un bac à sable pour le répertoire de travail donné
+func NewSandbox(workDir string, network bool) (*Sandbox, error) {
+	abs, err := filepath.Abs(workDir)
+	if err != nil {
+		return nil, fmt.Errorf("répertoire de travail invalide: %w", err)
+	}
+	if _, err := sandboxTool(); err != nil {
+		return nil, err
+	}
+	return &Sandbox{
+		WorkDir:     abs,
+		Network:     network,
+		MaxCopySize: DefaultMaxCopySize,
+	}, nil
+}
+
+// Name implémente Backend
+func (s *Sandbox) Name() string {
+	tool, _ := sandboxTool()
+	return "sandbox (" + tool + ")"
+}
+
+// sandboxTool retourne l'outil d'isolation disponible
+func sandboxTool() (string, error) {
+	if _, err := exec.LookPath("bwrap"); err == nil {
+		return "bwrap", nil
+	}
+	if _, err := exec.LookPath("unshare"); err == nil {
+		return "unshare", nil
+	}
+	return "", fmt.Errorf("aucun outil d'isolation disponible (installez bubblewrap ou util-linux)")
+}
+
+// Run implémente Backend. La commande s'exécute sur une copie du répertoire de
+// travail ; les fichiers modifiés sont listés dans Result.Sandbox.
+func (s *Sandbox) Run(ctx context.Context, command string, opts Options) (*Result, error) {
+	if opts.Sudo {
+		return nil, fmt.Errorf("sudo n'est pas disponible dans le bac à sable")
+	}
+
+	scratch, err := os.MkdirTemp("", "asione-sandbox-")
+	if err != nil {
+		return nil, fmt.Errorf("impossible de créer le répertoire du bac à sable: %w", err)
+	}
+	if err := copyTree(s.WorkDir, scratch, s.MaxCopySize); err != nil {
+		os.RemoveAll(scratch)
+		return nil, err
+	}
+
+	cmd, err := s.command(ctx, scratch, command)
+	if err != nil {
+		os.RemoveAll(scratch)
+		return nil, err
+	}
+
+	result, err := runCmd(cmd, opts)
+	if err != nil {
+		os.RemoveAll(scratch)
+		return nil, err
+	}
+	result.Command = command
+
+	changes, err := compareTrees(s.WorkDir, scratch)
+	if err != nil {
+		os.RemoveAll(scratch)
+		return nil, err
+	}
+	result.Sandbox = &SandboxRun{
+		WorkDir:    s.WorkDir,
+		ScratchDir: scratch,
+		Changes:    changes,
+	}
+	return result, nil
+}
+
+// command construit la commande d'isolation
+func (s *Sandbox) command(ctx context.Context, scratch, command string) (*exec.Cmd, error) {
+	tool, err := sandboxTool()
+	if err != nil {
+		return nil, err
+	}
+
+	if tool == "bwrap" {
+		args := []string{
+			"--ro-bind", "/", "/",
+			"--dev", "/dev",
+			"--proc", "/proc",
+			"--tmpfs", "/tmp",
+			"--bind", scratch, s.WorkDir,
+			"--chdir", s.WorkDir,
+			"--unshare-all",
+			"--die-with-parent",
+		}
+		if s.Network {
+			args = append(args, "--share-net")
+		}
+		args = append(args, "sh", "-c", command)
+		return exec.CommandContext(ctx, "bwrap", args...), nil
+	}
+
+	// unshare : voir unshareScript
+	args := []string{"--user", "--map-root-user", "--mount", "--fork"}
+	if !s.Network {
+		args = append(args, "--net")
+	}
+	args = append(args, "sh", "-c", unshareScript, "sandbox", scratch, s.WorkDir, command)
+	return exec.CommandContext(ctx, "unshare", args...), nil
+}
+
+// unshareScript prépare les montages du bac à sable lorsque bwrap est absent :
+// la copie est montée sur le répertoire de travail, puis chaque point de
+// montage (la racine comme /home, /sys, /boot...) est remonté en lecture seule
+// en conservant ses options verrouillées (nosuid, nodev...), et /tmp est
+// remplacé par un tmpfs. Si un montage ne peut pas être protégé, la commande
+// n'est pas exécutée.
+const unshareScript = `set -e
+mount --make-rprivate /
+mount --bind "$1" "$2"
+while read -r dev mnt fstype opts rest; do
+	mnt=$(printf '%b' "$mnt")
+	[ "$mnt" = "$2" ] && continue
+	flags=remount,bind,ro
+	for o in $(echo "$opts" | tr , ' '); do
+		case "$o" in nosuid|nodev|noexec|noatime|nodiratime|relatime|strictatime) flags="$flags,$o" ;; esac
+	done
+	mount -o "$flags" "$mnt" || { echo "bac à sable : impossible de protéger $mnt" >&2; exit 125; }
+done < /proc/self/mounts
+case "$2/" in
+/tmp/*) ;;
+*) mount -t tmpfs tmpfs /tmp ;;
+esac
+cd "$2"
+exec sh -c "$3"`
+
+// Apply recopie les modifications du bac à sable dans le répertoire réel. Les
+// suppressions sont appliquées en premier, des entrées les plus profondes aux
+// moins profondes, pour qu'un répertoire soit vidé avant d'être remplacé.
+// Aucun chemin passant par un lien symbolique n'est modifié.
+func (r *SandboxRun) Apply() error {
+	for i := len(r.Changes) - 1; i >= 0; i-- {
+		change := r.Changes[i]
+		if change.Kind != Deleted {
+			continue
+		}
+		if err := checkParents(r.WorkDir, change.Path); err != nil {
+			return err
+		}
+		if err := os.RemoveAll(filepath.Join(r.WorkDir, change.Path)); err != nil {
+			return fmt.Errorf("impossible de supprimer %s: %w", change.Path, err)
+		}
+	}
+
+	for _, change := range r.Changes {
+		if change.Kind == Deleted {
+			continue
+		}
+		if err := checkParents(r.WorkDir, change.Path); err != nil {
+			return err
+		}
+		dst := filepath.Join(r.WorkDir, change.Path)
+		src := filepath.Join(r.ScratchDir, change.Path)
+
+		if change.Kind == Replaced {
+			if err := os.RemoveAll(dst); err != nil {
+				return fmt.Errorf("impossible de remplacer %s: %w", change.Path, err)
+			}
+		}
+		if err := copyEntry(src, dst); err != nil {
+			return fmt.Errorf("impossible d'appliquer %s: %w", change.Path, err)
+		}
+	}
+	r.Applied = true
+	return nil
+}
+
+// checkParents vérifie qu'aucun répertoire parent du chemin relatif rel n'est,
+// sous root, un lien symbolique : une écriture pourrait sinon sortir de root
+func checkParents(root, rel string) error {
+	dir := root
+	for _, part := range strings.Split(filepath.Dir(rel), string(filepath.Separator)) {
+		if part == "." {
+			continue
+		}
+		dir = filepath.Join(dir, part)
+		info, err := os.Lstat(dir)
+		if os.IsNotExist(err) {
+			// Répertoire à créer : ses sous-répertoires n'existent pas non plus
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("impossible d'appliquer %s: %w", rel, err)
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("modification de %s refusée : le chemin passe par un lien symbolique", rel)
+		}
+	}
+	return nil
+}
+
+// Discard supprime la copie de travail du bac à sable
+func (r *SandboxRun) Discard() error {
+	return os.RemoveAll(r.ScratchDir)
+}
+
+// copyTree copie récursivement src dans dst, dans la limite de maxSize octets
+func copyTree(src, dst string, maxSize int64) error {
+	var total int64
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			total += info.Size()
+			if total > maxSize {
+				return fmt.Errorf("répertoire de travail trop volumineux pour le bac à sable (> %d Mo)", maxSize/(1024*1024))
+			}
+		}
+		return copyEntry(path, filepath.Join(dst, rel))
+	})
+}
+
+// copyEntry copie un fichier, un répertoire (sans son contenu) ou un lien symbolique
+func copyEntry(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case info.IsDir():
+		return os.MkdirAll(dst, info.Mode().Perm())
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		os.Remove(dst)
+		return os.Symlink(target, dst)
+	case !info.Mode().IsRegular():
+		// Les fichiers spéciaux (sockets, fifos) ne sont pas copiés
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chmod(dst, info.Mode().Perm())
+}
+
+// fileState résume l'état d'une entrée pour la comparaison
+type fileState struct {
+	mode os.FileMode
+	hash string
+}
+
+// snapshot indexe l'état de chaque entrée d'une arborescence par chemin relatif
+func snapshot(root string) (map[string]fileState, error) {
+	states := make(map[string]fileState)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == "." {
+			return err
+		}
+
+		state := fileState{mode: info.Mode()}
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			state.hash = target
+		case info.Mode().IsRegular():
+			hash, err := hashFile(path)
+			if err != nil {
+				return err
+			}
+			state.hash = hash
+		}
+		states[rel] = state
+		return nil
+	})
+	return states, err
+}
+
+// hashFile calcule l'empreinte SHA-256 d'un fichier
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// compareTrees liste les différences entre le répertoire original et sa copie
+func compareTrees(original, scratch string) ([]Change, error) {
+	before, err := snapshot(original)
+	if err != nil {
+		return nil, fmt.Errorf("erreur lors de l'analyse du répertoire de travail: %w", err)
+	}
+	after, err := snapshot(scratch)
+	if err != nil {
+		return nil, fmt.Errorf("erreur lors de l'analyse du bac à sable: %w", err)
+	}
+
+	var changes []Change
+	for path, state := range after {
+		old, existed := before[path]
+		switch {
+		case !existed:
+			changes = append(changes, Change{Path: path, Kind: Added})
+		case old.mode.Type() != state.mode.Type():
+			changes = append(changes, Change{Path: path, Kind: Replaced})
+		case old.hash != state.hash || old.mode != state.mode:
+			if !state.mode.IsDir() {
+				changes = append(changes, Change{Path: path, Kind: Modified})
+			}
+		}
+	}
+	for path := range before {
+		if _, exists := after[path]; !exists {
+			changes = append(changes, Change{Path: path, Kind: Deleted})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes, nil
+}
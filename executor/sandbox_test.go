@@ -0,0 +1,157 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFile crée un fichier de test et ses répertoires parents
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// symlink crée un lien symbolique de test
+func symlink(t *testing.T, target, path string) {
+	t.Helper()
+	if err := os.Symlink(target, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newRun prépare un répertoire de travail, une copie modifiée et un répertoire
+// extérieur qui ne doit jamais être touché
+func newRun(t *testing.T) (run *SandboxRun, outside string) {
+	t.Helper()
+	root := t.TempDir()
+	run = &SandboxRun{WorkDir: filepath.Join(root, "work"), ScratchDir: filepath.Join(root, "scratch")}
+	outside = filepath.Join(root, "outside")
+	for _, dir := range []string{run.WorkDir, run.ScratchDir, outside} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(t, filepath.Join(outside, "x"), "extérieur")
+	return run, outside
+}
+
+// compare calcule les modifications du bac à sable et vérifie leur liste
+func compare(t *testing.T, run *SandboxRun, want []Change) {
+	t.Helper()
+	changes, err := compareTrees(run.WorkDir, run.ScratchDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("modifications = %+v, %+v attendu", changes, want)
+	}
+	run.Changes = changes
+}
+
+// checkOutside vérifie que le répertoire extérieur n'a pas été modifié
+func checkOutside(t *testing.T, outside string) {
+	t.Helper()
+	entries, err := os.ReadDir(outside)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(outside, "x"))
+	if err != nil || string(data) != "extérieur" || len(entries) != 1 {
+		t.Errorf("répertoire extérieur modifié : %d entrée(s), x = %q (%v)", len(entries), data, err)
+	}
+}
+
+func TestCompareAndApply(t *testing.T) {
+	run, outside := newRun(t)
+	writeFile(t, filepath.Join(run.WorkDir, "same.txt"), "a")
+	writeFile(t, filepath.Join(run.WorkDir, "edit.txt"), "avant")
+	writeFile(t, filepath.Join(run.WorkDir, "old", "gone.txt"), "b")
+	writeFile(t, filepath.Join(run.ScratchDir, "same.txt"), "a")
+	writeFile(t, filepath.Join(run.ScratchDir, "edit.txt"), "après")
+	writeFile(t, filepath.Join(run.ScratchDir, "new", "file.txt"), "c")
+
+	compare(t, run, []Change{
+		{Path: "edit.txt", Kind: Modified},
+		{Path: "new", Kind: Added},
+		{Path: filepath.Join("new", "file.txt"), Kind: Added},
+		{Path: "old", Kind: Deleted},
+		{Path: filepath.Join("old", "gone.txt"), Kind: Deleted},
+	})
+	if err := run.Apply(); err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := compareTrees(run.WorkDir, run.ScratchDir)
+	if err != nil || len(after) != 0 {
+		t.Errorf("différences après application : %+v (%v)", after, err)
+	}
+	checkOutside(t, outside)
+}
+
+func TestApplySymlinkReplacedByDirectory(t *testing.T) {
+	// La commande remplace un lien vers l'extérieur par un répertoire et y écrit :
+	// le fichier doit être créé dans le répertoire de travail, pas à travers le lien
+	run, outside := newRun(t)
+	symlink(t, outside, filepath.Join(run.WorkDir, "link"))
+	writeFile(t, filepath.Join(run.ScratchDir, "link", "x"), "piégé")
+
+	compare(t, run, []Change{
+		{Path: "link", Kind: Replaced},
+		{Path: filepath.Join("link", "x"), Kind: Added},
+	})
+	if err := run.Apply(); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Lstat(filepath.Join(run.WorkDir, "link"))
+	if err != nil || !info.IsDir() {
+		t.Errorf("link n'est pas devenu un répertoire (%v)", err)
+	}
+	checkOutside(t, outside)
+}
+
+func TestApplyDirectoryReplacedBySymlink(t *testing.T) {
+	// Le contenu du répertoire remplacé est supprimé avant la création du lien,
+	// jamais à travers lui
+	run, outside := newRun(t)
+	writeFile(t, filepath.Join(run.WorkDir, "dir", "x"), "local")
+	symlink(t, outside, filepath.Join(run.ScratchDir, "dir"))
+
+	compare(t, run, []Change{
+		{Path: "dir", Kind: Replaced},
+		{Path: filepath.Join("dir", "x"), Kind: Deleted},
+	})
+	if err := run.Apply(); err != nil {
+		t.Fatal(err)
+	}
+
+	if target, err := os.Readlink(filepath.Join(run.WorkDir, "dir")); err != nil || target != outside {
+		t.Errorf("lien = %q (%v)", target, err)
+	}
+	checkOutside(t, outside)
+}
+
+func TestApplyRefusesSymlinkParent(t *testing.T) {
+	for _, kind := range []ChangeKind{Added, Modified, Replaced, Deleted} {
+		t.Run(string(kind), func(t *testing.T) {
+			run, outside := newRun(t)
+			symlink(t, outside, filepath.Join(run.WorkDir, "link"))
+			writeFile(t, filepath.Join(run.ScratchDir, "link", "x"), "piégé")
+			run.Changes = []Change{{Path: filepath.Join("link", "x"), Kind: kind}}
+
+			err := run.Apply()
+			if err == nil || !strings.Contains(err.Error(), "lien symbolique") {
+				t.Errorf("erreur = %v", err)
+			}
+			checkOutside(t, outside)
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package history
+
+import (
+	"asione-agent/types"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SummaryPrefix introduit les messages de résumé insérés par le gestionnaire
+const SummaryPrefix = "Résumé de la conversation précédente :\n"
+
+// Valeurs par défaut du gestionnaire de contexte
+const (
+	// Part de la fenêtre réservée à la réponse du modèle lorsque max_tokens
+	// n'est pas connu
+	DefaultReserveRatio = 0.25
+
+	// Part maximale de la fenêtre réservée à la réponse : au-delà, max_tokens
+	// est réduit pour laisser de la place à l'historique
+	maxReserveRatio = 0.5
+
+	// Marge couvrant l'imprécision de l'estimation du nombre de tokens
+	safetyMargin = 256
+
+	// Nombre minimal de tokens demandés pour la réponse
+	minResponseTokens = 256
+
+	// Taux de remplissage de la fenêtre déclenchant le résumé
+	DefaultThreshold = 0.8
+
+	// Nombre de tours récents conservés tels quels
+	DefaultKeepTurns = 3
+
+	// Taille maximale d'un message dans la transcription à résumer
+	transcriptMessageChars = 2000
+
+	// Estimation de la taille du résumé produit par le modèle
+	summaryAllowance = 1000
+
+	// Taille conservée des sorties d'outils anciennes lorsqu'aucun tour ne peut être résumé
+	shrunkToolChars = 500
+)
+
+// SummarizeFunc produit le résumé d'une transcription de conversation
+type SummarizeFunc func(ctx context.Context, transcript string) (string, error)
+
+// Manager surveille la taille de l'historique et résume les anciens tours
+// lorsque la fenêtre de contexte du modèle est presque pleine. Le prompt système
+// et les tours récents sont toujours conservés tels quels.
+type Manager struct {
+	// Taille de la fenêtre de contexte du modèle, en tokens
+	Limit int
+
+	// Nombre maximal de tokens générés par réponse (max_tokens), réservés
+	// dans la fenêtre de contexte
+	MaxTokens int
+
+	// Taux de remplissage (hors réserve pour la réponse) déclenchant le résumé
+	Threshold float64
+
+	// Nombre de tours récents conservés tels quels
+	KeepTurns int
+
+	// Fonction de résumé, généralement un appel au modèle
+	Summarize SummarizeFunc
+}
+
+// NewManager crée un gestionnaire de contexte avec les réglages par défaut
+func NewManager(limit, maxTokens int, summarize SummarizeFunc) *Manager {
+	return &Manager{
+		Limit:     limit,
+		MaxTokens: maxTokens,
+		Threshold: DefaultThreshold,
+		KeepTurns: DefaultKeepTurns,
+		Summarize: summarize,
+	}
+}
+
+// Usage décrit l'occupation de la fenêtre de contexte
+type Usage struct {
+	Tokens    int
+	Limit     int
+	Budget    int
+	Messages  int
+	Summaries int
+}
+
+// Percent retourne le taux d'occupation de la fenêtre de contexte
+func (u Usage) Percent() float64 {
+	if u.Limit == 0 {
+		return 0
+	}
+	return float64(u.Tokens) * 100 / float64(u.Limit)
+}
+
+// Reserve retourne le nombre de tokens réservés à la réponse : max_tokens,
+// plafonné à la moitié de la fenêtre de contexte
+func (m *Manager) Reserve() int {
+	if m.MaxTokens <= 0 {
+		return int(float64(m.Limit) * DefaultReserveRatio)
+	}
+	if ceiling := int(float64(m.Limit) * maxReserveRatio); m.MaxTokens > ceiling {
+		return ceiling
+	}
+	return m.MaxTokens
+}
+
+// budget retourne le nombre de tokens utilisables par l'historique
+func (m *Manager) budget() int {
+	budget := m.Limit - m.Reserve() - safetyMargin
+	if budget < 0 {
+		return 0
+	}
+	return budget
+}
+
+// ResponseTokens ramène maxTokens à la place laissée dans la fenêtre de
+// contexte par les messages envoyés, afin que la requête ne dépasse pas la
+// limite du modèle
+func (m *Manager) ResponseTokens(messages []types.Message, maxTokens int) int {
+	room := m.Limit - EstimateMessages(messages) - safetyMargin
+	if room < minResponseTokens {
+		room = minResponseTokens
+	}
+	if maxTokens <= 0 || maxTokens > room {
+		return room
+	}
+	return maxTokens
+}
+
+// trigger retourne le nombre de tokens au-delà duquel l'historique est résumé
+func (m *Manager) trigger() int {
+	return int(float64(m.budget()) * m.Threshold)
+}
+
+// Usage calcule l'occupation de la fenêtre de contexte par l'historique
+func (m *Manager) Usage(messages []types.Message) Usage {
+	usage := Usage{
+		Tokens:   EstimateMessages(messages),
+		Limit:    m.Limit,
+		Budget:   m.budget(),
+		Messages: len(messages),
+	}
+	for _, msg := range messages {
+		if IsSummary(msg) {
+			usage.Summaries++
+		}
+	}
+	return usage
+}
+
+// NeedsCompaction indique si l'historique approche de la limite du modèle
+func (m *Manager) NeedsCompaction(messages []types.Message) bool {
+	return EstimateMessages(messages) > m.trigger()
+}
+
+// IsSummary indique si le message est un résumé inséré par le gestionnaire
+func IsSummary(msg types.Message) bool {
+	return msg.Role == "system" && strings.HasPrefix(msg.Content, SummaryPrefix)
+}
+
+// Compact résume les tours les plus anciens de l'historique si nécessaire (ou
+// toujours si force est vrai). L'historique n'est découpé qu'au début d'un tour
+// (message utilisateur), afin de ne jamais séparer un appel d'outil de son résultat.
+// Il retourne le nouvel historique et le nombre de messages résumés.
+func (m *Manager) Compact(ctx context.Context, messages []types.Message, force bool) ([]types.Message, int, error) {
+	if !force && !m.NeedsCompaction(messages) {
+		return messages, 0, nil
+	}
+
+	// Le prompt système d'origine est toujours conservé
+	var head []types.Message
+	rest := messages
+	if len(rest) > 0 && rest[0].Role == "system" && !IsSummary(rest[0]) {
+		head, rest = rest[:1], rest[1:]
+	}
+
+	cut := m.cutIndex(head, rest)
+	if cut <= 0 {
+		// Un seul tour en cours : réduire les anciennes sorties d'outils à la place
+		shrunk, n := shrinkToolOutputs(messages)
+		if n == 0 {
+			return messages, 0, fmt.Errorf("l'historique ne peut pas être réduit davantage")
+		}
+		return shrunk, 0, nil
+	}
+
+	if m.Summarize == nil {
+		return messages, 0, fmt.Errorf("aucune fonction de résumé configurée")
+	}
+	summary, err := m.Summarize(ctx, Transcript(rest[:cut], m.budget()/2))
+	if err != nil {
+		return messages, 0, fmt.Errorf("erreur lors du résumé de l'historique: %w", err)
+	}
+
+	compacted := make([]types.Message, 0, len(head)+1+len(rest)-cut)
+	compacted = append(compacted, head...)
+	compacted = append(compacted, types.Message{
+		Role:    "system",
+		Content: SummaryPrefix + strings.TrimSpace(summary),
+	})
+	compacted = append(compacted, rest[cut:]...)
+	return compacted, cut, nil
+}
+
+// cutIndex choisit le début des tours conservés : le plus grand nombre de tours
+// récents (au plus KeepTurns) qui tient dans le budget après résumé
+func (m *Manager) cutIndex(head, rest []types.Message) int {
+	var starts []int
+	for i, msg := range rest {
+		if msg.Role == "user" {
+			starts = append(starts, i)
+		}
+	}
+
+	target := m.trigger() - EstimateMessages(head) - summaryAllowance
+	best := -1
+	for keep := 1; keep <= m.KeepTurns && keep <= len(starts); keep++ {
+		cut := starts[len(starts)-keep]
+		if cut == 0 {
+			break
+		}
+		if best != -1 && EstimateMessages(rest[cut:]) > target {
+			break
+		}
+		best = cut
+	}
+	return best
+}
+
+// shrinkToolOutputs tronque les sorties d'outils, sauf les deux dernières
+func shrinkToolOutputs(messages []types.Message) ([]types.Message, int) {
+	result := append([]types.Message{}, messages...)
+	seen := 0
+	shrunk := 0
+	for i := len(result) - 1; i >= 0; i-- {
+		if result[i].Role != "tool" {
+			continue
+		}
+		seen++
+		if seen <= 2 || len(result[i].Content) <= shrunkToolChars {
+			continue
+		}
+		result[i].Content = result[i].Content[:shrunkToolChars] + "\n[...sortie tronquée pour libérer le contexte...]"
+		shrunk++
+	}
+	return result, shrunk
+}
+
+// Transcript met en forme des messages pour les faire résumer. Au-delà de
+// maxTokens, les messages les plus anciens sont omis.
+func Transcript(messages []types.Message, maxTokens int) string {
+	lines := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		var line string
+		switch {
+		case IsSummary(msg):
+			line = "[Résumé antérieur] " + strings.TrimPrefix(msg.Content, SummaryPrefix)
+		case msg.Role == "user":
+			line = "Utilisateur : " + clip(msg.Content)
+			if n := len(msg.Images()); n > 0 {
+				line += fmt.Sprintf(" [%d image(s) jointe(s)]", n)
+			}
+		case msg.Role == "assistant":
+			line = "Assistant : " + clip(msg.Content)
+			for _, call := range msg.ToolCalls {
+				line += fmt.Sprintf("\n  → %s %s", call.Function.Name, clip(call.Function.Arguments))
+			}
+		case msg.Role == "tool":
+			line = fmt.Sprintf("Résultat (%s) : %s", msg.Name, clip(msg.Content))
+		default:
+			line = "Système : " + clip(msg.Content)
+		}
+		lines = append(lines, line)
+	}
+
+	// Conserver les messages les plus récents dans la limite donnée
+	total := 0
+	first := len(lines)
+	for first > 0 {
+		cost := EstimateTokens(lines[first-1])
+		if maxTokens > 0 && total+cost > maxTokens {
+			break
+		}
+		total += cost
+		first--
+	}
+
+	transcript := strings.Join(lines[first:], "\n\n")
+	if first > 0 {
+		transcript = "[...début de la conversation omis...]\n\n" + transcript
+	}
+	return transcript
+}
+
+// clip limite la taille d'un message dans la transcription
+func clip(s string) string {
+	s = strings.TrimSpace(s)
+	runes := []rune(s)
+	if len(runes) <= transcriptMessageChars {
+		return s
+	}
+	return string(runes[:transcriptMessageChars]) + " [...]"
+}
@@ -0,0 +1,53 @@
+package history
+
+import (
+	"asione-agent/types"
+	"strings"
+	"testing"
+)
+
+func TestManagerBudget(t *testing.T) {
+	tests := []struct {
+		name      string
+		limit     int
+		maxTokens int
+		reserve   int
+		budget    int
+	}{
+		{name: "max_tokens réservé", limit: 32000, maxTokens: 4096, reserve: 4096, budget: 32000 - 4096 - safetyMargin},
+		{name: "max_tokens inconnu", limit: 32000, maxTokens: 0, reserve: 8000, budget: 32000 - 8000 - safetyMargin},
+		// max_tokens par défaut égal à la fenêtre d'un modèle inconnu
+		{name: "max_tokens plafonné", limit: 8192, maxTokens: 8192, reserve: 4096, budget: 8192 - 4096 - safetyMargin},
+		{name: "fenêtre minuscule", limit: 200, maxTokens: 50, reserve: 50, budget: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager(tt.limit, tt.maxTokens, nil)
+			if got := m.Reserve(); got != tt.reserve {
+				t.Errorf("réserve = %d, %d attendue", got, tt.reserve)
+			}
+			if got := m.Usage(nil).Budget; got != tt.budget {
+				t.Errorf("budget = %d, %d attendu", got, tt.budget)
+			}
+		})
+	}
+}
+
+func TestManagerResponseTokens(t *testing.T) {
+	m := NewManager(8192, 8192, nil)
+	messages := []types.Message{{Role: "user", Content: strings.Repeat("mot ", 4000)}}
+	room := 8192 - EstimateMessages(messages) - safetyMargin
+
+	if got := m.ResponseTokens(messages, 8192); got != room {
+		t.Errorf("max_tokens = %d, %d attendu", got, room)
+	}
+	if got := m.ResponseTokens(messages, 100); got != 100 {
+		t.Errorf("max_tokens = %d, 100 attendu", got)
+	}
+	// Un historique qui remplit déjà la fenêtre laisse une réponse minimale
+	full := []types.Message{{Role: "user", Content: strings.Repeat("mot ", 20000)}}
+	if got := m.ResponseTokens(full, 8192); got != minResponseTokens {
+		t.Errorf("max_tokens = %d, %d attendu", got, minResponseTokens)
+	}
+}
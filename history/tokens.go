@@ -0,0 +1,94 @@
+package history
+
+import (
+	"asione-agent/types"
+	"strings"
+	"unicode/utf8"
+)
+
+// messageOverhead est le coût approximatif, en tokens, de l'enveloppe d'un message
+// (rôle, séparateurs) dans le format de conversation des modèles
+const messageOverhead = 4
+
+// DefaultContextLimit est la fenêtre de contexte supposée pour un modèle inconnu
+const DefaultContextLimit = 8192
+
+// contextLimits associe un fragment de nom de modèle à la taille de sa fenêtre
+// de contexte. Les entrées les plus spécifiques doivent précéder les plus générales.
+var contextLimits = []struct {
+	pattern string
+	limit   int
+}{
+	{"asi1", 32000},
+	{"gpt-4o", 128000},
+	{"gpt-4.1", 1000000},
+	{"gpt-4-turbo", 128000},
+	{"gpt-4-32k", 32768},
+	{"gpt-4", 8192},
+	{"gpt-3.5-turbo", 16385},
+	{"o1", 128000},
+	{"o3", 200000},
+	{"claude", 200000},
+	{"gemini", 1000000},
+	{"llama-3.1", 128000},
+	{"llama3.1", 128000},
+	{"llama-3", 8192},
+	{"llama3", 8192},
+	{"mixtral", 32768},
+	{"mistral", 32768},
+	{"qwen", 32768},
+	{"deepseek", 64000},
+}
+
+// ContextLimit retourne la taille de la fenêtre de contexte connue pour un modèle
+func ContextLimit(model string) int {
+	model = strings.ToLower(model)
+	for _, entry := range contextLimits {
+		if strings.Contains(model, entry.pattern) {
+			return entry.limit
+		}
+	}
+	return DefaultContextLimit
+}
+
+// EstimateTokens estime le nombre de tokens d'un texte. Les tokenizers courants
+// produisent environ un token pour 4 caractères en anglais ; le français et les
+// caractères non ASCII sont plus coûteux, d'où une estimation volontairement prudente.
+func EstimateTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	ascii := 0
+	other := 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
+
+// imageTokens est le coût approximatif d'une image jointe (image de taille
+// moyenne découpée en tuiles par les modèles de vision)
+const imageTokens = 765
+
+// EstimateMessage estime le nombre de tokens d'un message, appels d'outils compris
+func EstimateMessage(msg types.Message) int {
+	tokens := messageOverhead + EstimateTokens(msg.Content) + EstimateTokens(msg.Name)
+	for _, call := range msg.ToolCalls {
+		tokens += messageOverhead + EstimateTokens(call.Function.Name) + EstimateTokens(call.Function.Arguments)
+	}
+	tokens += len(msg.Images()) * imageTokens
+	return tokens
+}
+
+// EstimateMessages estime le nombre de tokens d'un historique complet
+func EstimateMessages(messages []types.Message) int {
+	total := 0
+	for _, msg := range messages {
+		total += EstimateMessage(msg)
+	}
+	return total
+}
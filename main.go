@@ -2,22 +2,96 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"net/smtp"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"asione-agent/api"
+	"asione-agent/attachment"
+	"asione-agent/audit"
+	"asione-agent/catalog"
+	"asione-agent/executor"
+	"asione-agent/history"
 	"asione-agent/memory"
+	"asione-agent/risk"
 	"asione-agent/search"
+	"asione-agent/session"
+	"asione-agent/settings"
 	"asione-agent/types"
+	"asione-agent/usage"
+	"asione-agent/webpage"
 )
 
+// defaultMaxSteps est le nombre maximal d'étapes (appels au modèle) par tâche
+const defaultMaxSteps = 10
+
+// apiCallTimeout limite la durée d'un appel au modèle
+const apiCallTimeout = 120 * time.Second
+
+// defaultFetchPages est le nombre de résultats de recherche dont la page est
+// lue pour compléter les extraits (SEARCH_FETCH_PAGES)
+const defaultFetchPages = 3
+
+// Extraits des pages lues transmis au modèle : au total et par page
+const (
+	maxPageExcerpts = 6
+	excerptsPerPage = 3
+)
+
+// taskDoneMarker est le marqueur par lequel le modèle déclare la tâche terminée
+// lorsqu'il ne dispose pas de l'appel d'outils
+const taskDoneMarker = "[TÂCHE TERMINÉE]"
+
+// ConfirmPolicy détermine quand l'agent demande confirmation avant d'exécuter une commande
+type ConfirmPolicy string
+
+const (
+	// PolicyAsk demande confirmation pour chaque commande
+	PolicyAsk ConfirmPolicy = "ask"
+	// PolicyAutoSafe exécute sans confirmation les commandes en lecture seule
+	PolicyAutoSafe ConfirmPolicy = "auto-safe"
+	// PolicyAutoAll exécute toutes les commandes sans confirmation ("oui à tout")
+	PolicyAutoAll ConfirmPolicy = "auto-all"
+	// PolicyDenyAll refuse toutes les commandes ("non à tout")
+	PolicyDenyAll ConfirmPolicy = "deny-all"
+)
+
+// parseConfirmPolicy valide le nom d'une politique de confirmation
+func parseConfirmPolicy(name string) (ConfirmPolicy, bool) {
+	switch p := ConfirmPolicy(strings.ToLower(strings.TrimSpace(name))); p {
+	case PolicyAsk, PolicyAutoSafe, PolicyAutoAll, PolicyDenyAll:
+		return p, true
+	}
+	return "", false
+}
+
+// errCommandDenied signale qu'une commande a été refusée par la politique de confirmation
+var errCommandDenied = errors.New("commande refusée par la politique de confirmation (deny-all)")
+
+// dryRunObservation est le résultat transmis au modèle pour une commande simulée
+const dryRunObservation = "Mode simulation actif : la commande a été expliquée à l'utilisateur mais n'a pas été exécutée. Ne proposez pas d'autre commande."
+
+// errDryRun signale qu'une commande n'a pas été exécutée car le mode simulation est actif
+var errDryRun = errors.New("mode simulation : commande non exécutée")
+
+// errCommandCancelled signale que l'utilisateur a refusé l'exécution d'une commande
+var errCommandCancelled = errors.New("exécution de la commande annulée par l'utilisateur")
+
+// errBudgetExceeded signale que le budget de la session est épuisé en mode bloquant
+var errBudgetExceeded = errors.New("budget de la session dépassé (voir 'usage budget')")
+
 // SystemInfo stocke les informations du système détectées au démarrage
 type SystemInfo struct {
 	OSName        string
@@ -35,6 +109,12 @@ type Agent struct {
 		BaseURL string
 		APIKey  string
 		Model   string
+
+		// Fournisseur : openai (API compatible, par défaut), anthropic ou ollama
+		Provider string
+
+		// Cibles de repli essayées dans l'ordre si la cible principale est indisponible
+		Fallbacks []api.Target
 	}
 
 	// Configuration SMTP pour l'envoi d'emails
@@ -49,8 +129,15 @@ type Agent struct {
 	// Client API
 	apiClient *api.Client
 
+	// Liste des modèles disponibles, mise en cache pour la session (vidée si la cible change)
+	modelList []types.Model
+
 	// Recherche Internet
-	webSearcher *search.WebSearcher
+	webSearcher search.Searcher
+
+	// Lecture des pages des premiers résultats (0 = seulement les extraits du moteur)
+	pageFetcher *webpage.Fetcher
+	fetchPages  int
 
 	// Scanner pour lire les entrées utilisateur
 	scanner *bufio.Scanner
@@ -66,6 +153,65 @@ type Agent struct {
 
 	// Informations système détectées au démarrage
 	systemInfo *SystemInfo
+
+	// Appel d'outils natif (désactivé si le modèle ne le prend pas en charge)
+	toolsEnabled bool
+
+	// Nombre maximal d'étapes de la boucle exécution/observation par tâche
+	maxSteps int
+
+	// Politique de confirmation des commandes, propre à la session
+	confirmPolicy ConfirmPolicy
+
+	// Exécution des commandes dans un bac à sable (réseau coupé sauf si sandboxNetwork)
+	sandboxEnabled bool
+	sandboxNetwork bool
+
+	// Mode simulation : les commandes sont expliquées mais jamais exécutées
+	dryRun bool
+
+	// Session courante et son stockage
+	session      *session.Session
+	sessionStore *session.Store
+
+	// Journal d'audit des commandes proposées et exécutées
+	auditLog *audit.Logger
+
+	// Gestion de la fenêtre de contexte (résumé des anciens tours)
+	contextManager *history.Manager
+
+	// Taille de la fenêtre de contexte imposée par CONTEXT_LIMIT (0 = selon le modèle)
+	contextLimitOverride int
+
+	// Registre de consommation des tokens et tarifs des modèles
+	usageLedger *usage.Ledger
+	prices      *usage.PriceTable
+
+	// Affichage de la consommation après chaque tâche
+	usageFooter bool
+	turnUsage   usage.Totals
+
+	// Budget de la session en dollars (0 = illimité) ; bloquant ou simple avertissement
+	sessionBudget float64
+	budgetBlock   bool
+	budgetWarned  bool
+
+	// Pièces jointes en attente, envoyées avec la prochaine demande
+	attachments []*attachment.Attachment
+
+	// Préférences persistantes (profil de génération actif et profils personnalisés)
+	settings *settings.Settings
+
+	// Limite de tokens générés par réponse (MAX_TOKENS), sauf si le profil en fixe une
+	maxTokens int
+
+	// Échec de la classification des demandes déjà signalé
+	intentWarned bool
+
+	// Demande en cours et dernière réponse brute du modèle, pour l'audit
+	currentTask  string
+	lastResponse string
+	lastModel    string
 }
 
 // detectSystemInfo détecte les informations du système (OS, kernel, architecture)
@@ -183,10 +329,16 @@ func NewAgent() *Agent {
 			BaseURL string
 			APIKey  string
 			Model   string
+
+			Provider string
+
+			Fallbacks []api.Target
 		}{
-			BaseURL: baseURL,
-			APIKey:  apiKey,
-			Model:   model,
+			BaseURL:   baseURL,
+			APIKey:    apiKey,
+			Model:     model,
+			Provider:  os.Getenv("API_PROVIDER"),
+			Fallbacks: fallbackTargetsFromEnv(baseURL, apiKey),
 		},
 		SMTPConfig: struct {
 			Host     string
@@ -207,7 +359,10 @@ func NewAgent() *Agent {
 				Role: "system",
 				Content: "Vous êtes un agent AI puissant qui aide l'utilisateur à accomplir ses tâches. " +
 					"Répondez de manière concise et directe. Utilisez des listes à puces pour les étapes. " +
-					"Si la tâche nécessite des commandes shell, ajoutez un bloc de code avec la commande à exécuter. " +
+					"Si la tâche nécessite des commandes shell, utilisez l'outil run_shell pour les exécuter ; " +
+					"si aucun outil n'est disponible, ajoutez un bloc de code avec la commande à exécuter. " +
+					"Vous recevrez le résultat de chaque commande (sortie et code de sortie) : analysez-le, " +
+					"corrigez l'étape en cas d'échec et poursuivez jusqu'à ce que la tâche soit terminée. " +
 					"Pour les opérations sur le système de fichiers, fournissez les commandes appropriées. " +
 					"Vous allez générer des commandes qui seront exécutées par l'agent.\n\n" +
 					"Contexte système:\n" +
@@ -218,22 +373,91 @@ func NewAgent() *Agent {
 					"Utilisez cette information pour adapter les commandes système en conséquence.",
 			},
 		},
-		systemInfo: systemInfo,
+		systemInfo:   systemInfo,
+		toolsEnabled: os.Getenv("TOOLS_ENABLED") != "false",
+		maxSteps:     defaultMaxSteps,
+	}
+
+	// Limite de tokens par réponse, lue une seule fois au démarrage
+	agent.maxTokens = api.DefaultMaxTokens
+	if val, err := strconv.Atoi(os.Getenv("MAX_TOKENS")); err == nil && val > 0 {
+		agent.maxTokens = val
+	}
+
+	// Préférences et profil de génération
+	prefs, err := settings.Load(settings.DefaultPath())
+	if err != nil {
+		fmt.Printf("Avertissement: %v\n", err)
+	}
+	agent.settings = prefs
+
+	// Limite d'étapes configurable
+	if val, err := strconv.Atoi(os.Getenv("AGENT_MAX_STEPS")); err == nil && val > 0 {
+		agent.maxSteps = val
+	}
+
+	// Politique de confirmation par défaut de la session
+	agent.confirmPolicy = PolicyAsk
+	if val := os.Getenv("CONFIRM_POLICY"); val != "" {
+		if policy, ok := parseConfirmPolicy(val); ok {
+			agent.confirmPolicy = policy
+		} else {
+			fmt.Printf("Avertissement: CONFIRM_POLICY invalide (%s), utilisation de '%s'\n", val, PolicyAsk)
+		}
+	}
+
+	// Bac à sable
+	agent.sandboxEnabled = os.Getenv("SANDBOX") == "true"
+	agent.sandboxNetwork = os.Getenv("SANDBOX_NETWORK") == "true"
+
+	// Initialiser la session
+	agent.session = session.New()
+	store, err := session.NewStore(session.DefaultDir())
+	if err != nil {
+		fmt.Printf("Avertissement: Impossible d'initialiser le stockage des sessions: %v\n", err)
+	} else {
+		agent.sessionStore = store
+	}
+
+	// Gestion de la fenêtre de contexte
+	if val, err := strconv.Atoi(os.Getenv("CONTEXT_LIMIT")); err == nil && val > 0 {
+		agent.contextLimitOverride = val
 	}
+	agent.contextManager = history.NewManager(agent.contextLimit(), agent.maxTokens, agent.summarizeHistory)
 
-	// Récupérer la clé API de recherche
-	searchAPIKey := os.Getenv("SEARCH_API_KEY")
+	// Consommation et budget
+	agent.prices = usage.NewPriceTable()
+	if err := agent.prices.LoadFile(usage.DefaultPricesPath()); err != nil {
+		fmt.Printf("Avertissement: %v\n", err)
+	}
+	ledger, err := usage.OpenLedger(usage.DefaultPath())
+	if err != nil {
+		fmt.Printf("Avertissement: Impossible d'initialiser le registre de consommation: %v\n", err)
+	} else {
+		agent.usageLedger = ledger
+	}
+	agent.usageFooter = os.Getenv("USAGE_FOOTER") == "true"
+	if val, err := strconv.ParseFloat(os.Getenv("SESSION_BUDGET"), 64); err == nil && val > 0 {
+		agent.sessionBudget = val
+	}
+	agent.budgetBlock = os.Getenv("BUDGET_MODE") == "block"
+	agent.warnUnpricedModel()
 
-	if searchAPIKey != "" {
-		agent.webSearcher = search.NewWebSearcher(searchAPIKey, "google")
+	// Initialiser le journal d'audit
+	auditLog, err := audit.NewLogger(audit.DefaultDir())
+	if err != nil {
+		fmt.Printf("Avertissement: Impossible d'initialiser le journal d'audit: %v\n", err)
 	} else {
-		// Si aucune clé API n'est disponible, on ne peut pas faire de recherche
-		agent.webSearcher = nil
-		fmt.Println("Avertissement: Aucune clé API de recherche configurée ou échec du chargement du fichier .env. Les fonctionnalités de recherche seront limitées.")
+		agent.auditLog = auditLog
 	}
 
-	// Configuration alternative avec DuckDuckGo si besoin
-	// agent.webSearcher = search.NewWebSearcher("", "duckduckgo")
+	// Moteur de recherche sur Internet
+	agent.webSearcher = searcherFromEnv()
+	agent.pageFetcher = webpage.NewFetcher()
+	agent.fetchPages = defaultFetchPages
+	if val, err := strconv.Atoi(os.Getenv("SEARCH_FETCH_PAGES")); err == nil && val >= 0 {
+		agent.fetchPages = val
+	}
 
 	// Créer le répertoire de configuration si nécessaire
 	configDir := filepath.Join(os.Getenv("HOME"), ".cline")
@@ -264,12 +488,24 @@ func (a *Agent) rememberInteraction(userInput, aiResponse string) {
 	}
 }
 
+// initClient crée le client API à partir de la configuration
+func (a *Agent) initClient() {
+	if a.apiClient != nil {
+		return
+	}
+	a.apiClient = api.NewClient(a.APIConfig.BaseURL, a.APIConfig.APIKey, a.APIConfig.Model)
+	a.apiClient.SetRetryPolicy(retryPolicyFromEnv())
+	a.apiClient.SetFallbacks(a.APIConfig.Fallbacks)
+	if err := a.apiClient.SetProvider(a.APIConfig.Provider); err != nil {
+		fmt.Printf("Avertissement: %v, utilisation de l'API compatible OpenAI\n", err)
+		a.APIConfig.Provider = api.ProviderOpenAI
+	}
+}
+
 // Start démarre l'agent avec son terminal intégré
 func (a *Agent) Start() {
 	// Allouer les ressources nécessaires
-	if a.apiClient == nil {
-		a.apiClient = api.NewClient(a.APIConfig.BaseURL, a.APIConfig.APIKey, a.APIConfig.Model)
-	}
+	a.initClient()
 
 	fmt.Println("┌─────────────────────────────────────────┐")
 	fmt.Println("│         ASIONE Agent démarré            │")
@@ -278,8 +514,16 @@ func (a *Agent) Start() {
 	fmt.Println("└─────────────────────────────────────────┘")
 	fmt.Println()
 
+	if a.dryRun {
+		fmt.Print("🔍 Mode simulation actif : les commandes seront expliquées sans être exécutées.\n\n")
+	}
+
 	for {
-		fmt.Print("ASI-agent> ")
+		if len(a.attachments) > 0 {
+			fmt.Printf("ASI-agent [📎 %d]> ", len(a.attachments))
+		} else {
+			fmt.Print("ASI-agent> ")
+		}
 		if !a.scanner.Scan() {
 			break
 		}
@@ -452,39 +696,231 @@ func (a *Agent) handleCommand(input string) {
 	case lowerInput == "config":
 		a.showConfig()
 	case lowerInput == "yes-to-all" || lowerInput == "oui à tout":
-		a.enableAutoConfirm(true)
-		fmt.Println("\n✅ Confirmation automatique activée. Toutes les commandes seront exécutées sans confirmation.\n")
+		a.setConfirmPolicy(PolicyAutoAll)
 	case lowerInput == "no-to-all" || lowerInput == "non à tout":
-		a.enableAutoConfirm(false)
-		fmt.Println("\n✅ Confirmation automatique désactivée. Toutes les commandes doivent être confirmées manuellement.\n")
+		a.setConfirmPolicy(PolicyDenyAll)
+	case lowerInput == "policy":
+		fmt.Printf("\nPolitique de confirmation : %s\n\n", a.confirmPolicy)
+	case strings.HasPrefix(lowerInput, "policy "):
+		if policy, ok := parseConfirmPolicy(input[7:]); ok {
+			a.setConfirmPolicy(policy)
+		} else {
+			fmt.Print("\nPolitique invalide (ask, auto-safe, auto-all, deny-all)\n\n")
+		}
+	case lowerInput == "tools on" || lowerInput == "tools off":
+		a.toolsEnabled = lowerInput == "tools on"
+		fmt.Printf("\n✅ Appel d'outils natif : %s\n\n", onOff(a.toolsEnabled))
+	case lowerInput == "sandbox on" || lowerInput == "sandbox off":
+		a.sandboxEnabled = lowerInput == "sandbox on"
+		fmt.Printf("\n✅ Bac à sable : %s\n\n", onOff(a.sandboxEnabled))
+	case lowerInput == "sandbox network on" || lowerInput == "sandbox network off":
+		a.sandboxNetwork = lowerInput == "sandbox network on"
+		fmt.Printf("\n✅ Réseau dans le bac à sable : %s\n\n", onOff(a.sandboxNetwork))
+	case lowerInput == "dry-run on" || lowerInput == "dry-run off":
+		a.dryRun = lowerInput == "dry-run on"
+		fmt.Printf("\n✅ Mode simulation : %s\n\n", onOff(a.dryRun))
+	case lowerInput == "audit" || strings.HasPrefix(lowerInput, "audit "):
+		a.showAudit(strings.TrimPrefix(input[5:], " "))
+	case lowerInput == "sessions" || strings.HasPrefix(lowerInput, "sessions "):
+		a.handleSessionsCommand(input[8:])
+	case lowerInput == "context":
+		a.showContext()
+	case lowerInput == "context compact":
+		a.compactContext(context.Background(), true)
+		a.showContext()
+	case lowerInput == "usage" || strings.HasPrefix(lowerInput, "usage "):
+		a.handleUsageCommand(strings.TrimSpace(input[5:]))
+	case strings.HasPrefix(lowerInput, "max-steps "):
+		a.setMaxSteps(input[10:])
 	case strings.HasPrefix(lowerInput, "email "):
 		a.handleEmailCommand(input[6:]) // "email" suivi par le reste de la commande
 	case strings.HasPrefix(lowerInput, "set-api-key "):
 		a.setAPIKey(input[12:])
 	case strings.HasPrefix(lowerInput, "set-base-url "):
 		a.setBaseURL(input[13:])
+	case strings.HasPrefix(lowerInput, "set-provider "):
+		a.setProvider(input[13:])
 	case strings.HasPrefix(lowerInput, "set-model "):
 		a.setModel(input[10:])
+	case lowerInput == "attach" || strings.HasPrefix(lowerInput, "attach "):
+		a.handleAttachCommand(strings.TrimSpace(input[6:]))
+	case lowerInput == "profile" || strings.HasPrefix(lowerInput, "profile "):
+		a.handleProfileCommand(strings.TrimSpace(input[7:]))
+	case lowerInput == "models" || strings.HasPrefix(lowerInput, "models "):
+		a.handleModelsCommand(strings.TrimSpace(input[6:]))
 	default:
+		a.turnUsage = usage.Totals{}
 		a.processTask(input)
+		a.saveSession()
+		if a.usageFooter && a.turnUsage.Calls > 0 {
+			fmt.Printf("📊 %s\n\n", formatUsage(a.turnUsage))
+		}
 	}
 }
 
-// enableAutoConfirm active ou désactive la confirmation automatique
-func (a *Agent) enableAutoConfirm(enabled bool) {
-	if enabled {
-		// Ajouter un message système pour que l'IA soit au courant
-		a.messages = append(a.messages, types.Message{
-			Role:    "system",
-			Content: "L'utilisateur a activé le mode 'oui à tout'. Les commandes critiques doivent être exécutées sans confirmation.",
-		})
-	} else {
-		// Informer que le mode a été désactivé
-		a.messages = append(a.messages, types.Message{
-			Role:    "system",
-			Content: "Le mode 'oui à tout' a été désactivé. Toutes les commandes doivent être confirmées manuellement.",
-		})
+// setConfirmPolicy change la politique de confirmation de la session
+func (a *Agent) setConfirmPolicy(policy ConfirmPolicy) {
+	a.confirmPolicy = policy
+
+	// Ajouter un message système pour que l'IA soit au courant
+	var notice string
+	switch policy {
+	case PolicyAsk:
+		notice = "Toutes les commandes doivent être confirmées manuellement par l'utilisateur."
+		fmt.Print("\n✅ Confirmation demandée pour chaque commande.\n\n")
+	case PolicyAutoSafe:
+		notice = "Les commandes en lecture seule sont exécutées sans confirmation ; les autres doivent être confirmées."
+		fmt.Print("\n✅ Les commandes en lecture seule seront exécutées sans confirmation.\n\n")
+	case PolicyAutoAll:
+		notice = "L'utilisateur a activé le mode 'oui à tout'. Les commandes sont exécutées sans confirmation."
+		fmt.Print("\n✅ Confirmation automatique activée. Toutes les commandes seront exécutées sans confirmation.\n\n")
+	case PolicyDenyAll:
+		notice = "L'utilisateur a activé le mode 'non à tout'. Aucune commande ne sera exécutée : répondez sans exécuter de commande."
+		fmt.Print("\n✅ Mode 'non à tout' activé. Aucune commande ne sera exécutée.\n\n")
+	}
+	a.messages = append(a.messages, types.Message{
+		Role:    "system",
+		Content: notice,
+	})
+
+	a.saveSession()
+}
+
+// sessionTitleLength est la longueur maximale du titre d'une session
+const sessionTitleLength = 60
+
+// saveSession enregistre l'état de la session courante
+func (a *Agent) saveSession() {
+	if a.sessionStore == nil || a.session == nil {
+		return
+	}
+	a.session.ConfirmPolicy = string(a.confirmPolicy)
+	a.session.Messages = a.messages
+	a.session.Model = a.APIConfig.Model
+	if a.lastModel != "" {
+		a.session.Model = a.lastModel
+	}
+	if a.session.Title == "" && a.currentTask != "" {
+		a.session.Title = truncateText(a.currentTask, sessionTitleLength)
+	}
+	if err := a.sessionStore.Save(a.session); err != nil {
+		fmt.Printf("Avertissement: Impossible d'enregistrer la session: %v\n", err)
+	}
+}
+
+// handleSessionsCommand gère les sous-commandes list, resume et delete
+func (a *Agent) handleSessionsCommand(args string) {
+	if a.sessionStore == nil {
+		fmt.Print("\nLe stockage des sessions n'est pas disponible.\n\n")
+		return
+	}
+
+	fields := strings.Fields(args)
+	if len(fields) == 0 || fields[0] == "list" {
+		a.listSessions()
+		return
+	}
+	if len(fields) != 2 {
+		fmt.Print("\nUsage : sessions list | sessions resume <id> | sessions delete <id>\n\n")
+		return
+	}
+
+	switch fields[0] {
+	case "resume":
+		if err := a.resumeSession(fields[1]); err != nil {
+			fmt.Printf("\nErreur: %v\n\n", err)
+		}
+	case "delete":
+		a.deleteSession(fields[1])
+	default:
+		fmt.Print("\nUsage : sessions list | sessions resume <id> | sessions delete <id>\n\n")
+	}
+}
+
+// listSessions affiche les sessions enregistrées
+func (a *Agent) listSessions() {
+	sessions, err := a.sessionStore.List()
+	if err != nil {
+		fmt.Printf("\nErreur: %v\n\n", err)
+		return
+	}
+	if len(sessions) == 0 {
+		fmt.Print("\nAucune session enregistrée.\n\n")
+		return
+	}
+
+	fmt.Printf("\nSessions enregistrées (%d) :\n", len(sessions))
+	for _, sess := range sessions {
+		marker := " "
+		if a.session != nil && sess.ID == a.session.ID {
+			marker = "*"
+		}
+		title := sess.Title
+		if title == "" {
+			title = "(sans titre)"
+		}
+		fmt.Printf(" %s %s  %s  %3d messages  %s\n", marker, sess.ID,
+			sess.UpdatedAt.Format("2006-01-02 15:04"), len(sess.Messages), title)
+	}
+	fmt.Println()
+}
+
+// resumeSession reprend une session enregistrée avec tout son historique.
+// Le prompt système courant remplace celui de la session pour refléter le système actuel.
+func (a *Agent) resumeSession(ref string) error {
+	if a.sessionStore == nil {
+		return fmt.Errorf("le stockage des sessions n'est pas disponible")
+	}
+	sess, err := a.sessionStore.Find(ref)
+	if err != nil {
+		return err
+	}
+
+	messages := append([]types.Message{}, a.messages[0])
+	for i, msg := range sess.Messages {
+		if i == 0 && msg.Role == "system" {
+			continue
+		}
+		messages = append(messages, msg)
+	}
+	a.messages = messages
+	a.session = sess
+	if policy, ok := parseConfirmPolicy(sess.ConfirmPolicy); ok {
+		a.confirmPolicy = policy
+	}
+
+	fmt.Printf("\n✅ Session %s reprise (%d messages, politique %s)\n", sess.ID, len(sess.Messages), a.confirmPolicy)
+	if sess.Model != "" && sess.Model != a.APIConfig.Model {
+		fmt.Printf("Note : la session utilisait le modèle %s (modèle actuel : %s)\n", sess.Model, a.APIConfig.Model)
+	}
+
+	// Rappeler le dernier échange pour reprendre le fil
+	for i := len(messages) - 1; i > 0; i-- {
+		if messages[i].Role == "user" {
+			fmt.Printf("Dernière demande : %s\n", truncateText(messages[i].Content, 200))
+			break
+		}
+	}
+	fmt.Println()
+	return nil
+}
+
+// deleteSession supprime une session enregistrée
+func (a *Agent) deleteSession(ref string) {
+	sess, err := a.sessionStore.Find(ref)
+	if err != nil {
+		fmt.Printf("\nErreur: %v\n\n", err)
+		return
 	}
+	if a.session != nil && sess.ID == a.session.ID {
+		fmt.Print("\nImpossible de supprimer la session en cours.\n\n")
+		return
+	}
+	if err := a.sessionStore.Delete(sess.ID); err != nil {
+		fmt.Printf("\nErreur: %v\n\n", err)
+		return
+	}
+	fmt.Printf("\n✅ Session %s supprimée\n\n", sess.ID)
 }
 
 // showMemoryStatus affiche l'état de la mémoire à long terme
@@ -521,8 +957,14 @@ func (a *Agent) rememberManual(content string) {
 		return
 	}
 
+	keywords := a.storeMemory("manual", content)
+	fmt.Printf("\nInformation mémorisée avec les mots-clés : %v\n\n", keywords)
+}
+
+// storeMemory mémorise une information indexée par ses mots-clés et retourne ces derniers
+func (a *Agent) storeMemory(source, content string) []string {
 	metadata := map[string]string{
-		"source":    "manual",
+		"source":    source,
 		"timestamp": time.Now().Format(time.RFC3339),
 	}
 
@@ -531,10 +973,10 @@ func (a *Agent) rememberManual(content string) {
 	keywords := a.extractKeywords(description)
 
 	for _, keyword := range keywords {
-		a.knowledgeIntegrator.Remember("manual", keyword, content, metadata)
+		a.knowledgeIntegrator.Remember(source, keyword, content, metadata)
 	}
 
-	fmt.Printf("\nInformation mémorisée avec les mots-clés : %v\n\n", keywords)
+	return keywords
 }
 
 // extractKeywords extrait les mots-clés d'un texte
@@ -586,7 +1028,33 @@ func (a *Agent) showHelp() {
 	fmt.Println("  config                   - Affiche la configuration")
 	fmt.Println("  set-api-key <key>        - Définit la clé API")
 	fmt.Println("  set-base-url <url>       - Définit l'URL de base du fournisseur")
-	fmt.Println("  set-model <model>        - Définit le modèle à utiliser")
+	fmt.Println("  set-model <model>        - Définit le modèle à utiliser (vérifié auprès du fournisseur, --force pour ignorer)")
+	fmt.Println("  models [filtre]          - Liste les modèles disponibles")
+	fmt.Println("  attach <chemin>          - Joint une image ou un fichier texte à la prochaine demande")
+	fmt.Println("  attach [clear]           - Liste ou retire les pièces jointes en attente")
+	fmt.Println("  profile [nom]            - Affiche ou change le profil de génération (precise, balanced, creative)")
+	fmt.Println("  profile set <param> <v>  - Modifie un paramètre du profil actif (temperature, top_p, max_tokens...)")
+	fmt.Println("  profile reset            - Rétablit les valeurs prédéfinies du profil actif")
+	fmt.Println("  models select [filtre]   - Choisit un modèle dans la liste")
+	fmt.Println("  models refresh           - Recharge la liste des modèles")
+	fmt.Println("  set-provider <nom>       - Fournisseur : openai, anthropic ou ollama")
+	fmt.Println("  tools on|off             - Active/désactive l'appel d'outils natif")
+	fmt.Println("  max-steps <n>            - Nombre maximal d'étapes par tâche")
+	fmt.Println("  dry-run on|off           - Explique les commandes sans les exécuter")
+	fmt.Println("  sandbox on|off           - Exécute les commandes dans un bac à sable jetable")
+	fmt.Println("  sandbox network on|off   - Autorise le réseau dans le bac à sable")
+	fmt.Println("  policy <politique>       - Confirmation : ask, auto-safe, auto-all, deny-all")
+	fmt.Println("  yes-to-all / no-to-all   - Exécute toutes les commandes / n'en exécute aucune")
+	fmt.Println("  sessions list            - Liste les sessions enregistrées")
+	fmt.Println("  sessions resume <id>     - Reprend une session (id, préfixe ou 'last')")
+	fmt.Println("  sessions delete <id>     - Supprime une session")
+	fmt.Println("  context                  - Affiche l'occupation de la fenêtre de contexte")
+	fmt.Println("  context compact          - Résume dès maintenant les anciens échanges")
+	fmt.Println("  usage                    - Consommation de tokens et coûts (session, jour, modèle)")
+	fmt.Println("  usage footer on|off      - Affiche la consommation après chaque tâche")
+	fmt.Println("  usage budget <$>|off     - Budget de la session en dollars")
+	fmt.Println("  usage budget-mode <mode> - Dépassement du budget : warn ou block")
+	fmt.Println("  audit [filtres]          - Journal des commandes (date, failed, exit <code>, texte)")
 	fmt.Println("  <tâche>                  - Exécute une tâche (ex: coder, chercher, etc.)")
 	fmt.Println()
 }
@@ -597,6 +1065,107 @@ func (a *Agent) showConfig() {
 	fmt.Printf("  Base URL: %s\n", a.APIConfig.BaseURL)
 	fmt.Printf("  API Key: %s\n", maskString(a.APIConfig.APIKey))
 	fmt.Printf("  Model: %s\n", a.APIConfig.Model)
+	fmt.Printf("  Fournisseur: %s\n", providerLabel(a.APIConfig.Provider))
+	name, prof := a.settings.Active()
+	fmt.Printf("  Profil: %s (%s)\n", name, prof.Summary())
+	if a.webSearcher != nil {
+		fmt.Printf("  Recherche: %s (pages lues: %d)\n", a.webSearcher.Name(), a.fetchPages)
+	} else {
+		fmt.Println("  Recherche: désactivée")
+	}
+	if len(a.APIConfig.Fallbacks) > 0 {
+		fmt.Println("  Cibles de repli:")
+		for i, target := range a.APIConfig.Fallbacks {
+			fmt.Printf("    %d. %s\n", i+1, target)
+		}
+	}
+	if a.apiClient != nil {
+		fmt.Printf("  Dernière réponse: %s\n", a.apiClient.LastTarget())
+	}
+	fmt.Printf("  Outils natifs: %s\n", onOff(a.toolsEnabled))
+	fmt.Printf("  Étapes max par tâche: %d\n", a.maxSteps)
+	fmt.Printf("  Fenêtre de contexte: %d tokens\n", a.contextLimit())
+	fmt.Printf("  Budget de session: %s\n", a.budgetLabel())
+	fmt.Printf("  Politique de confirmation: %s\n", a.confirmPolicy)
+	fmt.Printf("  Mode simulation: %s\n", onOff(a.dryRun))
+	fmt.Printf("  Bac à sable: %s (réseau %s)\n", onOff(a.sandboxEnabled), onOff(a.sandboxNetwork))
+	if a.session != nil {
+		fmt.Printf("  Session: %s\n", a.session.ID)
+	}
+	if a.auditLog != nil {
+		fmt.Printf("  Journal d'audit: %s\n", a.auditLog.Dir())
+	}
+	fmt.Println()
+}
+
+// auditDefaultLimit est le nombre d'entrées d'audit affichées par défaut
+const auditDefaultLimit = 20
+
+// showAudit interroge le journal d'audit.
+// Syntaxe : audit [AAAA-MM-JJ|today] [failed] [exit <code>] [limit <n>] [texte]
+func (a *Agent) showAudit(args string) {
+	if a.auditLog == nil {
+		fmt.Print("\nLe journal d'audit n'est pas disponible.\n\n")
+		return
+	}
+
+	query := audit.Query{Limit: auditDefaultLimit}
+	var text []string
+	fields := strings.Fields(args)
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		switch lower := strings.ToLower(field); {
+		case lower == "today" || lower == "aujourd'hui":
+			query.Date = time.Now()
+		case lower == "failed" || lower == "échecs":
+			query.Failed = true
+		case (lower == "exit" || lower == "limit") && i+1 < len(fields):
+			n, err := strconv.Atoi(fields[i+1])
+			if err != nil {
+				fmt.Printf("\nValeur invalide pour %s : %s\n\n", lower, fields[i+1])
+				return
+			}
+			i++
+			if lower == "exit" {
+				query.ExitCode = &n
+			} else {
+				query.Limit = n
+			}
+		default:
+			if date, err := time.ParseInLocation("2006-01-02", field, time.Local); err == nil {
+				query.Date = date
+			} else {
+				text = append(text, field)
+			}
+		}
+	}
+	query.Text = strings.Join(text, " ")
+
+	entries, err := a.auditLog.Search(query)
+	if err != nil {
+		fmt.Printf("\nErreur lors de la lecture du journal d'audit: %v\n\n", err)
+		return
+	}
+	if len(entries) == 0 {
+		fmt.Print("\nAucune entrée trouvée dans le journal d'audit.\n\n")
+		return
+	}
+
+	fmt.Printf("\nJournal d'audit (%d entrée(s)) :\n", len(entries))
+	for _, e := range entries {
+		status := e.Decision
+		if e.Executed {
+			status = fmt.Sprintf("code %d, %s", e.ExitCode, time.Duration(e.DurationMs)*time.Millisecond)
+		}
+		sudo := ""
+		if e.Sudo {
+			sudo = " [sudo]"
+		}
+		fmt.Printf("  %s  %-24s%s $ %s\n", e.Timestamp.Format("2006-01-02 15:04:05"), status, sudo, e.Command)
+		if e.Error != "" {
+			fmt.Printf("      erreur : %s\n", e.Error)
+		}
+	}
 	fmt.Println()
 }
 
@@ -604,11 +1173,12 @@ func (a *Agent) showConfig() {
 func (a *Agent) setAPIKey(key string) {
 	a.APIConfig.APIKey = strings.TrimSpace(key)
 	a.apiClient.SetCredentials(a.APIConfig.BaseURL, a.APIConfig.APIKey, a.APIConfig.Model)
+	a.modelList = nil
 	fmt.Println("\nClé API définie avec succès\n")
 
 	// Mettre à jour le moteur de recherche avec la clé si disponible
 	if strings.Contains(a.APIConfig.BaseURL, "serpapi") {
-		a.webSearcher = search.NewWebSearcher(a.APIConfig.APIKey, "google")
+		a.webSearcher = search.NewSerpAPISearcher(a.APIConfig.APIKey, "google")
 	}
 }
 
@@ -618,356 +1188,2053 @@ func (a *Agent) setBaseURL(url string) {
 	if url != "" {
 		a.APIConfig.BaseURL = url
 		a.apiClient.SetCredentials(a.APIConfig.BaseURL, a.APIConfig.APIKey, a.APIConfig.Model)
+		a.modelList = nil
 		fmt.Println("\nURL de base définie avec succès\n")
 
 		// Mettre à jour le moteur de recherche si c'est un service de recherche
 		if strings.Contains(url, "serpapi") || strings.Contains(url, "googleapis") {
-			a.webSearcher = search.NewWebSearcher(a.APIConfig.APIKey, "google")
+			a.webSearcher = search.NewSerpAPISearcher(a.APIConfig.APIKey, "google")
 		}
 	} else {
 		fmt.Println("\nURL invalide\n")
 	}
 }
 
-// setModel définit le modèle
-func (a *Agent) setModel(model string) {
-	model = strings.TrimSpace(model)
-	if model != "" {
-		a.APIConfig.Model = model
-		a.apiClient.SetCredentials(a.APIConfig.BaseURL, a.APIConfig.APIKey, a.APIConfig.Model)
-		fmt.Println("\nModèle défini avec succès\n")
-	} else {
-		fmt.Println("\nModèle invalide\n")
+// handleAttachCommand traite la commande attach : ajout d'un fichier, liste ou
+// retrait des pièces jointes en attente
+func (a *Agent) handleAttachCommand(args string) {
+	switch strings.ToLower(args) {
+	case "":
+		if len(a.attachments) == 0 {
+			fmt.Print("\nAucune pièce jointe en attente. Usage : attach <chemin>\n\n")
+			return
+		}
+		fmt.Println("\n📎 Pièces jointes pour la prochaine demande :")
+		for _, att := range a.attachments {
+			fmt.Printf("  • %s\n", describeAttachment(att))
+		}
+		fmt.Println()
+	case "clear":
+		a.attachments = nil
+		fmt.Print("\n✅ Pièces jointes retirées\n\n")
+	default:
+		att, err := attachment.Load(args)
+		if err != nil {
+			fmt.Printf("\n❌ %v\n\n", err)
+			return
+		}
+		a.attachments = append(a.attachments, att)
+		fmt.Printf("\n📎 %s\nElle sera envoyée avec votre prochaine demande.\n\n", describeAttachment(att))
 	}
 }
 
-// processTask traite une tâche utilisateur
-func (a *Agent) processTask(task string) {
-	// Vérifier si l'utilisateur demande une recherche
-	if strings.Contains(strings.ToLower(task), "cherche") ||
-		strings.Contains(strings.ToLower(task), "recherche") ||
-		strings.Contains(strings.ToLower(task), "trouve") {
-		a.performWebSearch(task)
-		return
+// describeAttachment décrit une pièce jointe sur une ligne
+func describeAttachment(att *attachment.Attachment) string {
+	desc := fmt.Sprintf("%s (%s, %s, %s octets)", att.Name(), att.Kind, att.MediaType, formatThousands(int(att.Size)))
+	if att.Truncated {
+		desc += fmt.Sprintf(" – tronqué à %s octets", formatThousands(len(att.Data)))
+	}
+	return desc
+}
+
+// userMessage compose le message utilisateur d'une demande et y joint les
+// pièces en attente, qui sont alors consommées
+func (a *Agent) userMessage(task string) types.Message {
+	if len(a.attachments) == 0 {
+		return types.Message{Role: "user", Content: task}
+	}
+	msg := attachment.Message(task, a.attachments)
+	fmt.Printf("📎 %d pièce(s) jointe(s) envoyée(s)\n", len(a.attachments))
+	a.attachments = nil
+	return msg
+}
+
+// chatOptions retourne les paramètres de génération du profil donné (le profil
+// actif si name est vide), complétés par la limite MAX_TOKENS et les outils
+func (a *Agent) chatOptions(name string, tools []types.Tool) api.ChatOptions {
+	_, prof := a.settings.Active()
+	if name != "" {
+		if p, ok := a.settings.Lookup(name); ok {
+			prof = p
+		}
 	}
+	opts := api.ChatOptions{MaxTokens: a.maxTokens}.Merge(prof.ChatOptions)
+	opts.Tools = tools
+	return opts
+}
 
-	// Vérifier si l'utilisateur demande des informations récentes
-	needsRecentInfo := strings.Contains(strings.ToLower(task), "récemment") ||
-		strings.Contains(strings.ToLower(task), "dernières") ||
-		strings.Contains(strings.ToLower(task), "2024") ||
-		strings.Contains(strings.ToLower(task), "2025")
+// handleProfileCommand traite la commande profile : affichage, changement,
+// modification d'un paramètre ou réinitialisation du profil actif
+func (a *Agent) handleProfileCommand(args string) {
+	fields := strings.Fields(args)
+	active, _ := a.settings.Active()
 
-	// Si besoin d'informations récentes ou pas de clé API configurée, faire une recherche
-	if needsRecentInfo || a.APIConfig.APIKey == "" {
-		a.performWebSearch(task)
+	switch {
+	case len(fields) == 0:
+		fmt.Println("\nProfils de génération :")
+		for _, name := range a.settings.Names() {
+			prof, _ := a.settings.Lookup(name)
+			marker := "  "
+			if name == active {
+				marker = "▶ "
+			}
+			custom := ""
+			if a.settings.Customized(name) {
+				custom = " [personnalisé]"
+			}
+			fmt.Printf("  %s%-10s %s%s\n", marker, name, prof.Summary(), custom)
+			if prof.Description != "" {
+				fmt.Printf("      %s\n", prof.Description)
+			}
+		}
+		fmt.Println()
+		return
+	case fields[0] == "set":
+		if len(fields) < 3 {
+			fmt.Printf("\nUsage : profile set <%s> <valeur|off>\n\n", strings.Join(settings.Params, "|"))
+			return
+		}
+		rest := strings.TrimSpace(args[len("set"):])
+		value := strings.TrimSpace(rest[len(fields[1]):])
+		if err := a.settings.SetParam(active, strings.ToLower(fields[1]), value); err != nil {
+			fmt.Printf("\n%v\n\n", err)
+			return
+		}
+	case fields[0] == "reset" && len(fields) == 1:
+		if err := a.settings.Reset(active); err != nil {
+			fmt.Printf("\n%v\n\n", err)
+			return
+		}
+	case len(fields) == 1:
+		if err := a.settings.Use(strings.ToLower(fields[0])); err != nil {
+			fmt.Printf("\n%v\n\n", err)
+			return
+		}
+	default:
+		fmt.Print("\nUsage : profile [nom] | profile set <param> <valeur> | profile reset\n\n")
 		return
 	}
 
-	// Sinon, traiter la tâche avec le modèle AI
-	a.processWithAI(task)
+	if err := a.settings.Save(); err != nil {
+		fmt.Printf("Avertissement: %v\n", err)
+	}
+	name, prof := a.settings.Active()
+	fmt.Printf("\n✅ Profil %s : %s\n\n", name, prof.Summary())
 }
 
-// performWebSearch effectue une recherche sur Internet
-func (a *Agent) performWebSearch(task string) {
-	fmt.Printf("\nRecherche sur Internet pour: %s\n", task)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+// modelSuggestions est le nombre de modèles proposés pour un nom inconnu
+const modelSuggestions = 3
 
-	// Extraire le terme de recherche de la tâche
-	query := a.extractSearchQuery(task)
+// setModel définit le modèle après avoir vérifié qu'il figure dans la liste du
+// fournisseur ; un nom inconnu donne lieu à des suggestions. Le suffixe --force
+// désactive la vérification (modèle non listé par le fournisseur).
+func (a *Agent) setModel(model string) {
+	model = strings.TrimSpace(model)
+	force := strings.HasSuffix(model, "--force")
+	if force {
+		model = strings.TrimSpace(strings.TrimSuffix(model, "--force"))
+	}
+	if model == "" {
+		fmt.Println("\nModèle invalide\n")
+		return
+	}
+	if force {
+		a.applyModel(model)
+		return
+	}
 
-	results, err := a.webSearcher.Search(ctx, query)
+	models, err := a.availableModels(false)
 	if err != nil {
-		fmt.Printf("Erreur lors de la recherche: %v\n\n", err)
+		fmt.Printf("\n⚠️ Liste des modèles indisponible, modèle non vérifié (%s)\n", describeAPIError(err))
+		a.applyModel(model)
 		return
 	}
-
-	// Afficher les résultats
-	fmt.Println(search.FormatSearchResults(results))
-
-	// Demander confirmation pour utiliser ces résultats
-	fmt.Println("Voulez-vous utiliser ces informations pour compléter votre tâche ? (oui/non) [ENTRÉE pour 'oui']")
-	fmt.Print(">")
-	if !a.scanner.Scan() {
+	if m, ok := catalog.Find(models, model); ok {
+		a.applyModel(m.ID)
 		return
 	}
 
-	input := a.scanner.Text()
-	response := strings.ToLower(strings.TrimSpace(input))
-
-	// Par défaut, saisie vide = "oui"
-	if response == "" {
-		response = "oui"
-		fmt.Println("✅ (confirmation par défaut)")
+	fmt.Printf("\nModèle inconnu chez ce fournisseur : %s\n", model)
+	suggestions := catalog.Suggest(models, model, modelSuggestions)
+	if len(suggestions) == 0 {
+		fmt.Print("Utilisez 'models' pour lister les modèles disponibles, ou 'set-model <model> --force'.\n\n")
+		return
 	}
-
-	if response == "oui" || response == "yes" || response == "y" {
-		// Utiliser les résultats pour compléter la tâche
-		a.processWithAIBasedOnSearch(task, search.FormatSearchResults(results))
+	fmt.Printf("Vouliez-vous dire : %s ?\n", strings.Join(suggestions, ", "))
+	ok, err := a.askYesNo(fmt.Sprintf("Utiliser '%s' ? (oui/non) [ENTRÉE pour 'non'] ", suggestions[0]), false)
+	if err != nil || !ok {
+		fmt.Print("Modèle inchangé.\n\n")
+		return
 	}
+	a.applyModel(suggestions[0])
+}
+
+// applyModel définit le modèle de la cible principale sans vérification
+func (a *Agent) applyModel(model string) {
+	a.APIConfig.Model = model
+	a.apiClient.SetCredentials(a.APIConfig.BaseURL, a.APIConfig.APIKey, a.APIConfig.Model)
+	fmt.Printf("\nModèle défini avec succès : %s\n", model)
+	a.warnUnpricedModel()
+	fmt.Println()
+}
+
+// availableModels retourne la liste des modèles du fournisseur, triée par nom.
+// Elle est mise en cache pour la session, sauf si refresh est demandé.
+func (a *Agent) availableModels(refresh bool) ([]types.Model, error) {
+	if a.modelList != nil && !refresh {
+		return a.modelList, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), apiCallTimeout)
+	defer cancel()
+	resp, err := a.apiClient.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	models := append([]types.Model{}, resp.Data...)
+	catalog.Sort(models)
+	a.modelList = models
+	return models, nil
+}
+
+// handleModelsCommand traite la commande models : liste filtrée, sélection
+// interactive ou rechargement de la liste
+func (a *Agent) handleModelsCommand(args string) {
+	lower := strings.ToLower(args)
+	switch {
+	case lower == "refresh":
+		a.modelList = nil
+		a.listModels("")
+	case lower == "select" || strings.HasPrefix(lower, "select "):
+		a.selectModel(strings.TrimSpace(args[6:]))
+	default:
+		a.listModels(args)
+	}
+}
+
+// listModels affiche les modèles disponibles correspondant au filtre et les retourne
+func (a *Agent) listModels(filter string) []types.Model {
+	models, err := a.availableModels(false)
+	if err != nil {
+		fmt.Printf("\n❌ Impossible de récupérer la liste des modèles : %s\n\n", describeAPIError(err))
+		return nil
+	}
+
+	models = catalog.Filter(models, filter)
+	if len(models) == 0 {
+		if filter != "" {
+			fmt.Printf("\nAucun modèle ne correspond à '%s'.\n\n", filter)
+		} else {
+			fmt.Print("\nLe fournisseur n'a retourné aucun modèle.\n\n")
+		}
+		return nil
+	}
+
+	width := 0
+	for _, m := range models {
+		if len(m.ID) > width {
+			width = len(m.ID)
+		}
+	}
+
+	fmt.Printf("\n📋 Modèles disponibles (%d) :\n", len(models))
+	for i, m := range models {
+		owner := m.OwnedBy
+		if owner == "" {
+			owner = "-"
+		}
+		created := "-"
+		if m.Created > 0 {
+			created = time.Unix(m.Created, 0).Format("2006-01-02")
+		}
+		current := ""
+		if m.ID == a.APIConfig.Model {
+			current = "  ← actuel"
+		}
+		fmt.Printf("  %3d. %-*s  %-16s %s%s\n", i+1, width, m.ID, owner, created, current)
+	}
+	fmt.Println()
+	return models
+}
+
+// selectModel affiche les modèles correspondant au filtre et laisse l'utilisateur
+// en choisir un par son numéro ou son nom
+func (a *Agent) selectModel(filter string) {
+	models := a.listModels(filter)
+	if len(models) == 0 {
+		return
+	}
+
+	fmt.Print("Numéro ou nom du modèle (ENTRÉE pour annuler) : ")
+	if !a.scanner.Scan() {
+		return
+	}
+	choice := strings.TrimSpace(a.scanner.Text())
+	if choice == "" {
+		fmt.Print("Modèle inchangé.\n\n")
+		return
+	}
+	if n, err := strconv.Atoi(choice); err == nil {
+		if n < 1 || n > len(models) {
+			fmt.Printf("Numéro invalide (1-%d). Modèle inchangé.\n\n", len(models))
+			return
+		}
+		a.applyModel(models[n-1].ID)
+		return
+	}
+	a.setModel(choice)
+}
+
+// recordUsage enregistre la consommation d'un appel au modèle. Si le fournisseur
+// ne la communique pas, elle est estimée à partir de la requête et de la réponse.
+func (a *Agent) recordUsage(resp *types.ChatResponse, request []types.Message) {
+	record := usage.Record{
+		Timestamp:        time.Now(),
+		Model:            resp.Model,
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+	}
+	if record.Model == "" {
+		record.Model = a.APIConfig.Model
+	}
+	if a.session != nil {
+		record.SessionID = a.session.ID
+	}
+	if record.PromptTokens+record.CompletionTokens == 0 {
+		record.PromptTokens = history.EstimateMessages(request)
+		for _, choice := range resp.Choices {
+			record.CompletionTokens += history.EstimateMessage(choice.Message)
+		}
+		record.Estimated = true
+	}
+	record.Cost, _ = a.prices.Cost(record.Model, record.PromptTokens, record.CompletionTokens)
+
+	a.turnUsage.Add(record)
+	if a.usageLedger != nil {
+		if err := a.usageLedger.Add(record); err != nil {
+			fmt.Printf("Avertissement: Impossible d'enregistrer la consommation: %v\n", err)
+		}
+	}
+
+	// Prévenir une seule fois lorsque le budget est franchi
+	if a.sessionBudget > 0 && !a.budgetWarned && a.sessionUsage().Cost >= a.sessionBudget {
+		a.budgetWarned = true
+		fmt.Printf("\n⚠️  Budget de la session atteint : %s dépensés sur %s.\n", formatCost(a.sessionUsage().Cost), formatCost(a.sessionBudget))
+		if a.budgetBlock {
+			fmt.Println("Les prochains appels au modèle seront bloqués (voir 'usage budget').")
+		}
+	}
+}
+
+// sessionUsage retourne la consommation cumulée de la session courante
+func (a *Agent) sessionUsage() usage.Totals {
+	if a.usageLedger == nil || a.session == nil {
+		return a.turnUsage
+	}
+	return a.usageLedger.Session(a.session.ID)
+}
+
+// checkBudget refuse un nouvel appel au modèle si le budget bloquant est épuisé
+func (a *Agent) checkBudget() error {
+	if a.sessionBudget > 0 && a.budgetBlock && a.sessionUsage().Cost >= a.sessionBudget {
+		return errBudgetExceeded
+	}
+	return nil
+}
+
+// handleUsageCommand gère la commande usage et ses réglages
+func (a *Agent) handleUsageCommand(args string) {
+	fields := strings.Fields(strings.ToLower(args))
+	switch {
+	case len(fields) == 0:
+		a.showUsage()
+	case len(fields) == 2 && fields[0] == "footer" && (fields[1] == "on" || fields[1] == "off"):
+		a.usageFooter = fields[1] == "on"
+		fmt.Printf("\n✅ Consommation après chaque tâche : %s\n\n", onOff(a.usageFooter))
+	case len(fields) == 2 && fields[0] == "budget":
+		if fields[1] == "off" {
+			a.sessionBudget = 0
+		} else {
+			budget, err := strconv.ParseFloat(strings.TrimPrefix(strings.Replace(fields[1], ",", ".", 1), "$"), 64)
+			if err != nil || budget <= 0 {
+				fmt.Print("\nMontant invalide (ex: usage budget 0.50)\n\n")
+				return
+			}
+			a.sessionBudget = budget
+		}
+		a.budgetWarned = false
+		fmt.Printf("\n✅ Budget de session : %s\n", a.budgetLabel())
+		a.warnUnpricedModel()
+		fmt.Println()
+	case len(fields) == 2 && fields[0] == "budget-mode" && (fields[1] == "warn" || fields[1] == "block"):
+		a.budgetBlock = fields[1] == "block"
+		fmt.Printf("\n✅ Budget de session : %s\n\n", a.budgetLabel())
+	default:
+		fmt.Print("\nUsage : usage | usage footer on|off | usage budget <montant>|off | usage budget-mode warn|block\n\n")
+	}
+}
+
+// warnUnpricedModel prévient que le budget de session ne peut pas être suivi
+// lorsque le tarif du modèle courant est inconnu (son coût est compté nul)
+func (a *Agent) warnUnpricedModel() {
+	if a.sessionBudget <= 0 {
+		return
+	}
+	if _, ok := a.prices.Lookup(a.APIConfig.Model); ok {
+		return
+	}
+	fmt.Printf("⚠️  Tarif inconnu pour le modèle %s : le budget de session ne sera jamais atteint. "+
+		"Ajoutez son tarif dans %s.\n", a.APIConfig.Model, usage.DefaultPricesPath())
+}
+
+// budgetLabel décrit le budget de la session
+func (a *Agent) budgetLabel() string {
+	if a.sessionBudget <= 0 {
+		return "illimité"
+	}
+	mode := "avertissement"
+	if a.budgetBlock {
+		mode = "bloquant"
+	}
+	return fmt.Sprintf("%s (%s)", formatCost(a.sessionBudget), mode)
+}
+
+// showUsage affiche la consommation de la session, du jour et par modèle
+func (a *Agent) showUsage() {
+	fmt.Println("\nConsommation :")
+	fmt.Printf("  Session : %s\n", formatUsage(a.sessionUsage()))
+	if a.sessionBudget > 0 {
+		fmt.Printf("  Budget : %s\n", a.budgetLabel())
+	}
+	if a.usageLedger == nil {
+		fmt.Println()
+		return
+	}
+	fmt.Printf("  Aujourd'hui : %s\n", formatUsage(a.usageLedger.Day(time.Now())))
+	fmt.Printf("  Total : %s\n", formatUsage(a.usageLedger.Total(nil)))
+
+	if models := a.usageLedger.ByModel(); len(models) > 0 {
+		fmt.Println("\nPar modèle :")
+		for _, g := range models {
+			price := ""
+			if _, known := a.prices.Lookup(g.Key); !known {
+				price = " (tarif inconnu)"
+			}
+			fmt.Printf("  %-28s %s%s\n", g.Key, formatUsage(g.Totals), price)
+		}
+	}
+
+	if days := a.usageLedger.ByDay(); len(days) > 0 {
+		fmt.Println("\nPar jour (7 derniers) :")
+		for i, g := range days {
+			if i == 7 {
+				break
+			}
+			fmt.Printf("  %s  %s\n", g.Key, formatUsage(g.Totals))
+		}
+	}
+	fmt.Println()
+}
+
+// formatUsage résume une consommation : « 1 240 tokens, $0.0030 »
+func formatUsage(t usage.Totals) string {
+	s := fmt.Sprintf("%s tokens, %s", formatThousands(t.Tokens()), formatCost(t.Cost))
+	if t.Calls > 1 {
+		s += fmt.Sprintf(" (%d appels)", t.Calls)
+	}
+	if t.Estimated {
+		s += " [estimation]"
+	}
+	return s
+}
+
+// formatCost formate un montant en dollars
+func formatCost(cost float64) string {
+	if cost < 0.01 && cost > 0 {
+		return fmt.Sprintf("$%.4f", cost)
+	}
+	return fmt.Sprintf("$%.2f", cost)
+}
+
+// formatThousands formate un entier avec un séparateur de milliers
+func formatThousands(n int) string {
+	digits := strconv.Itoa(n)
+	var sb strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteRune(d)
+	}
+	return sb.String()
+}
+
+// contextLimit retourne la taille de la fenêtre de contexte du modèle courant
+func (a *Agent) contextLimit() int {
+	if a.contextLimitOverride > 0 {
+		return a.contextLimitOverride
+	}
+	return history.ContextLimit(a.APIConfig.Model)
+}
+
+// syncContextManager met à jour la fenêtre de contexte et la limite max_tokens
+// du gestionnaire de contexte, qui dépendent du modèle et du profil actifs
+func (a *Agent) syncContextManager() {
+	a.contextManager.Limit = a.contextLimit()
+	a.contextManager.MaxTokens = a.chatOptions("", nil).MaxTokens
+}
+
+// compactContext résume les anciens tours de l'historique lorsque la fenêtre
+// de contexte est presque pleine (ou immédiatement si force est vrai)
+func (a *Agent) compactContext(ctx context.Context, force bool) {
+	a.syncContextManager()
+	if !force && !a.contextManager.NeedsCompaction(a.messages) {
+		return
+	}
+
+	before := history.EstimateMessages(a.messages)
+	fmt.Println("\n🗜️  Résumé des anciens échanges pour libérer la fenêtre de contexte...")
+	messages, summarized, err := a.contextManager.Compact(ctx, a.messages, force)
+	if err != nil {
+		fmt.Printf("⚠️  Impossible de réduire l'historique : %v\n", err)
+		return
+	}
+	a.messages = messages
+	after := history.EstimateMessages(a.messages)
+	if summarized > 0 {
+		fmt.Printf("✅ %d messages résumés (~%d → ~%d tokens)\n", summarized, before, after)
+	} else {
+		fmt.Printf("✅ Anciennes sorties de commandes tronquées (~%d → ~%d tokens)\n", before, after)
+	}
+	a.saveSession()
+}
+
+// summarizeHistory fait résumer une portion de la conversation par le modèle
+func (a *Agent) summarizeHistory(ctx context.Context, transcript string) (string, error) {
+	if err := a.checkBudget(); err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, apiCallTimeout)
+	defer cancel()
+
+	messages := []types.Message{
+		{
+			Role: "system",
+			Content: "Vous résumez une conversation entre un utilisateur et un agent qui exécute des commandes " +
+				"sur son système. Rédigez un résumé factuel et concis qui permettra à l'agent de poursuivre " +
+				"le travail : demandes de l'utilisateur, commandes exécutées et leurs résultats importants, " +
+				"erreurs rencontrées, fichiers et chemins concernés, décisions prises et état d'avancement. " +
+				"N'inventez rien.",
+		},
+		{
+			Role:    "user",
+			Content: transcript,
+		},
+	}
+
+	resp, err := a.apiClient.ChatCompletion(ctx, messages, a.chatOptions(settings.ProfilePrecise, nil))
+	if err != nil {
+		return "", err
+	}
+	a.recordUsage(resp, messages)
+	if len(resp.Choices) == 0 || strings.TrimSpace(resp.Choices[0].Message.Content) == "" {
+		return "", fmt.Errorf("résumé vide")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+// showContext affiche l'occupation de la fenêtre de contexte
+func (a *Agent) showContext() {
+	a.syncContextManager()
+	usage := a.contextManager.Usage(a.messages)
+
+	fmt.Printf("\nFenêtre de contexte (%s) :\n", a.APIConfig.Model)
+	fmt.Printf("  Tokens estimés : ~%d / %d (%.1f%%)\n", usage.Tokens, usage.Limit, usage.Percent())
+	fmt.Printf("  Budget de l'historique : %d tokens (%d réservés à la réponse)\n", usage.Budget, a.contextManager.Reserve())
+	fmt.Printf("  Messages : %d (dont %d résumé(s))\n", usage.Messages, usage.Summaries)
+	fmt.Println()
+}
+
+// setProvider définit le fournisseur de la cible principale
+func (a *Agent) setProvider(name string) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if err := a.apiClient.SetProvider(name); err != nil {
+		fmt.Printf("\n%v\n\n", err)
+		return
+	}
+	a.APIConfig.Provider = name
+	a.modelList = nil
+	fmt.Printf("\nFournisseur défini : %s (URL de base : %s)\n\n", providerLabel(name), a.APIConfig.BaseURL)
+}
+
+// modelAvailable indique si le modèle peut être consulté : une clé API est
+// nécessaire, sauf avec ollama qui s'exécute localement sans authentification
+func (a *Agent) modelAvailable() bool {
+	if a.apiClient == nil {
+		return false
+	}
+	return a.APIConfig.APIKey != "" || strings.EqualFold(strings.TrimSpace(a.APIConfig.Provider), api.ProviderOllama)
+}
+
+// providerLabel retourne le nom affiché d'un fournisseur
+func providerLabel(name string) string {
+	if name == "" {
+		return api.ProviderOpenAI
+	}
+	return name
+}
+
+// setMaxSteps définit le nombre maximal d'étapes par tâche
+func (a *Agent) setMaxSteps(value string) {
+	steps, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || steps <= 0 {
+		fmt.Print("\nNombre d'étapes invalide\n\n")
+		return
+	}
+	a.maxSteps = steps
+	fmt.Printf("\nNombre maximal d'étapes défini à %d\n\n", steps)
+}
+
+// processTask traite une tâche utilisateur
+func (a *Agent) processTask(task string) {
+	// Des pièces jointes ne peuvent être examinées que par le modèle
+	if len(a.attachments) > 0 {
+		a.processWithAI(task)
+		return
+	}
+
+	// Sans modèle disponible, seule la recherche est possible
+	if !a.modelAvailable() {
+		if a.webSearcher != nil {
+			a.performWebSearch(task, "")
+			return
+		}
+		fmt.Print("\nErreur: Clé API non configurée. Veuillez configurer votre clé API avec 'set-api-key'.\n\n")
+		return
+	}
+
+	// Le modèle décide du traitement de la demande
+	intent, query := a.classifyIntent(task)
+	switch intent {
+	case intentSearch:
+		if a.webSearcher != nil {
+			a.performWebSearch(task, query)
+			return
+		}
+		fmt.Println("\nℹ️  Recherche Internet non configurée (voir SEARCH_BACKEND) : réponse à partir des connaissances du modèle.")
+	case intentMemory:
+		if a.knowledgeIntegrator != nil {
+			a.processWithMemory(task, query)
+			return
+		}
+	}
+
+	// Tâche shell ou question : le modèle la traite, avec ses outils
+	a.processWithAI(task)
+}
+
+// Intentions d'une demande, qui déterminent son traitement
+const (
+	intentShell    = "shell"
+	intentQuestion = "question"
+	intentSearch   = "search"
+	intentMemory   = "memory"
+)
+
+// intentSchema est le schéma de la réponse attendue lors de la classification d'une demande
+const intentSchema = `{
+	"type": "object",
+	"properties": {
+		"intent": {"type": "string", "enum": ["shell", "question", "search", "memory"]},
+		"query": {"type": "string"}
+	},
+	"required": ["intent", "query"],
+	"additionalProperties": false
+}`
+
+// intentInstructions décrit au modèle les intentions entre lesquelles choisir
+const intentInstructions = "Vous classez la demande d'un utilisateur adressée à un agent qui travaille dans son terminal. " +
+	"Choisissez une intention :\n" +
+	"- shell : agir sur la machine locale (fichiers, dossiers, processus, paquets, configuration du système)\n" +
+	"- question : question à laquelle vous pouvez répondre avec vos connaissances\n" +
+	"- search : information à chercher sur Internet (actualité, données récentes ou postérieures à vos connaissances, " +
+	"documentation en ligne, ou recherche web demandée explicitement)\n" +
+	"- memory : information que l'utilisateur a demandé de mémoriser ou déjà donnée lors de sessions précédentes\n" +
+	"Renseignez query avec les termes de recherche pour search, un mot-clé pour memory, une chaîne vide sinon. " +
+	"Chercher un fichier ou un contenu sur la machine relève de shell, pas de search."
+
+// Classification des demandes : durée maximale, taille de la réponse et
+// nombre de messages récents fournis comme contexte
+const (
+	intentTimeout       = 20 * time.Second
+	intentMaxTokens     = 100
+	intentContextLength = 4
+)
+
+// classifyIntent demande au modèle, par un appel court, comment traiter une
+// demande, et retourne l'intention et les termes à rechercher. En cas d'échec,
+// la demande est traitée comme une question ordinaire et l'erreur est signalée
+// une seule fois par session.
+func (a *Agent) classifyIntent(task string) (intent, query string) {
+	if a.checkBudget() != nil {
+		return intentQuestion, ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), intentTimeout)
+	defer cancel()
+
+	// Les derniers échanges permettent de classer les demandes de suivi
+	var recent []string
+	for i := len(a.messages) - 1; i > 0 && len(recent) < intentContextLength; i-- {
+		msg := a.messages[i]
+		if (msg.Role == "user" || msg.Role == "assistant") && msg.Content != "" {
+			recent = append([]string{fmt.Sprintf("%s : %s", msg.Role, truncateText(msg.Content, 300))}, recent...)
+		}
+	}
+	prompt := "Demande : " + task
+	if len(recent) > 0 {
+		prompt = "Échanges précédents :\n" + strings.Join(recent, "\n") + "\n\n" + prompt
+	}
+
+	messages := []types.Message{
+		{Role: "system", Content: intentInstructions},
+		{Role: "user", Content: prompt},
+	}
+	opts := a.chatOptions(settings.ProfilePrecise, nil)
+	opts.MaxTokens = intentMaxTokens
+	result, err := a.apiClient.ChatCompletionJSON(ctx, messages, opts, api.JSONRequest{
+		Schema:      json.RawMessage(intentSchema),
+		Name:        "intent",
+		MaxAttempts: 1,
+	})
+	if result.Attempts > 0 {
+		a.recordUsage(&types.ChatResponse{Model: result.Model, Usage: result.Usage}, messages)
+	}
+	if err != nil {
+		a.reportIntentError(err)
+		return intentQuestion, ""
+	}
+
+	var decision struct {
+		Intent string `json:"intent"`
+		Query  string `json:"query"`
+	}
+	if err := json.Unmarshal(result.Data, &decision); err != nil {
+		a.reportIntentError(fmt.Errorf("erreur de désérialisation: %w", err))
+		return intentQuestion, ""
+	}
+	return decision.Intent, strings.TrimSpace(decision.Query)
+}
+
+// reportIntentError signale, la première fois seulement, l'échec de la
+// classification des demandes
+func (a *Agent) reportIntentError(err error) {
+	if a.intentWarned {
+		return
+	}
+	a.intentWarned = true
+	fmt.Printf("\n⚠️  Classification de la demande impossible (%v) : elle est traitée comme une question. "+
+		"Cet avertissement ne sera plus affiché.\n", err)
+}
+
+// processWithMemory traite une demande portant sur des informations mémorisées :
+// les entrées correspondantes de la mémoire à long terme sont jointes à la demande
+func (a *Agent) processWithMemory(task, query string) {
+	if query == "" {
+		query = task
+	}
+	fmt.Printf("\n🧠 Recherche dans la mémoire : %s\n", query)
+
+	// La mémoire ne sait chercher qu'un terme à la fois
+	var entries []memory.KnowledgeEntry
+	seen := make(map[string]bool)
+	for _, term := range append([]string{query}, strings.Fields(query)...) {
+		if len([]rune(term)) < 3 {
+			continue
+		}
+		for _, entry := range a.knowledgeIntegrator.SearchKnowledge(term) {
+			if !seen[entry.ID] {
+				seen[entry.ID] = true
+				entries = append(entries, entry)
+			}
+		}
+	}
+	fmt.Printf("   %d entrée(s) trouvée(s)\n", len(entries))
+
+	a.messages = append(a.messages, types.Message{
+		Role:    "user",
+		Content: fmt.Sprintf("%s\n\n%s", task, a.knowledgeIntegrator.FormatKnowledgeResponse(entries)),
+	})
+
+	fmt.Printf("\n[AI] Analyse et exécution de la tâche...\n")
+	if err := a.converse(context.Background(), task, true); err != nil {
+		reportAPIError(err)
+	}
+}
+
+// performWebSearch effectue une recherche sur Internet ; sans termes de
+// recherche fournis, ils sont tirés de la tâche
+func (a *Agent) performWebSearch(task, query string) {
+	if a.webSearcher == nil {
+		fmt.Print("\nRecherche Internet indisponible : aucun moteur de recherche configuré (voir SEARCH_BACKEND).\n\n")
+		return
+	}
+	if query == "" {
+		query = a.extractSearchQuery(task)
+	}
+	fmt.Printf("\nRecherche sur Internet pour: %s\n", query)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	results, err := a.webSearcher.Search(ctx, query)
+	if err != nil {
+		fmt.Printf("Erreur lors de la recherche: %v\n\n", err)
+		return
+	}
+
+	// Afficher les résultats
+	fmt.Println(search.FormatSearchResults(results))
+
+	// Sans modèle, les résultats ne peuvent pas être exploités davantage
+	if !a.modelAvailable() {
+		return
+	}
+
+	// Demander confirmation pour utiliser ces résultats
+	fmt.Println("Voulez-vous utiliser ces informations pour compléter votre tâche ? (oui/non) [ENTRÉE pour 'oui']")
+	fmt.Print(">")
+	if !a.scanner.Scan() {
+		return
+	}
+
+	input := a.scanner.Text()
+	response := strings.ToLower(strings.TrimSpace(input))
+
+	// Par défaut, saisie vide = "oui"
+	if response == "" {
+		response = "oui"
+		fmt.Println("✅ (confirmation par défaut)")
+	}
+
+	if response == "oui" || response == "yes" || response == "y" {
+		// Compléter les résultats par le texte des premières pages, puis les
+		// utiliser pour accomplir la tâche
+		searchResults := search.FormatSearchResults(results)
+		if excerpts := a.readResultPages(query, results); excerpts != "" {
+			searchResults += "\n" + excerpts
+		}
+		a.processWithAIBasedOnSearch(task, searchResults, search.Sources(results))
+	}
+}
+
+// readResultPages télécharge les pages des premiers résultats et retourne leurs
+// extraits les plus pertinents pour la requête, numérotés comme les résultats
+func (a *Agent) readResultPages(query string, results *search.Results) string {
+	if a.pageFetcher == nil || a.fetchPages == 0 || len(results.Items) == 0 {
+		return ""
+	}
+
+	var urls []string
+	for i, item := range results.Items {
+		if i >= a.fetchPages {
+			break
+		}
+		urls = append(urls, item.URL)
+	}
+
+	fmt.Printf("📄 Lecture de %d page(s)...\n", len(urls))
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	pages := a.pageFetcher.FetchAll(ctx, urls)
+
+	var chunks []webpage.Chunk
+	for i, page := range pages {
+		if page.Err != nil {
+			fmt.Printf("   ✗ [%d] %s : %v\n", i+1, page.URL, page.Err)
+			continue
+		}
+		note := ""
+		if page.Truncated {
+			note = ", tronquée"
+		}
+		fmt.Printf("   ✓ [%d] %s (%d Ko%s)\n", i+1, page.URL, (page.Size+1023)/1024, note)
+		chunks = append(chunks, webpage.Split(i, page.Content, webpage.DefaultChunkSize)...)
+	}
+
+	return webpage.FormatExcerpts(pages, webpage.Select(query, chunks, maxPageExcerpts, excerptsPerPage))
 }
 
 // extractSearchQuery extrait le terme de recherche de la tâche
 func (a *Agent) extractSearchQuery(task string) string {
 	lowerTask := strings.ToLower(task)
 
-	// Supprimer les mots-clés de recherche communs
-	terms := []string{"cherche", "recherche", "trouve", "informations sur", "dernières nouvelles sur", "qu'est-ce que"}
+	// Supprimer les mots-clés de recherche communs
+	terms := []string{"cherche", "recherche", "trouve", "informations sur", "dernières nouvelles sur", "qu'est-ce que"}
+
+	result := task
+	for _, term := range terms {
+		if strings.Contains(lowerTask, term) {
+			result = strings.ReplaceAll(result, term, "")
+		}
+	}
+
+	return strings.TrimSpace(result)
+}
+
+// executeCommand exécute une commande shell, une par une, après confirmation et explication
+// Affiche la sortie en direct tout en la capturant pour la renvoyer au modèle
+// Relaie l'entrée standard pour permettre la saisie du mot de passe sudo
+// Si approved est vrai (étape déjà validée dans un plan), la commande n'est pas reconfirmée,
+// sauf si elle est destructive : une confirmation saisie en toutes lettres est alors exigée.
+// La politique de confirmation de la session est consultée avant toute question.
+func (a *Agent) executeCommand(cmd string, approved bool) (*executor.Result, error) {
+	entry := a.newAuditEntry(cmd)
+	result, err := a.runCommand(cmd, approved, &entry)
+
+	if result != nil {
+		entry.Executed = true
+		entry.ExitCode = result.ExitCode
+		entry.DurationMs = result.Duration.Milliseconds()
+		entry.Output = result.Stdout + result.Stderr
+	} else if err != nil && err != errDryRun && err != errCommandDenied && err != errCommandCancelled {
+		entry.Error = err.Error()
+	}
+	a.recordAudit(entry)
+
+	return result, err
+}
+
+// newAuditEntry prépare l'entrée d'audit d'une commande proposée par le modèle
+func (a *Agent) newAuditEntry(cmd string) audit.Entry {
+	entry := audit.Entry{
+		Timestamp: time.Now(),
+		Prompt:    a.currentTask,
+		Model:     a.lastModel,
+		Response:  a.lastResponse,
+		Command:   cmd,
+	}
+	if entry.Model == "" {
+		entry.Model = a.APIConfig.Model
+	}
+	if a.session != nil {
+		entry.SessionID = a.session.ID
+	}
+	return entry
+}
+
+// recordDecision consigne une commande écartée avant toute exécution
+func (a *Agent) recordDecision(cmd, decision string) {
+	entry := a.newAuditEntry(cmd)
+	entry.Decision = decision
+	a.recordAudit(entry)
+}
+
+// recordAudit ajoute une entrée au journal d'audit
+func (a *Agent) recordAudit(entry audit.Entry) {
+	if a.auditLog == nil {
+		return
+	}
+	if err := a.auditLog.Log(entry); err != nil {
+		fmt.Printf("Avertissement: Impossible d'écrire dans le journal d'audit: %v\n", err)
+	}
+}
+
+// runCommand applique la politique de confirmation puis exécute la commande ;
+// la décision prise est consignée dans l'entrée d'audit
+func (a *Agent) runCommand(cmd string, approved bool, entry *audit.Entry) (*executor.Result, error) {
+	if a.dryRun {
+		entry.Decision = audit.DecisionDryRun
+		a.explainCommand(cmd)
+		return nil, errDryRun
+	}
+
+	if a.confirmPolicy == PolicyDenyAll {
+		entry.Decision = audit.DecisionDenied
+		fmt.Printf("\n⛔ Commande non exécutée (mode 'non à tout') : %s\n", cmd)
+		return nil, errCommandDenied
+	}
+
+	// Évaluer le risque de la commande
+	assessment := risk.Classify(cmd)
+	entry.Risk = assessment.Level.String()
+
+	// sudo est retiré de la commande et confié à l'exécuteur, avec l'identité
+	// demandée par -u/-g
+	command, elevation := cmd, risk.Elevation{}
+	if assessment.UsesSudo {
+		var err error
+		command, elevation, err = risk.StripSudo(cmd)
+		if err != nil {
+			entry.Decision = audit.DecisionDenied
+			fmt.Printf("\n⛔ Commande non exécutée : %v\n$ %s\n", err, cmd)
+			return nil, err
+		}
+	}
+	destructive := assessment.Level == risk.Destructive
+	autoApproved := !destructive && (approved || a.confirmPolicy == PolicyAutoAll ||
+		(a.confirmPolicy == PolicyAutoSafe && assessment.Level == risk.ReadOnly))
+
+	if autoApproved {
+		entry.Decision = audit.DecisionAutoApproved
+		fmt.Printf("\n$ %s\n", cmd)
+		printRisk(assessment)
+	} else {
+		// Résumer l'intention de la commande
+		fmt.Printf("\nJe m'apprête à exécuter la commande suivante :\n")
+		fmt.Printf("$ %s\n", cmd)
+		printRisk(assessment)
+
+		var ok bool
+		var err error
+		entry.Decision = audit.DecisionApproved
+		if destructive {
+			// Jamais de confirmation par défaut pour une commande destructive
+			ok, err = a.askTyped(destructiveConfirmation)
+			entry.Decision = audit.DecisionConfirmed
+		} else {
+			ok, err = a.askYesNo("Voulez-vous que je l'exécute ? (oui/non) [ENTRÉE pour 'oui'] ", true)
+		}
+		if err != nil {
+			entry.Decision = audit.DecisionCancelled
+			return nil, err
+		}
+		if !ok {
+			entry.Decision = audit.DecisionCancelled
+			return nil, errCommandCancelled
+		}
+	}
+
+	// sudo n'est utilisé que si la commande en a réellement besoin ; une
+	// identité cible (sudo -u) impose sudo, même pour root
+	needsSudo := (assessment.UsesSudo || assessment.RequiresRoot) && os.Geteuid() != 0
+	if elevation != (risk.Elevation{}) {
+		needsSudo = true
+	}
+	cmd = command
+
+	// Choisir le mode d'exécution
+	var backend executor.Backend = executor.Local{}
+	if a.sandboxEnabled {
+		workDir, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("répertoire de travail introuvable: %w", err)
+		}
+		sandbox, err := executor.NewSandbox(workDir, a.sandboxNetwork)
+		if err != nil {
+			fmt.Printf("❌ Bac à sable indisponible : %v\n", err)
+			return nil, err
+		}
+		backend = sandbox
+		if elevation != (risk.Elevation{}) {
+			err := fmt.Errorf("sudo -u/-g n'est pas disponible dans le bac à sable")
+			fmt.Printf("⛔ Commande non exécutée : %v\n", err)
+			entry.Decision = audit.DecisionDenied
+			return nil, err
+		}
+		if needsSudo {
+			fmt.Println("⚠️  sudo n'est pas disponible dans le bac à sable : exécution sans élévation.")
+			needsSudo = false
+		}
+	}
+
+	entry.Sudo = needsSudo
+	entry.Backend = backend.Name()
+	if needsSudo {
+		fmt.Println("\n⚠️  Cette commande nécessite des privilèges administrateur (sudo).")
+
+		// Seul le mode 'oui à tout' dispense de confirmer l'élévation de privilèges
+		if a.confirmPolicy != PolicyAutoAll {
+			ok, err := a.askYesNo("Confirmer l'exécution avec sudo ? (oui/non) [ENTRÉE pour 'oui'] ", true)
+			if err != nil {
+				entry.Decision = audit.DecisionCancelled
+				return nil, err
+			}
+			if !ok {
+				entry.Decision = audit.DecisionCancelled
+				return nil, errCommandCancelled
+			}
+		}
+
+		// Exécuter avec sudo -S pour permettre la saisie du mot de passe
+		fmt.Printf("🔐 Exécution avec sudo -S%s : %s\n", sudoIdentity(elevation), cmd)
+	} else {
+		fmt.Printf("➡️  Exécution [%s] : %s\n", backend.Name(), cmd)
+	}
+
+	// Exécuter la commande (sortie affichée en direct et capturée)
+	result, err := backend.Run(context.Background(), cmd, executor.Options{
+		Sudo:      needsSudo,
+		SudoUser:  elevation.User,
+		SudoGroup: elevation.Group,
+	})
+	if err != nil {
+		fmt.Printf("❌ La commande n'a pas pu être lancée : %v\n", err)
+		return nil, err
+	}
+	if result.Sandbox != nil {
+		a.reviewSandboxChanges(result.Sandbox)
+	}
+
+	if !result.Success() {
+		fmt.Printf("❌ La commande a échoué (code de sortie %d)\n", result.ExitCode)
+	} else {
+		fmt.Printf("✅ Commande exécutée avec succès.\n")
+	}
+	return result, nil
+}
+
+// askYesNo pose une question fermée à l'utilisateur ; une saisie vide retourne defaultYes
+func (a *Agent) askYesNo(prompt string, defaultYes bool) (bool, error) {
+	fmt.Print(prompt)
+	if !a.scanner.Scan() {
+		return false, fmt.Errorf("lecture de l'entrée utilisateur interrompue")
+	}
+	response := strings.ToLower(strings.TrimSpace(a.scanner.Text()))
+
+	// Saisie vide = réponse par défaut
+	if response == "" {
+		if defaultYes {
+			fmt.Println("✅ (confirmation par défaut)")
+		}
+		return defaultYes, nil
+	}
+
+	return response == "oui" || response == "o" || response == "yes" || response == "y", nil
+}
+
+// explainCommand affiche, sans l'exécuter, l'analyse d'une commande : commandes
+// reconnues, niveau de risque, chemins touchés et explication générée par le modèle
+func (a *Agent) explainCommand(cmd string) {
+	assessment := risk.Classify(cmd)
+
+	fmt.Printf("\n🔍 Simulation (commande non exécutée) :\n$ %s\n", cmd)
+	if len(assessment.Commands) > 0 {
+		fmt.Println("Commandes analysées :")
+		for _, c := range assessment.Commands {
+			fmt.Printf("  • %s\n", c)
+		}
+	}
+	printRisk(assessment)
+	if assessment.RequiresRoot {
+		fmt.Println("Nécessite les droits administrateur (sudo).")
+	}
+	if len(assessment.Reads) > 0 {
+		fmt.Printf("Chemins lus : %s\n", strings.Join(assessment.Reads, ", "))
+	}
+	if len(assessment.Writes) > 0 {
+		fmt.Printf("Chemins modifiés : %s\n", strings.Join(assessment.Writes, ", "))
+	}
+
+	// Explication pédagogique générée par le modèle, hors historique de la conversation
+	if !a.modelAvailable() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), apiCallTimeout)
+	defer cancel()
+
+	messages := []types.Message{
+		{
+			Role: "system",
+			Content: "Vous êtes un formateur Linux. Expliquez de manière concise et pédagogique ce que fait " +
+				"la commande fournie : chaque partie (options, pipes, redirections), son effet sur le système " +
+				"et les précautions à prendre. N'exécutez rien et ne proposez pas d'autre commande.",
+		},
+		{
+			Role: "user",
+			Content: fmt.Sprintf("Commande : %s\nRisque évalué : %s (%s)\nSystème : %s %s",
+				cmd, assessment.Level, strings.Join(assessment.Reasons, ", "), a.systemInfo.OSName, a.systemInfo.OSVersion),
+		},
+	}
+
+	if err := a.checkBudget(); err != nil {
+		fmt.Printf("(explication indisponible : %v)\n", err)
+		return
+	}
+
+	fmt.Println("\n📘 Explication :")
+	resp, err := a.apiClient.ChatCompletionStream(ctx, messages, a.chatOptions("", nil), func(delta string) {
+		fmt.Print(delta)
+	})
+	fmt.Println()
+	if err != nil {
+		fmt.Printf("(explication indisponible : %s)\n", describeAPIError(err))
+		return
+	}
+	a.recordUsage(resp, messages)
+}
+
+// reviewSandboxChanges affiche les fichiers modifiés dans le bac à sable et propose
+// de les appliquer au répertoire réel
+func (a *Agent) reviewSandboxChanges(run *executor.SandboxRun) {
+	defer run.Discard()
+
+	if len(run.Changes) == 0 {
+		fmt.Println("🧪 Bac à sable : aucun fichier modifié.")
+		return
+	}
+
+	fmt.Printf("\n🧪 Bac à sable : %d modification(s) dans %s\n", len(run.Changes), run.WorkDir)
+	for _, change := range run.Changes {
+		fmt.Printf("  [%s] %s\n", change.Kind, change.Path)
+	}
+	for _, change := range run.Changes {
+		fmt.Println()
+		fmt.Print(run.Diff(change))
+	}
+
+	ok, err := a.askYesNo("\nAppliquer ces modifications au répertoire réel ? (oui/non) [ENTRÉE pour 'non'] ", false)
+	if err != nil || !ok {
+		fmt.Println("Modifications abandonnées.")
+		return
+	}
+	if err := run.Apply(); err != nil {
+		fmt.Printf("❌ Échec de l'application des modifications : %v\n", err)
+		return
+	}
+	fmt.Println("✅ Modifications appliquées.")
+}
+
+// destructiveConfirmation est le mot à saisir pour confirmer une commande destructive
+const destructiveConfirmation = "confirmer"
+
+// askTyped exige que l'utilisateur saisisse exactement le mot attendu
+func (a *Agent) askTyped(word string) (bool, error) {
+	fmt.Printf("🛑 Commande destructive : tapez '%s' pour l'exécuter (toute autre saisie annule) : ", word)
+	if !a.scanner.Scan() {
+		return false, fmt.Errorf("lecture de l'entrée utilisateur interrompue")
+	}
+	return strings.TrimSpace(a.scanner.Text()) == word, nil
+}
+
+// riskIcons associe une pastille à chaque niveau de risque
+var riskIcons = map[risk.Level]string{
+	risk.ReadOnly:    "🟢",
+	risk.Modifying:   "🟡",
+	risk.Privileged:  "🟠",
+	risk.Destructive: "🔴",
+}
+
+// sudoIdentity décrit l'identité cible de sudo, si elle n'est pas root
+func sudoIdentity(e risk.Elevation) string {
+	var parts []string
+	if e.User != "" {
+		parts = append(parts, "-u "+e.User)
+	}
+	if e.Group != "" {
+		parts = append(parts, "-g "+e.Group)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " " + strings.Join(parts, " ")
+}
+
+// printRisk affiche le niveau de risque d'une commande et ses raisons
+func printRisk(assessment *risk.Assessment) {
+	fmt.Printf("%s Risque : %s", riskIcons[assessment.Level], assessment.Level)
+	if len(assessment.Reasons) > 0 {
+		fmt.Printf(" (%s)", strings.Join(assessment.Reasons, ", "))
+	}
+	fmt.Println()
+}
+
+// formatObservation formate le résultat d'une commande pour le renvoyer au modèle
+func formatObservation(result *executor.Result) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Commande: %s\n", result.Command))
+	sb.WriteString(fmt.Sprintf("Code de sortie: %d (durée: %s)\n", result.ExitCode, result.Duration.Round(time.Millisecond)))
+	if result.Stdout != "" {
+		sb.WriteString("Sortie standard:\n" + result.Stdout + "\n")
+	}
+	if result.Stderr != "" {
+		sb.WriteString("Sortie d'erreur:\n" + result.Stderr + "\n")
+	}
+	if result.Stdout == "" && result.Stderr == "" {
+		sb.WriteString("(aucune sortie)\n")
+	}
+	if result.Truncated {
+		sb.WriteString("(sortie tronquée, seule la fin est conservée)\n")
+	}
+	if run := result.Sandbox; run != nil {
+		sb.WriteString(fmt.Sprintf("Exécutée dans un bac à sable, %d fichier(s) modifié(s)", len(run.Changes)))
+		if run.Applied {
+			sb.WriteString(", modifications appliquées par l'utilisateur.\n")
+		} else if len(run.Changes) > 0 {
+			sb.WriteString(", modifications NON appliquées au répertoire réel.\n")
+		} else {
+			sb.WriteString(".\n")
+		}
+	}
+	return sb.String()
+}
+
+// rawResponse retourne la réponse brute du modèle, appels d'outils compris
+func rawResponse(reply types.Message) string {
+	raw := reply.Content
+	for _, call := range reply.ToolCalls {
+		raw += fmt.Sprintf("\n[%s] %s", call.Function.Name, call.Function.Arguments)
+	}
+	return strings.TrimSpace(raw)
+}
+
+// shellBlockLanguages liste les langages de blocs de code considérés comme des commandes shell
+var shellBlockLanguages = map[string]bool{
+	"": true, "bash": true, "sh": true, "shell": true, "zsh": true, "console": true,
+}
+
+// extractCommandsFromResponse extrait, dans l'ordre, toutes les commandes des blocs
+// de code shell de la réponse ; elles forment le plan d'exécution
+func (a *Agent) extractCommandsFromResponse(response string) []string {
+	var commands []string
+
+	// Parcourir les blocs ``` ``` successifs
+	rest := response
+	for {
+		start := strings.Index(rest, "```")
+		if start == -1 {
+			break
+		}
+		rest = rest[start+3:]
+		end := strings.Index(rest, "```")
+		if end == -1 {
+			break
+		}
+		block := rest[:end]
+		rest = rest[end+3:]
+
+		// La première ligne indique le langage du bloc
+		lang := ""
+		if nl := strings.Index(block, "\n"); nl != -1 {
+			lang = strings.ToLower(strings.TrimSpace(block[:nl]))
+			block = block[nl+1:]
+		}
+		if !shellBlockLanguages[lang] {
+			continue
+		}
+
+		// Retirer les invites "$ " éventuellement recopiées par le modèle
+		lines := strings.Split(strings.TrimSpace(block), "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimPrefix(line, "$ ")
+		}
+		if cmd := strings.TrimSpace(strings.Join(lines, "\n")); cmd != "" {
+			commands = append(commands, cmd)
+		}
+	}
+	if len(commands) > 0 {
+		return commands
+	}
 
-	result := task
-	for _, term := range terms {
-		if strings.Contains(lowerTask, term) {
-			result = strings.ReplaceAll(result, term, "")
+	// Vérifier si la réponse contient une commande directement (sans bloc de code)
+	lowerResp := strings.ToLower(response)
+	if idx := strings.Index(lowerResp, "commande :"); idx != -1 {
+		line := response[idx+len("commande :"):]
+		if end := strings.Index(line, "\n"); end != -1 {
+			line = line[:end]
+		}
+		if cmd := strings.Trim(strings.TrimSpace(line), "`"); cmd != "" {
+			commands = append(commands, cmd)
 		}
 	}
 
-	return strings.TrimSpace(result)
+	return commands
 }
 
-// executeCommand exécute une commande shell, une par une, après confirmation et explication
-// Affiche et conserve les logs d'exécution pour le débogage
-// Relaie l'entrée standard pour permettre la saisie du mot de passe sudo
-func (a *Agent) executeCommand(cmd string) error {
-	// Résumer l'intention de la commande
-	fmt.Printf("\nJe m'apprête à exécuter la commande suivante :\n")
-	fmt.Printf("$ %s\n", cmd)
-	fmt.Printf("Voulez-vous que je l'exécute ? (oui/non) [ENTRÉE pour 'oui'] ")
+// planStep représente une étape d'un plan d'exécution
+type planStep struct {
+	Command     string
+	Explanation string
+}
 
-	// Attendre la confirmation de l'utilisateur
-	if !a.scanner.Scan() {
-		return fmt.Errorf("lecture de l'entrée utilisateur interrompue")
+// executePlan affiche le plan complet puis l'exécute étape par étape selon le choix
+// de l'utilisateur (tout approuver, une par une, annuler). L'exécution s'arrête au
+// premier échec, sauf si l'utilisateur choisit de continuer. Retourne, pour chaque
+// étape, l'observation à transmettre au modèle, et si l'utilisateur a abandonné le plan.
+func (a *Agent) executePlan(steps []planStep) ([]string, bool) {
+	observations := make([]string, len(steps))
+	for i := range observations {
+		observations[i] = "Étape non exécutée (plan interrompu)."
+	}
+
+	// En mode 'oui à tout' ou en simulation, le plan est approuvé d'office
+	approveAll := a.confirmPolicy == PolicyAutoAll || a.dryRun
+	if len(steps) > 1 {
+		fmt.Printf("\n📋 Plan d'exécution (%d étapes) :\n", len(steps))
+		for i, step := range steps {
+			fmt.Printf("  %d. $ %s\n", i+1, step.Command)
+			if step.Explanation != "" {
+				fmt.Printf("     %s\n", step.Explanation)
+			}
+		}
+	}
+	if len(steps) > 1 && a.confirmPolicy == PolicyDenyAll && !a.dryRun {
+		fmt.Print("\n⛔ Plan non exécuté (mode 'non à tout').\n\n")
+		for i := range observations {
+			observations[i] = "Commande refusée : le mode 'non à tout' est actif."
+			a.recordDecision(steps[i].Command, audit.DecisionDenied)
+		}
+		return observations, true
+	}
+	if len(steps) > 1 && !approveAll {
+		fmt.Print("\nExécuter le plan : [t]out approuver, [u]ne par une, [a]nnuler ? [ENTRÉE pour 'une par une'] ")
+		if !a.scanner.Scan() {
+			return observations, false
+		}
+		switch strings.ToLower(strings.TrimSpace(a.scanner.Text())) {
+		case "t", "tout", "all":
+			approveAll = true
+		case "a", "annuler", "cancel", "n", "non":
+			fmt.Print("\nPlan annulé.\n\n")
+			for i := range observations {
+				observations[i] = "L'utilisateur a annulé le plan : étape non exécutée."
+				a.recordDecision(steps[i].Command, audit.DecisionCancelled)
+			}
+			return observations, true
+		}
 	}
-	input := a.scanner.Text()
-	response := strings.ToLower(strings.TrimSpace(input))
 
-	// Par défaut, saisie vide = "oui"
-	if response == "" {
-		response = "oui"
-		fmt.Println("✅ (confirmation par défaut)")
+	for i, step := range steps {
+		cmd := step.Command
+		if len(steps) > 1 {
+			fmt.Printf("\n── Étape %d/%d ──\n", i+1, len(steps))
+		}
+
+		autoSafe := a.confirmPolicy == PolicyAutoSafe && risk.Classify(cmd).Level == risk.ReadOnly
+		if !approveAll && !autoSafe && len(steps) > 1 {
+			fmt.Printf("$ %s\n", cmd)
+			fmt.Print("[o]ui, [p]asser, [m]odifier, [a]rrêter ? [ENTRÉE pour 'oui'] ")
+			if !a.scanner.Scan() {
+				return observations, false
+			}
+			switch strings.ToLower(strings.TrimSpace(a.scanner.Text())) {
+			case "p", "passer", "skip":
+				observations[i] = "Étape ignorée par l'utilisateur."
+				a.recordDecision(cmd, audit.DecisionSkipped)
+				continue
+			case "a", "arrêter", "stop":
+				fmt.Print("\nPlan interrompu.\n\n")
+				return observations, true
+			case "m", "modifier", "edit":
+				fmt.Print("Nouvelle commande (ENTRÉE pour conserver) : ")
+				if !a.scanner.Scan() {
+					return observations, false
+				}
+				if edited := strings.TrimSpace(a.scanner.Text()); edited != "" {
+					cmd = edited
+				}
+			}
+		}
+
+		// Une étape déjà approuvée n'est pas reconfirmée
+		result, err := a.executeCommand(cmd, approveAll || len(steps) > 1)
+		if err == errDryRun {
+			observations[i] = dryRunObservation
+			continue
+		}
+		if err == errCommandDenied {
+			observations[i] = "Commande refusée : le mode 'non à tout' est actif."
+			return observations, true
+		}
+		if err == errCommandCancelled {
+			observations[i] = "L'utilisateur a refusé l'exécution de cette commande."
+			if len(steps) == 1 {
+				return observations, true
+			}
+			continue
+		}
+		if err != nil {
+			observations[i] = fmt.Sprintf("Échec de la commande: %v", err)
+		} else {
+			observations[i] = formatObservation(result)
+			if result.Success() {
+				continue
+			}
+		}
+
+		// Arrêt au premier échec, sauf si l'utilisateur choisit de continuer
+		if i == len(steps)-1 {
+			break
+		}
+		fmt.Printf("\n⚠️  L'étape %d a échoué. Continuer avec les étapes suivantes ? (oui/non) [ENTRÉE pour 'non'] ", i+1)
+		if !a.scanner.Scan() {
+			return observations, false
+		}
+		response := strings.ToLower(strings.TrimSpace(a.scanner.Text()))
+		if response != "oui" && response != "yes" && response != "y" && response != "o" {
+			fmt.Print("\nPlan interrompu après l'échec.\n\n")
+			return observations, false
+		}
+	}
+
+	return observations, false
+}
+
+// agentTools retourne les outils déclarés au modèle
+func agentTools() []types.Tool {
+	return []types.Tool{
+		{
+			Type: "function",
+			Function: types.FunctionDefinition{
+				Name:        "run_shell",
+				Description: "Exécute une commande shell sur la machine de l'utilisateur (après sa confirmation).",
+				Parameters: json.RawMessage(`{
+					"type": "object",
+					"properties": {
+						"command": {"type": "string", "description": "La commande shell à exécuter"},
+						"explanation": {"type": "string", "description": "Ce que fait la commande, en une phrase"}
+					},
+					"required": ["command"]
+				}`),
+			},
+		},
+		{
+			Type: "function",
+			Function: types.FunctionDefinition{
+				Name:        "web_search",
+				Description: "Recherche des informations récentes sur Internet.",
+				Parameters: json.RawMessage(`{
+					"type": "object",
+					"properties": {
+						"query": {"type": "string", "description": "Les termes de recherche"}
+					},
+					"required": ["query"]
+				}`),
+			},
+		},
+		{
+			Type: "function",
+			Function: types.FunctionDefinition{
+				Name:        "remember",
+				Description: "Mémorise une information importante dans la mémoire à long terme.",
+				Parameters: json.RawMessage(`{
+					"type": "object",
+					"properties": {
+						"content": {"type": "string", "description": "L'information à mémoriser"}
+					},
+					"required": ["content"]
+				}`),
+			},
+		},
+		{
+			Type: "function",
+			Function: types.FunctionDefinition{
+				Name:        "recall",
+				Description: "Recherche dans la mémoire à long terme les informations déjà mémorisées.",
+				Parameters: json.RawMessage(`{
+					"type": "object",
+					"properties": {
+						"query": {"type": "string", "description": "Le terme à rechercher"}
+					},
+					"required": ["query"]
+				}`),
+			},
+		},
 	}
+}
 
-	if response != "oui" && response != "yes" && response != "y" {
-		return fmt.Errorf("exécution de la commande annulée par l'utilisateur")
+// runTool exécute un appel d'outil demandé par le modèle et retourne le résultat à lui transmettre
+func (a *Agent) runTool(ctx context.Context, call types.ToolCall) string {
+	var args struct {
+		Command     string `json:"command"`
+		Explanation string `json:"explanation"`
+		Query       string `json:"query"`
+		Content     string `json:"content"`
+	}
+	if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+		return fmt.Sprintf("Erreur: arguments invalides pour %s: %v", call.Function.Name, err)
 	}
 
-	// Vérifier si la commande nécessite des privilèges élevés
-	needsSudo := strings.Contains(strings.ToLower(cmd), "sudo") || strings.Contains(strings.ToLower(cmd), "/etc/") || strings.Contains(strings.ToLower(cmd), "apt") || strings.Contains(strings.ToLower(cmd), "yum") || strings.Contains(strings.ToLower(cmd), "systemctl")
+	fmt.Printf("🔧 Outil demandé : %s\n", call.Function.Name)
 
-	var command *exec.Cmd
-	if needsSudo {
-		fmt.Println("\n⚠️  Cette commande nécessite des privilèges administrateur (sudo).")
-		fmt.Print("Confirmer l'exécution avec sudo ? (oui/non) [ENTRÉE pour 'oui'] ")
-		if !a.scanner.Scan() {
-			return fmt.Errorf("lecture de l'entrée utilisateur interrompue")
+	switch call.Function.Name {
+	case "run_shell":
+		if args.Command == "" {
+			return "Erreur: aucune commande fournie"
 		}
-		input := a.scanner.Text()
-		response := strings.ToLower(strings.TrimSpace(input))
+		if args.Explanation != "" {
+			fmt.Printf("ℹ️  %s\n", args.Explanation)
+		}
+		result, err := a.executeCommand(args.Command, false)
+		if err == errDryRun {
+			return dryRunObservation
+		}
+		if err == errCommandDenied {
+			return "Commande refusée : le mode 'non à tout' est actif. N'essayez pas d'autre commande."
+		}
+		if err == errCommandCancelled {
+			return "L'utilisateur a refusé l'exécution de cette commande."
+		}
+		if err != nil {
+			fmt.Printf("\nErreur lors de l'exécution de la commande: %v\n\n", err)
+			return fmt.Sprintf("Échec de la commande: %v", err)
+		}
+		return formatObservation(result)
 
-		// Par défaut, saisie vide = "oui"
-		if response == "" {
-			response = "oui"
-			fmt.Println("✅ (confirmation par défaut)")
+	case "web_search":
+		if a.webSearcher == nil {
+			return "Recherche Internet indisponible : aucun moteur de recherche configuré."
 		}
+		searchCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+		fmt.Printf("🔎 Recherche : %s\n", args.Query)
+		results, err := a.webSearcher.Search(searchCtx, args.Query)
+		if err != nil {
+			return fmt.Sprintf("Erreur lors de la recherche: %v", err)
+		}
+		return search.FormatSearchResults(results)
 
-		if response != "oui" && response != "yes" && response != "y" {
-			return fmt.Errorf("exécution de la commande annulée par l'utilisateur")
+	case "remember":
+		if a.knowledgeIntegrator == nil {
+			return "Mémoire à long terme indisponible."
 		}
+		keywords := a.storeMemory("agent", args.Content)
+		fmt.Printf("🧠 Information mémorisée avec les mots-clés : %v\n", keywords)
+		return fmt.Sprintf("Information mémorisée (mots-clés : %s).", strings.Join(keywords, ", "))
 
-		// Exécuter avec sudo -S pour permettre la saisie du mot de passe
-		cmd = strings.ReplaceAll(cmd, "sudo ", "")
-		fmt.Printf("🔐 Exécution avec sudo -S : %s\n", cmd)
-		command = exec.Command("sudo", "-S", "sh", "-c", cmd)
-
-		// Relier l'entrée/sortie du terminal au processus
-		command.Stdin = os.Stdin
-		command.Stdout = os.Stdout
-		command.Stderr = os.Stderr
-	} else {
-		fmt.Printf("➡️  Exécution : %s\n", cmd)
-		command = exec.Command("sh", "-c", cmd)
-		command.Stdout = os.Stdout
-		command.Stderr = os.Stderr
-		command.Stdin = os.Stdin
+	case "recall":
+		if a.knowledgeIntegrator == nil {
+			return "Mémoire à long terme indisponible."
+		}
+		return a.knowledgeIntegrator.FormatKnowledgeResponse(a.knowledgeIntegrator.SearchKnowledge(args.Query))
 	}
 
-	// Exécuter la commande (sans capturer la sortie, car affichée en direct)
-	err := command.Run()
-	if err != nil {
-		fmt.Printf("❌ La commande a échoué : %v\n", err)
-		return err
+	return fmt.Sprintf("Erreur: outil inconnu %q", call.Function.Name)
+}
+
+// isToolsUnsupported indique si l'erreur API signale que le modèle refuse les outils
+func isToolsUnsupported(err error) bool {
+	var apiErr *api.APIError
+	if !errors.As(err, &apiErr) || apiErr.Kind != api.KindBadRequest {
+		return false
+	}
+	if apiErr.StatusCode != 400 && apiErr.StatusCode != 422 {
+		return false
 	}
+	return strings.HasPrefix(apiErr.Param, "tool") ||
+		strings.Contains(strings.ToLower(apiErr.Message), "tool")
+}
 
-	fmt.Printf("✅ Commande exécutée avec succès.\n")
-	return nil
+// reportAPIError affiche l'échec d'un appel au modèle avec la marche à suivre
+func reportAPIError(err error) {
+	fmt.Printf("\n❌ %s\n", describeAPIError(err))
+
+	var apiErr *api.APIError
+	if errors.As(err, &apiErr) {
+		fmt.Printf("   Détail : %s\n", apiErr.Message)
+		if apiErr.RequestID != "" {
+			fmt.Printf("   Identifiant de requête : %s\n", apiErr.RequestID)
+		}
+	}
+	fmt.Println()
+}
+
+// describeAPIError traduit une erreur d'appel au modèle en message compréhensible
+func describeAPIError(err error) string {
+	var apiErr *api.APIError
+	if !errors.As(err, &apiErr) {
+		return fmt.Sprintf("Erreur lors de l'appel API: %v", err)
+	}
+
+	switch apiErr.Kind {
+	case api.KindAuth:
+		return "Clé API refusée par le fournisseur. Vérifiez-la avec 'config' et corrigez-la avec 'set-api-key <clé>'."
+	case api.KindQuota:
+		return "Le crédit ou le quota du compte est épuisé. Vérifiez la facturation auprès du fournisseur ou changez de modèle avec 'set-model'."
+	case api.KindRateLimited:
+		return "Limite de requêtes atteinte malgré plusieurs tentatives. Patientez un peu avant de réessayer."
+	case api.KindOverloaded:
+		return "Le service d'IA est surchargé. Réessayez plus tard ou changez de modèle avec 'set-model'."
+	case api.KindServer:
+		return fmt.Sprintf("Le fournisseur a rencontré une erreur interne (%d). Réessayez plus tard.", apiErr.StatusCode)
+	case api.KindNetwork:
+		if errors.Is(err, context.DeadlineExceeded) {
+			return "Le fournisseur n'a pas répondu à temps. Réessayez ou vérifiez l'URL avec 'config'."
+		}
+		return "Impossible de joindre le fournisseur. Vérifiez votre connexion et l'URL de base ('set-base-url')."
+	}
+
+	// Requête refusée : distinguer les causes les plus courantes
+	message := strings.ToLower(apiErr.Message)
+	switch {
+	case apiErr.Code == "context_length_exceeded" || strings.Contains(message, "context length") ||
+		strings.Contains(message, "maximum context"):
+		return "La conversation dépasse la fenêtre de contexte du modèle. Utilisez 'context compact' pour résumer les anciens échanges."
+	case apiErr.Code == "model_not_found" || apiErr.StatusCode == 404:
+		return "Le modèle ou l'adresse demandés sont introuvables chez ce fournisseur. Vérifiez 'set-model' et 'set-base-url'."
+	case apiErr.Param != "":
+		return fmt.Sprintf("Requête refusée par le fournisseur (paramètre '%s').", apiErr.Param)
+	}
+	return fmt.Sprintf("Requête refusée par le fournisseur (%d).", apiErr.StatusCode)
+}
+
+// searcherFromEnv crée le moteur de recherche choisi par SEARCH_BACKEND. À défaut,
+// le moteur est déduit des clés configurées : Google Custom Search si GOOGLE_CSE_ID
+// est défini, SerpAPI si SEARCH_API_KEY l'est, sinon DuckDuckGo, qui ne demande
+// aucune clé. Retourne nil si le moteur choisi est mal configuré.
+func searcherFromEnv() search.Searcher {
+	cfg := search.Config{
+		Backend:  os.Getenv("SEARCH_BACKEND"),
+		APIKey:   os.Getenv("SEARCH_API_KEY"),
+		CX:       os.Getenv("GOOGLE_CSE_ID"),
+		Endpoint: os.Getenv("SEARCH_ENDPOINT"),
+	}
+	if cfg.Backend == "" {
+		switch {
+		case cfg.APIKey != "" && cfg.CX != "":
+			cfg.Backend = search.BackendGoogleCSE
+		case cfg.APIKey != "":
+			cfg.Backend = search.BackendSerpAPI
+		default:
+			cfg.Backend = search.BackendDuckDuckGo
+		}
+	}
+
+	searcher, err := search.New(cfg)
+	if err != nil {
+		fmt.Printf("Avertissement: %v. Les fonctionnalités de recherche seront désactivées.\n", err)
+		return nil
+	}
+	return searcher
 }
 
-// extractCommandFromResponse extrait la commande d'un bloc de code dans la réponse
-func (a *Agent) extractCommandFromResponse(response string) (string, bool) {
-	// Rechercher un bloc de code dans les ``` ```
-	start := strings.Index(response, "```bash")
-	if start == -1 {
-		start = strings.Index(response, "```sh")
+// retryPolicyFromEnv construit la politique de nouvelles tentatives des appels API
+// à partir de API_RETRY_ATTEMPTS et API_RETRY_BACKOFF
+func retryPolicyFromEnv() api.RetryPolicy {
+	policy := api.DefaultRetryPolicy()
+	if val, err := strconv.Atoi(os.Getenv("API_RETRY_ATTEMPTS")); err == nil && val > 0 {
+		policy.MaxAttempts = val
+	}
+	if val, err := time.ParseDuration(os.Getenv("API_RETRY_BACKOFF")); err == nil && val > 0 {
+		policy.InitialBackoff = val
 	}
-	if start == -1 {
-		start = strings.Index(response, "```")
+	policy.OnRetry = func(attempt int, wait time.Duration, err *api.APIError) {
+		fmt.Printf("\n⏳ %s, nouvelle tentative (%d/%d) dans %s...\n",
+			retryReason(err), attempt, policy.MaxAttempts, wait.Round(100*time.Millisecond))
 	}
+	policy.OnFallback = func(next api.Target, err *api.APIError) {
+		fmt.Printf("\n↪️  %s, bascule vers %s\n", retryReason(err), next)
+	}
+	return policy
+}
 
-	if start != -1 {
-		start += len("```")
-		// Extraire le contenu du bloc de code
-		end := strings.Index(response[start:], "```")
-		if end != -1 {
-			codeBlock := strings.TrimSpace(response[start : start+end])
-			// Enlever "bash" ou "sh" du début si présent
-			codeBlock = strings.TrimPrefix(codeBlock, "bash")
-			codeBlock = strings.TrimPrefix(codeBlock, "sh")
-			codeBlock = strings.TrimSpace(codeBlock)
-			return codeBlock, true
+// fallbackTargetsFromEnv lit les cibles de repli API_FALLBACK_<n>_BASE_URL,
+// API_FALLBACK_<n>_API_KEY et API_FALLBACK_<n>_MODEL (n = 1, 2, ...). L'URL et la
+// clé de la cible principale sont reprises lorsqu'elles ne sont pas précisées.
+func fallbackTargetsFromEnv(baseURL, apiKey string) []api.Target {
+	var targets []api.Target
+	for n := 1; ; n++ {
+		prefix := fmt.Sprintf("API_FALLBACK_%d_", n)
+		target := api.Target{
+			BaseURL:  os.Getenv(prefix + "BASE_URL"),
+			APIKey:   os.Getenv(prefix + "API_KEY"),
+			Model:    os.Getenv(prefix + "MODEL"),
+			Provider: os.Getenv(prefix + "PROVIDER"),
 		}
+		if target.BaseURL == "" && target.Model == "" {
+			return targets
+		}
+		if target.BaseURL == "" {
+			target.BaseURL = baseURL
+		}
+		if target.APIKey == "" {
+			target.APIKey = apiKey
+		}
+		if target.Model == "" {
+			fmt.Printf("Avertissement: %sMODEL manquant, cible de repli ignorée\n", prefix)
+			continue
+		}
+		if _, err := api.ProviderFor(target.Provider); err != nil {
+			fmt.Printf("Avertissement: %v, cible de repli ignorée\n", err)
+			continue
+		}
+		targets = append(targets, target)
+	}
+}
+
+// retryReason décrit brièvement la cause d'une nouvelle tentative
+func retryReason(err *api.APIError) string {
+	switch err.Kind {
+	case api.KindRateLimited:
+		return "Limite de requêtes atteinte"
+	case api.KindOverloaded:
+		return "Service surchargé"
+	case api.KindNetwork:
+		return "Connexion interrompue"
+	default:
+		return fmt.Sprintf("Erreur du serveur (%d)", err.StatusCode)
 	}
+}
 
-	// Vérifier si la réponse contient une commande directement (sans bloc de code)
-	// Chercher les séquences de commande après "```" ou "commande :"
-	lowerResp := strings.ToLower(response)
-	if strings.Contains(lowerResp, "commande :") {
-		start = strings.Index(lowerResp, "commande :") + len("commande :")
-		// Chercher la ligne suivante ou la fin de la ligne
-		end := strings.Index(response[start:], "\n")
-		if end == -1 {
-			end = len(response) - start
-		} else {
-			end = end + strings.Index(response, response[start:]) + start - start
-		}
-		cmd := strings.TrimSpace(response[start:end])
-		if cmd != "" {
-			return cmd, true
-		}
-	}
-
-	// Chercher les blocs de commandes après "voici la commande" ou "vous pouvez utiliser"
-	indicators := []string{"voici la commande", "vous pouvez utiliser", "utilise cette commande", "commande pour", "exécute cette commande"}
-	for _, indicator := range indicators {
-		if idx := strings.Index(lowerResp, indicator); idx != -1 {
-			// Chercher le début du bloc de commande
-			cmdStart := idx + len(indicator)
-			// Chercher un bloc de code ou une commande en ligne
-			if nextCode := strings.Index(response[cmdStart:], "```"); nextCode != -1 {
-				cmdStart += nextCode + 3
-				if endCode := strings.Index(response[cmdStart:], "```"); endCode != -1 {
-					cmd := strings.TrimSpace(response[cmdStart : cmdStart+endCode])
-					if cmd != "" {
-						return cmd, true
-					}
-				}
-			}
+// runShellPlan exécute sous forme de plan les appels run_shell d'une même réponse
+// lorsqu'il y en a plusieurs ; retourne les observations indexées par identifiant d'appel
+func (a *Agent) runShellPlan(calls []types.ToolCall) map[string]string {
+	var steps []planStep
+	var ids []string
+	for _, call := range calls {
+		if call.Function.Name != "run_shell" {
+			continue
+		}
+		var args struct {
+			Command     string `json:"command"`
+			Explanation string `json:"explanation"`
 		}
+		if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil || args.Command == "" {
+			continue
+		}
+		steps = append(steps, planStep{Command: args.Command, Explanation: args.Explanation})
+		ids = append(ids, call.ID)
+	}
+	if len(steps) < 2 {
+		return nil
 	}
 
-	return "", false
+	observations, _ := a.executePlan(steps)
+	planned := make(map[string]string, len(ids))
+	for i, id := range ids {
+		planned[id] = observations[i]
+	}
+	return planned
+}
+
+// joinObservations regroupe les observations d'un plan en un seul message
+func joinObservations(steps []planStep, observations []string) string {
+	if len(steps) == 1 {
+		return observations[0]
+	}
+	var sb strings.Builder
+	for i, step := range steps {
+		sb.WriteString(fmt.Sprintf("Étape %d ($ %s) :\n%s\n", i+1, step.Command, observations[i]))
+	}
+	return sb.String()
 }
 
 // processWithAI traite une tâche avec le modèle d'intelligence artificielle
 func (a *Agent) processWithAI(task string) {
-	if a.APIConfig.APIKey == "" {
+	if !a.modelAvailable() {
 		fmt.Println("\nErreur: Clé API non configurée. Veuillez configurer votre clé API avec 'set-api-key'.\n")
 		return
 	}
 
 	fmt.Printf("\n[AI] Analyse et exécution de la tâche...\n")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-	defer cancel()
+	ctx := context.Background()
 
-	// Ajouter le message utilisateur à l'historique
-	a.messages = append(a.messages, types.Message{
-		Role:    "user",
-		Content: task,
-	})
+	// Ajouter le message utilisateur à l'historique, avec les pièces jointes en attente
+	a.messages = append(a.messages, a.userMessage(task))
 
-	// Appeler l'API avec tout l'historique des messages
-	resp, err := a.apiClient.ChatCompletion(ctx, a.messages)
-	if err != nil {
-		// Vérifier si c'est une erreur 503 (Service Unavailable)
-		if strings.Contains(err.Error(), "503") || strings.Contains(err.Error(), "engine_overloaded") {
+	// Appeler l'API avec tout l'historique des messages, en affichant la réponse au fil de l'eau
+	if err := a.converse(ctx, task, true); err != nil {
+		// Service toujours surchargé après les nouvelles tentatives : se rabattre
+		// sur la recherche Internet lorsqu'elle est configurée
+		var apiErr *api.APIError
+		if errors.As(err, &apiErr) && apiErr.Kind == api.KindOverloaded && a.webSearcher != nil {
 			fmt.Printf("\nLe service d'IA est temporairement surchargé. Tentative de récupération avec recherche...\n")
 			// Forcer l'utilisation de la recherche Internet en cas de panne du service IA
-			a.performWebSearch(task)
+			a.performWebSearch(task, "")
 			return
 		}
-		fmt.Printf("\nErreur lors de l'appel API: %v\n", err)
-		return
+		reportAPIError(err)
 	}
+}
 
-	// Ajouter la réponse de l'IA à l'historique
-	a.messages = append(a.messages, types.Message{
-		Role:    "assistant",
-		Content: resp.Choices[0].Message.Content,
-	})
+// converse fait travailler l'agent en boucle : le modèle propose une action,
+// l'agent l'exécute et lui renvoie le résultat observé, jusqu'à ce que le modèle
+// déclare la tâche terminée ou que la limite d'étapes soit atteinte. Si remember
+// est vrai, les réponses sont enregistrées dans la mémoire à long terme.
+func (a *Agent) converse(ctx context.Context, task string, remember bool) error {
+	a.currentTask = task
+	for step := 1; step <= a.maxSteps; step++ {
+		// Enregistrer la progression pour ne rien perdre en cas d'interruption
+		if step > 1 {
+			a.saveSession()
+		}
 
-	// Enregistrer l'interaction dans la mémoire à long terme
-	a.rememberInteraction(task, resp.Choices[0].Message.Content)
+		// Résumer les anciens tours si la fenêtre de contexte est presque pleine
+		a.compactContext(ctx, false)
 
-	// Afficher la réponse
-	fmt.Printf("\n%s\n\n", resp.Choices[0].Message.Content)
+		resp, err := a.streamCompletion(ctx)
+		if err != nil {
+			return err
+		}
+		reply := resp.Choices[0].Message
+		a.lastResponse = rawResponse(reply)
+		a.lastModel = resp.Model
+
+		// Ajouter la réponse de l'IA à l'historique
+		a.messages = append(a.messages, types.Message{
+			Role:      "assistant",
+			Content:   reply.Content,
+			ToolCalls: reply.ToolCalls,
+		})
 
-	// Afficher la réponse
-	fmt.Printf("\n%s\n\n", resp.Choices[0].Message.Content)
+		if len(reply.ToolCalls) > 0 {
+			// Plusieurs commandes demandées dans la même réponse forment un plan
+			planned := a.runShellPlan(reply.ToolCalls)
 
-	// Extraire et exécuter la commande si présente
-	if cmd, found := a.extractCommandFromResponse(resp.Choices[0].Message.Content); found {
-		err := a.executeCommand(cmd)
-		if err != nil {
-			fmt.Printf("\nErreur lors de l'exécution de la commande: %v\n", err)
-		} else {
-			fmt.Printf("\nCommande exécutée avec succès.\n\n")
+			// Exécuter chaque outil demandé et renvoyer son résultat au modèle
+			for _, call := range reply.ToolCalls {
+				content, ok := planned[call.ID]
+				if !ok {
+					content = a.runTool(ctx, call)
+				}
+				a.messages = append(a.messages, types.Message{
+					Role:       "tool",
+					ToolCallID: call.ID,
+					Name:       call.Function.Name,
+					Content:    content,
+				})
+			}
+			continue
+		}
+
+		// Enregistrer l'interaction dans la mémoire à long terme
+		if remember {
+			a.rememberInteraction(task, reply.Content)
+		}
+
+		// Avec les outils natifs, une réponse sans appel d'outil est la réponse finale
+		if a.toolsEnabled || strings.Contains(reply.Content, taskDoneMarker) {
+			return nil
+		}
+
+		// Repli pour les modèles sans appel d'outils : extraire les commandes du texte
+		commands := a.extractCommandsFromResponse(reply.Content)
+		if len(commands) == 0 {
+			return nil
+		}
+		steps := make([]planStep, len(commands))
+		for i, cmd := range commands {
+			steps[i] = planStep{Command: cmd}
+		}
+		observations, aborted := a.executePlan(steps)
+		if aborted {
+			fmt.Print("\nExécution annulée, fin de la tâche.\n\n")
+			return nil
+		}
+		if a.dryRun {
+			// Rien n'a été exécuté : il n'y a pas de résultat à observer
+			return nil
+		}
+
+		a.messages = append(a.messages, types.Message{
+			Role: "user",
+			Content: "Résultat de l'exécution :\n" + joinObservations(steps, observations) +
+				"\nSi la tâche est terminée, répondez par un bref résumé suivi de " + taskDoneMarker +
+				". Sinon, donnez la prochaine commande à exécuter.",
+		})
+	}
+
+	fmt.Printf("\n⚠️  Limite de %d étapes atteinte, arrêt de la tâche (voir 'max-steps').\n\n", a.maxSteps)
+	return nil
+}
+
+// streamCompletion appelle l'API en streaming avec l'historique courant et affiche
+// les tokens au fur et à mesure de leur arrivée
+func (a *Agent) streamCompletion(ctx context.Context) (*types.ChatResponse, error) {
+	var tools []types.Tool
+	if a.toolsEnabled {
+		tools = agentTools()
+	}
+
+	if err := a.checkBudget(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, apiCallTimeout)
+	defer cancel()
+
+	// Avec des cibles de repli, indiquer en tête de réponse la cible qui a répondu
+	headerShown := false
+	showHeader := func() {
+		if !headerShown && len(a.APIConfig.Fallbacks) > 0 {
+			fmt.Printf("🤖 %s\n", a.apiClient.LastTarget())
 		}
+		headerShown = true
+	}
+
+	fmt.Println()
+	// max_tokens ne doit pas faire dépasser la fenêtre de contexte du modèle
+	opts := a.chatOptions("", tools)
+	opts.MaxTokens = a.contextManager.ResponseTokens(a.messages, opts.MaxTokens)
+	resp, err := a.apiClient.ChatCompletionStream(ctx, a.messages, opts, func(delta string) {
+		showHeader()
+		fmt.Print(delta)
+	})
+	if err != nil && tools != nil && isToolsUnsupported(err) {
+		// Le modèle ne gère pas l'appel d'outils : basculer sur l'extraction des blocs de code
+		fmt.Println("⚠️  Le modèle ne prend pas en charge l'appel d'outils, retour à l'extraction des commandes.")
+		a.toolsEnabled = false
+		return a.streamCompletion(ctx)
+	}
+	if err != nil {
+		return nil, err
 	}
+	showHeader()
+	fmt.Print("\n\n")
+	a.recordUsage(resp, a.messages)
+
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("réponse vide du modèle")
+	}
+	return resp, nil
 }
 
-// processWithAIBasedOnSearch traite une tâche avec le modèle d'intelligence artificielle en utilisant les résultats de recherche
-func (a *Agent) processWithAIBasedOnSearch(task, searchResults string) {
-	if a.APIConfig.APIKey == "" {
+// citationInstruction demande au modèle de citer les sources numérotées des résultats de recherche
+const citationInstruction = "Appuyez votre réponse sur ces informations. Citez la source de chaque information " +
+	"par son numéro entre crochets, par exemple [1] ou [2, 3], juste après l'information qu'elle appuie. " +
+	"N'utilisez que les numéros des sources ci-dessus et n'ajoutez pas de liste des sources : elle sera affichée à l'utilisateur."
+
+// processWithAIBasedOnSearch traite une tâche avec le modèle d'intelligence artificielle en utilisant les résultats de recherche.
+// Les sources citées dans la réponse sont vérifiées, affichées et conservées avec la réponse dans l'historique.
+func (a *Agent) processWithAIBasedOnSearch(task, searchResults string, sources []types.Source) {
+	if !a.modelAvailable() {
 		fmt.Println("\nErreur: Clé API non configurée. Veuillez configurer votre clé API avec 'set-api-key'.\n")
 		return
 	}
 
 	fmt.Printf("\n[AI] Analyse des résultats de recherche et exécution de la tâche...\n")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-	defer cancel()
+	ctx := context.Background()
 
 	// Ajouter le message utilisateur à l'historique
 	a.messages = append(a.messages, types.Message{
 		Role:    "user",
-		Content: fmt.Sprintf("Tâche: %s\n\nRésultats de recherche:\n%s", task, searchResults),
+		Content: fmt.Sprintf("Tâche: %s\n\nRésultats de recherche:\n%s\n\n%s", task, searchResults, citationInstruction),
 	})
 
-	// Appeler l'API avec tout l'historique des messages
-	resp, err := a.apiClient.ChatCompletion(ctx, a.messages)
-	if err != nil {
-		fmt.Printf("\nErreur lors de l'appel API: %v\n", err)
+	// Appeler l'API avec tout l'historique des messages, en affichant la réponse au fil de l'eau
+	if err := a.converse(ctx, task, false); err != nil {
+		reportAPIError(err)
 		return
 	}
 
-	// Afficher la réponse
-	fmt.Printf("\n%s\n\n", resp.Choices[0].Message.Content)
-
-	// Ajouter la réponse de l'IA à l'historique
-	a.messages = append(a.messages, types.Message{
-		Role:    "assistant",
-		Content: resp.Choices[0].Message.Content,
-	})
+	// La réponse finale est le dernier message de l'assistant, s'il suit la
+	// dernière demande (l'historique a pu être résumé entre-temps)
+	for i := len(a.messages) - 1; i > 0 && a.messages[i].Role != "user"; i-- {
+		if a.messages[i].Role == "assistant" && a.messages[i].Content != "" {
+			a.messages[i].Sources = sources
+			a.showCitedSources(a.messages[i].Content, sources)
+			return
+		}
+	}
+}
 
-	// Extraire et exécuter la commande si présente
-	if cmd, found := a.extractCommandFromResponse(resp.Choices[0].Message.Content); found {
-		err := a.executeCommand(cmd)
-		if err != nil {
-			fmt.Printf("\nErreur lors de l'exécution de la commande: %v\n\n", err)
-		} else {
-			fmt.Printf("\nCommande exécutée avec succès.\n\n")
+// showCitedSources vérifie les citations d'une réponse et affiche les sources citées
+func (a *Agent) showCitedSources(reply string, sources []types.Source) {
+	cited, unknown := search.CheckCitations(reply, sources)
+	if len(unknown) > 0 {
+		labels := make([]string, len(unknown))
+		for i, n := range unknown {
+			labels[i] = fmt.Sprintf("[%d]", n)
 		}
+		fmt.Printf("⚠️  Citation(s) sans source correspondante : %s\n", strings.Join(labels, ", "))
+	}
+	if len(cited) == 0 {
+		fmt.Print("⚠️  La réponse ne cite aucune source.\n\n")
+		return
 	}
+	fmt.Println(search.FormatSources(cited))
 }
 
 // maskString masque une partie d'une chaîne (pour les clés API)
@@ -978,7 +3245,115 @@ func maskString(s string) string {
 	return s[:4] + "..." + s[len(s)-4:]
 }
 
+// truncateText raccourcit un texte sur une seule ligne à max caractères
+func truncateText(text string, max int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max-1]) + "…"
+}
+
+// onOff retourne un libellé lisible pour un booléen d'activation
+func onOff(enabled bool) string {
+	if enabled {
+		return "activé"
+	}
+	return "désactivé"
+}
+
+// runJSONMode traite une demande unique (arguments ou entrée standard) et
+// n'écrit sur la sortie standard que la réponse JSON validée, pour les scripts.
+// Les messages de l'agent sont redirigés vers la sortie d'erreur. Retourne le
+// code de sortie du programme.
+func runJSONMode(prompt, schemaPath string) int {
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+
+	agent := NewAgent()
+	agent.initClient()
+
+	if strings.TrimSpace(prompt) == "" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Erreur: lecture de la demande: %v\n", err)
+			return 1
+		}
+		prompt = string(data)
+	}
+	if strings.TrimSpace(prompt) == "" {
+		fmt.Fprintln(os.Stderr, "Erreur: aucune demande (en argument ou sur l'entrée standard)")
+		return 2
+	}
+
+	var schemaJSON json.RawMessage
+	if schemaPath != "" {
+		data, err := os.ReadFile(schemaPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Erreur: lecture du schéma: %v\n", err)
+			return 2
+		}
+		schemaJSON = data
+	}
+
+	if err := agent.checkBudget(); err != nil {
+		fmt.Fprintf(os.Stderr, "Erreur: %v\n", err)
+		return 1
+	}
+
+	messages := []types.Message{
+		{
+			Role: "system",
+			Content: "Vous êtes un assistant interrogé par un programme. Répondez à la demande de " +
+				"l'utilisateur de manière exacte et concise, sans inventer d'information.",
+		},
+		{Role: "user", Content: prompt},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), apiCallTimeout)
+	defer cancel()
+	result, err := agent.apiClient.ChatCompletionJSON(ctx, messages, agent.chatOptions("", nil), api.JSONRequest{Schema: schemaJSON})
+	if result.Attempts > 0 {
+		agent.recordUsage(&types.ChatResponse{Model: result.Model, Usage: result.Usage}, messages)
+	}
+	if err != nil {
+		var apiErr *api.APIError
+		if errors.As(err, &apiErr) {
+			fmt.Fprintf(os.Stderr, "Erreur: %s\n", describeAPIError(err))
+		} else {
+			fmt.Fprintf(os.Stderr, "Erreur: %v\n", err)
+		}
+		return 1
+	}
+
+	var out bytes.Buffer
+	if err := json.Compact(&out, result.Data); err != nil {
+		out.Reset()
+		out.Write(result.Data)
+	}
+	fmt.Fprintln(stdout, out.String())
+	return 0
+}
+
 func main() {
+	dryRun := flag.Bool("dry-run", false, "Explique les commandes proposées sans les exécuter")
+	resume := flag.String("resume", "", "Reprend une session enregistrée (identifiant, préfixe ou 'last')")
+	jsonMode := flag.Bool("json", false, "Traite une seule demande et n'affiche que la réponse JSON validée")
+	schemaPath := flag.String("schema", "", "Schéma JSON auquel la réponse doit se conformer (avec --json)")
+	flag.Parse()
+
+	if *jsonMode {
+		os.Exit(runJSONMode(strings.Join(flag.Args(), " "), *schemaPath))
+	}
+
 	agent := NewAgent()
+	agent.dryRun = *dryRun
+	if *resume != "" {
+		if err := agent.resumeSession(*resume); err != nil {
+			fmt.Printf("Erreur: %v\n", err)
+			os.Exit(1)
+		}
+	}
 	agent.Start()
 }
@@ -0,0 +1,508 @@
+package risk
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Redirect représente une redirection d'entrée/sortie (>, >>, <, ...)
+type Redirect struct {
+	Op     string
+	Target string
+}
+
+// Command représente une commande simple extraite d'une ligne shell
+type Command struct {
+	Name      string
+	Args      []string
+	Redirects []Redirect
+
+	// La commande est précédée de sudo (ou doas)
+	Sudo bool
+
+	// La commande reçoit l'entrée standard d'un pipe
+	Piped bool
+}
+
+// String retourne la commande sous une forme lisible
+func (c Command) String() string {
+	parts := append([]string{c.Name}, c.Args...)
+	for _, r := range c.Redirects {
+		parts = append(parts, r.Op+" "+r.Target)
+	}
+	s := strings.Join(parts, " ")
+	if c.Sudo && c.Name != "sudo" && c.Name != "doas" {
+		s = "sudo " + s
+	}
+	return s
+}
+
+// tokenKind distingue les mots des opérateurs
+type tokenKind int
+
+const (
+	wordToken tokenKind = iota
+	opToken
+)
+
+// token représente un élément lexical d'une ligne shell, avec sa position
+// [start, end) dans la ligne d'origine
+type token struct {
+	kind       tokenKind
+	value      string
+	start, end int
+}
+
+// Parse découpe une ligne shell en commandes simples, en tenant compte des guillemets,
+// des pipes, des séparateurs (;, &&, ||, &), des redirections, des sous-shells et des
+// substitutions de commandes $(...) et `...`, dont le contenu est analysé récursivement.
+func Parse(line string) ([]Command, error) {
+	tokens, nested, err := lex(line)
+	if err != nil {
+		return nil, err
+	}
+
+	commands := buildCommands(tokens)
+	for _, inner := range nested {
+		sub, err := Parse(inner)
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, sub...)
+	}
+	return commands, nil
+}
+
+// operators liste les opérateurs reconnus, les plus longs en premier
+var operators = []string{
+	"<<<", "&&", "||", ";;", "|&", ">>", "&>", ">&", "<<", "<>", ">|",
+	"|", "&", ";", "<", ">", "(", ")",
+}
+
+// lex transforme une ligne shell en jetons. Le contenu des substitutions de
+// commandes est retourné séparément pour être analysé à son tour.
+func lex(line string) ([]token, []string, error) {
+	var (
+		tokens    []token
+		nested    []string
+		cur       strings.Builder
+		inWord    bool
+		wordStart int
+		heredocs  []heredoc
+		wantDoc   bool
+	)
+
+	endWord := func(end int) {
+		if !inWord {
+			return
+		}
+		tokens = append(tokens, token{kind: wordToken, value: cur.String(), start: wordStart, end: end})
+		if wantDoc {
+			// Un délimiteur cité (<<'EOF', <<"EOF", <<\EOF) désactive les substitutions du corps
+			quoted := strings.ContainsAny(line[wordStart:end], `'"\`)
+			heredocs = append(heredocs, heredoc{delim: cur.String(), expand: !quoted})
+			wantDoc = false
+		}
+		cur.Reset()
+		inWord = false
+	}
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if !inWord {
+			wordStart = i
+		}
+		switch {
+		case c == ' ' || c == '\t' || c == '\r':
+			endWord(i)
+
+		case c == '\n':
+			endWord(i)
+			tokens = append(tokens, token{kind: opToken, value: ";", start: i, end: i + 1})
+			// Ignorer le corps des here-documents, sauf les substitutions qu'il contient
+			for len(heredocs) > 0 {
+				doc := heredocs[0]
+				heredocs = heredocs[1:]
+				for i+1 < len(line) {
+					end := strings.IndexByte(line[i+1:], '\n')
+					var docLine string
+					if end == -1 {
+						docLine = line[i+1:]
+						i = len(line) - 1
+					} else {
+						docLine = line[i+1 : i+1+end]
+						i += end + 1
+					}
+					if strings.TrimSpace(docLine) == doc.delim {
+						break
+					}
+					if doc.expand {
+						subs, err := substitutions(docLine)
+						if err != nil {
+							return nil, nil, err
+						}
+						nested = append(nested, subs...)
+					}
+				}
+			}
+
+		case c == '#' && !inWord:
+			// Commentaire jusqu'à la fin de la ligne
+			end := strings.IndexByte(line[i:], '\n')
+			if end == -1 {
+				i = len(line)
+			} else {
+				i += end - 1
+			}
+
+		case c == '\\':
+			if i+1 < len(line) {
+				i++
+				if line[i] != '\n' {
+					cur.WriteByte(line[i])
+					inWord = true
+				}
+			}
+
+		case c == '\'':
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end == -1 {
+				return nil, nil, fmt.Errorf("apostrophe non fermée")
+			}
+			cur.WriteString(line[i+1 : i+1+end])
+			inWord = true
+			i += end + 1
+
+		case c == '"':
+			j := i + 1
+			for ; j < len(line) && line[j] != '"'; j++ {
+				switch {
+				case line[j] == '\\' && j+1 < len(line):
+					j++
+					cur.WriteByte(line[j])
+				case line[j] == '$' && j+1 < len(line) && line[j+1] == '(':
+					inner, end, err := balanced(line, j+1)
+					if err != nil {
+						return nil, nil, err
+					}
+					nested = append(nested, inner)
+					cur.WriteString(line[j : end+1])
+					j = end
+				case line[j] == '`':
+					end := strings.IndexByte(line[j+1:], '`')
+					if end == -1 {
+						return nil, nil, fmt.Errorf("apostrophe inverse non fermée")
+					}
+					nested = append(nested, line[j+1:j+1+end])
+					cur.WriteString(line[j : j+2+end])
+					j += end + 1
+				default:
+					cur.WriteByte(line[j])
+				}
+			}
+			if j >= len(line) {
+				return nil, nil, fmt.Errorf("guillemet non fermé")
+			}
+			inWord = true
+			i = j
+
+		case c == '`':
+			end := strings.IndexByte(line[i+1:], '`')
+			if end == -1 {
+				return nil, nil, fmt.Errorf("apostrophe inverse non fermée")
+			}
+			nested = append(nested, line[i+1:i+1+end])
+			cur.WriteString(line[i : i+2+end])
+			inWord = true
+			i += end + 1
+
+		case c == '$' && i+1 < len(line) && (line[i+1] == '(' || line[i+1] == '{'):
+			inner, end, err := balanced(line, i+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			// $(( )) est une expression arithmétique, ${ } une variable
+			if line[i+1] == '(' && !strings.HasPrefix(inner, "(") {
+				nested = append(nested, inner)
+			}
+			cur.WriteString(line[i : end+1])
+			inWord = true
+			i = end
+
+		case (c == '<' || c == '>') && i+1 < len(line) && line[i+1] == '(':
+			// Substitution de processus <(...) ou >(...)
+			inner, end, err := balanced(line, i+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			nested = append(nested, inner)
+			cur.WriteString(line[i : end+1])
+			inWord = true
+			i = end
+
+		case strings.IndexByte("|&;<>()", c) != -1:
+			// Un numéro de descripteur collé à une redirection (2>, 1>>) n'est pas un mot
+			if (c == '<' || c == '>') && inWord && isDigits(cur.String()) {
+				cur.Reset()
+				inWord = false
+			}
+			endWord(i)
+			for _, op := range operators {
+				if strings.HasPrefix(line[i:], op) {
+					tokens = append(tokens, token{kind: opToken, value: op, start: i, end: i + len(op)})
+					i += len(op) - 1
+					if op == "<<" {
+						// Le mot suivant est le délimiteur du here-document ("<<-" inclus)
+						if i+1 < len(line) && line[i+1] == '-' {
+							i++
+						}
+						wantDoc = true
+					}
+					break
+				}
+			}
+
+		default:
+			cur.WriteByte(c)
+			inWord = true
+		}
+	}
+	endWord(len(line))
+
+	return tokens, nested, nil
+}
+
+// heredoc décrit un here-document en attente de son corps
+type heredoc struct {
+	delim  string
+	expand bool // corps soumis aux substitutions du shell
+}
+
+// substitutions retourne les commandes substituées ($(...) et `...`) d'une
+// ligne de here-document dont le corps est interprété par le shell
+func substitutions(text string) ([]string, error) {
+	var found []string
+	for i := 0; i < len(text); i++ {
+		switch {
+		case text[i] == '\\':
+			i++
+		case text[i] == '$' && i+1 < len(text) && text[i+1] == '(':
+			inner, end, err := balanced(text, i+1)
+			if err != nil {
+				return nil, err
+			}
+			if !strings.HasPrefix(inner, "(") {
+				found = append(found, inner)
+			}
+			i = end
+		case text[i] == '`':
+			end := strings.IndexByte(text[i+1:], '`')
+			if end == -1 {
+				return nil, fmt.Errorf("apostrophe inverse non fermée")
+			}
+			found = append(found, text[i+1:i+1+end])
+			i += end + 1
+		}
+	}
+	return found, nil
+}
+
+// balanced retourne le contenu entre la parenthèse (ou accolade) ouvrante située
+// à l'index open et sa fermante, ainsi que l'index de cette dernière
+func balanced(line string, open int) (string, int, error) {
+	openCh := line[open]
+	closeCh := byte(')')
+	if openCh == '{' {
+		closeCh = '}'
+	}
+
+	depth := 0
+	for i := open; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '\'':
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end == -1 {
+				return "", 0, fmt.Errorf("apostrophe non fermée")
+			}
+			i += end + 1
+		case openCh:
+			depth++
+		case closeCh:
+			depth--
+			if depth == 0 {
+				return line[open+1 : i], i, nil
+			}
+		}
+	}
+	return "", 0, fmt.Errorf("parenthèse non fermée")
+}
+
+// isDigits indique si la chaîne n'est composée que de chiffres
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// buildCommands regroupe les jetons en commandes simples
+func buildCommands(tokens []token) []Command {
+	var (
+		commands []Command
+		words    []string
+		redirs   []Redirect
+		piped    bool
+	)
+
+	flush := func(nextPiped bool) {
+		if len(words) > 0 || len(redirs) > 0 {
+			if cmd, ok := newCommand(words, redirs); ok {
+				cmd.Piped = piped
+				commands = append(commands, cmd)
+			}
+		}
+		words, redirs = nil, nil
+		piped = nextPiped
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		if tok.kind == wordToken {
+			words = append(words, tok.value)
+			continue
+		}
+
+		switch tok.value {
+		case "|", "|&":
+			flush(true)
+		case ";", ";;", "&&", "||", "&", "(", ")":
+			flush(false)
+		default:
+			// Redirection : le mot suivant en est la cible
+			if i+1 < len(tokens) && tokens[i+1].kind == wordToken {
+				redirs = append(redirs, Redirect{Op: tok.value, Target: tokens[i+1].value})
+				i++
+			}
+		}
+	}
+	flush(false)
+
+	return commands
+}
+
+// wrappers liste les commandes qui exécutent la commande passée en argument,
+// avec le nombre d'options prenant une valeur à ignorer
+var wrappers = map[string]map[string]bool{
+	"sudo":    {"-u": true, "-g": true, "-C": true, "-D": true, "-h": true, "-p": true, "-r": true, "-t": true, "-U": true},
+	"doas":    {"-u": true, "-C": true},
+	"env":     {"-u": true, "-C": true, "-S": true},
+	"nohup":   {},
+	"nice":    {"-n": true},
+	"ionice":  {"-c": true, "-n": true, "-p": true},
+	"time":    {"-f": true, "-o": true},
+	"timeout": {"-s": true, "-k": true},
+	"stdbuf":  {"-i": true, "-o": true, "-e": true},
+	"command": {},
+	"builtin": {},
+	"exec":    {"-a": true},
+	"xargs":   {"-I": true, "-n": true, "-P": true, "-d": true, "-L": true, "-s": true, "-a": true, "-E": true},
+}
+
+// newCommand construit une commande à partir de ses mots, en retirant les
+// affectations de variables et les commandes enveloppes (sudo, env, nohup...)
+func newCommand(words []string, redirs []Redirect) (Command, bool) {
+	cmd := Command{Redirects: redirs}
+
+	// Dernière enveloppe sudo/doas et ses options, conservées lorsqu'elle
+	// n'est suivie d'aucune commande (sudo -i, sudo -s, doas -s)
+	var elevator string
+	var elevatorOpts []string
+
+	for len(words) > 0 {
+		w := words[0]
+
+		// Affectations de variables (VAR=valeur commande) et accolades de groupe
+		if isAssignment(w) || w == "{" || w == "}" || w == "!" {
+			words = words[1:]
+			continue
+		}
+
+		valueOpts, isWrapper := wrappers[w]
+		if !isWrapper {
+			break
+		}
+		elevates := w == "sudo" || w == "doas"
+		if elevates {
+			cmd.Sudo = true
+			elevator, elevatorOpts = w, nil
+		}
+		words = words[1:]
+
+		// Options de l'enveloppe
+		for len(words) > 0 && strings.HasPrefix(words[0], "-") {
+			opt := words[0]
+			words = words[1:]
+			if elevates {
+				elevatorOpts = append(elevatorOpts, opt)
+			}
+			if opt == "--" {
+				break
+			}
+			if valueOpts[opt] && len(words) > 0 {
+				if elevates {
+					elevatorOpts = append(elevatorOpts, words[0])
+				}
+				words = words[1:]
+			}
+		}
+		// timeout prend une durée avant la commande
+		if w == "timeout" && len(words) > 0 {
+			words = words[1:]
+		}
+	}
+
+	// Le } final d'un groupe { ...; } n'est pas un argument
+	for len(words) > 0 && words[len(words)-1] == "}" {
+		words = words[:len(words)-1]
+	}
+
+	if len(words) == 0 && cmd.Sudo {
+		// sudo ou doas sans commande : ouverture d'un shell root (-i, -s)
+		cmd.Name = elevator
+		cmd.Args = elevatorOpts
+		return cmd, true
+	}
+	if len(words) == 0 {
+		// Redirection seule (ex: "> fichier") : rattachée à une commande vide
+		if len(redirs) == 0 {
+			return cmd, false
+		}
+		cmd.Name = ":"
+		return cmd, true
+	}
+
+	cmd.Name = words[0]
+	cmd.Args = words[1:]
+	return cmd, true
+}
+
+// isAssignment indique si le mot est une affectation de variable NOM=valeur
+func isAssignment(w string) bool {
+	eq := strings.IndexByte(w, '=')
+	if eq <= 0 {
+		return false
+	}
+	for i, c := range w[:eq] {
+		if !(c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9')) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,220 @@
+package risk
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// formatCommands rend les commandes comparables, sans distinguer les listes vides des listes nulles
+func formatCommands(cmds []Command) string {
+	var parts []string
+	for _, c := range cmds {
+		parts = append(parts, fmt.Sprintf("%q %q %v sudo=%v pipe=%v", c.Name, c.Args, c.Redirects, c.Sudo, c.Piped))
+	}
+	return strings.Join(parts, "\n")
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want []Command
+		err  string // fragment de l'erreur attendue
+	}{
+		// Guillemets et échappements
+		{
+			name: "guillemets",
+			line: `echo 'a b' "c $d" e\ f`,
+			want: []Command{{Name: "echo", Args: []string{"a b", "c $d", "e f"}}},
+		},
+		{
+			name: "commentaire",
+			line: "ls -l # rm -rf /",
+			want: []Command{{Name: "ls", Args: []string{"-l"}}},
+		},
+
+		// Pipes, séparateurs et sous-shells
+		{
+			name: "pipes",
+			line: "ps aux | grep 'ssh' | wc -l",
+			want: []Command{
+				{Name: "ps", Args: []string{"aux"}},
+				{Name: "grep", Args: []string{"ssh"}, Piped: true},
+				{Name: "wc", Args: []string{"-l"}, Piped: true},
+			},
+		},
+		{
+			name: "sous-shell et séparateurs",
+			line: "(cd /tmp && ls) || echo échec; date &",
+			want: []Command{
+				{Name: "cd", Args: []string{"/tmp"}},
+				{Name: "ls"},
+				{Name: "echo", Args: []string{"échec"}},
+				{Name: "date"},
+			},
+		},
+
+		// Substitutions de commandes
+		{
+			name: "substitution $(...)",
+			line: "echo $(rm -rf /tmp/x) ok",
+			want: []Command{
+				{Name: "echo", Args: []string{"$(rm -rf /tmp/x)", "ok"}},
+				{Name: "rm", Args: []string{"-rf", "/tmp/x"}},
+			},
+		},
+		{
+			name: "apostrophes inverses",
+			line: "echo `whoami`",
+			want: []Command{{Name: "echo", Args: []string{"`whoami`"}}, {Name: "whoami"}},
+		},
+		{
+			name: "substitution entre guillemets",
+			line: `echo "a $(rm x) b"`,
+			want: []Command{{Name: "echo", Args: []string{"a $(rm x) b"}}, {Name: "rm", Args: []string{"x"}}},
+		},
+		{
+			name: "pas de substitution entre apostrophes",
+			line: "echo 'a $(rm x)'",
+			want: []Command{{Name: "echo", Args: []string{"a $(rm x)"}}},
+		},
+		{
+			name: "expression arithmétique",
+			line: "echo $((1 + 2)) ${HOME}",
+			want: []Command{{Name: "echo", Args: []string{"$((1 + 2))", "${HOME}"}}},
+		},
+		{
+			name: "substitution de processus",
+			line: "diff <(ls a) <(ls b)",
+			want: []Command{
+				{Name: "diff", Args: []string{"<(ls a)", "<(ls b)"}},
+				{Name: "ls", Args: []string{"a"}},
+				{Name: "ls", Args: []string{"b"}},
+			},
+		},
+
+		// Here-documents
+		{
+			name: "here-document",
+			line: "cat <<EOF > out.txt\nhello | rm -rf /\nEOF\nls",
+			want: []Command{
+				{Name: "cat", Redirects: []Redirect{{"<<", "EOF"}, {">", "out.txt"}}},
+				{Name: "ls"},
+			},
+		},
+		{
+			name: "substitution dans un here-document",
+			line: "cat <<EOF\nhôte : $(rm -rf /tmp/x) `id`\nEOF",
+			want: []Command{
+				{Name: "cat", Redirects: []Redirect{{"<<", "EOF"}}},
+				{Name: "rm", Args: []string{"-rf", "/tmp/x"}},
+				{Name: "id"},
+			},
+		},
+		{
+			name: "here-document à délimiteur cité",
+			line: "cat <<'EOF'\n$(rm -rf /tmp/x)\nEOF",
+			want: []Command{{Name: "cat", Redirects: []Redirect{{"<<", "EOF"}}}},
+		},
+		{
+			name: "here-document indenté",
+			line: "cat <<-\"FIN\"\n\t`rm x`\n\tFIN",
+			want: []Command{{Name: "cat", Redirects: []Redirect{{"<<", "FIN"}}}},
+		},
+
+		// Redirections
+		{
+			name: "redirections",
+			line: "ls > /tmp/list 2>&1 < in.txt",
+			want: []Command{{Name: "ls", Redirects: []Redirect{{">", "/tmp/list"}, {">&", "1"}, {"<", "in.txt"}}}},
+		},
+		{
+			name: "ajout et here-string",
+			line: "tr a b <<< abc >> out.txt",
+			want: []Command{{Name: "tr", Args: []string{"a", "b"}, Redirects: []Redirect{{"<<<", "abc"}, {">>", "out.txt"}}}},
+		},
+
+		// Préfixes, sudo et doas
+		{
+			name: "variables et enveloppes",
+			line: "FOO=1 env -u X nohup sudo -E apt-get install -y vim",
+			want: []Command{{Name: "apt-get", Args: []string{"install", "-y", "vim"}, Sudo: true}},
+		},
+		{
+			name: "sudo",
+			line: "sudo ls /root",
+			want: []Command{{Name: "ls", Args: []string{"/root"}, Sudo: true}},
+		},
+		{
+			name: "sudo -u",
+			line: "sudo -u postgres psql",
+			want: []Command{{Name: "psql", Sudo: true}},
+		},
+		{
+			name: "sudo option collée",
+			line: "sudo -ubob --group=adm id",
+			want: []Command{{Name: "id", Sudo: true}},
+		},
+		{
+			name: "sudo --",
+			line: "sudo -- rm -f x",
+			want: []Command{{Name: "rm", Args: []string{"-f", "x"}, Sudo: true}},
+		},
+		{
+			name: "doas -u",
+			line: "doas -u bob ls",
+			want: []Command{{Name: "ls", Sudo: true}},
+		},
+		{
+			name: "sudo dans un pipe",
+			line: "echo x | sudo tee /etc/motd",
+			want: []Command{{Name: "echo", Args: []string{"x"}}, {Name: "tee", Args: []string{"/etc/motd"}, Sudo: true, Piped: true}},
+		},
+		{
+			name: "sudo su",
+			line: "sudo su -",
+			want: []Command{{Name: "su", Args: []string{"-"}, Sudo: true}},
+		},
+		{
+			name: "sudo -i",
+			line: "sudo -i",
+			want: []Command{{Name: "sudo", Args: []string{"-i"}, Sudo: true}},
+		},
+		{
+			name: "doas -s",
+			line: "doas -s",
+			want: []Command{{Name: "doas", Args: []string{"-s"}, Sudo: true}},
+		},
+		{
+			name: "sudo seul",
+			line: "sudo",
+			want: []Command{{Name: "sudo", Sudo: true}},
+		},
+
+		// Erreurs
+		{name: "apostrophe non fermée", line: "echo 'a", err: "apostrophe non fermée"},
+		{name: "guillemet non fermé", line: `echo "a`, err: "guillemet non fermé"},
+		{name: "parenthèse non fermée", line: "echo $(ls", err: "parenthèse non fermée"},
+		{name: "apostrophe inverse non fermée", line: "echo `ls", err: "apostrophe inverse non fermée"},
+		{name: "erreur dans une substitution", line: "echo $(echo 'a)", err: "non fermé"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.line)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("erreur = %v, %q attendu", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("erreur inattendue : %v", err)
+			}
+			if g, w := formatCommands(got), formatCommands(tt.want); g != w {
+				t.Errorf("commandes :\n%s\nattendu :\n%s", g, w)
+			}
+		})
+	}
+}
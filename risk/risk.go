@@ -0,0 +1,956 @@
+package risk
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Level représente le niveau de risque d'une commande
+type Level int
+
+const (
+	// ReadOnly : la commande ne fait que lire l'état du système
+	ReadOnly Level = iota
+	// Modifying : la commande modifie des fichiers ou l'état de l'utilisateur
+	Modifying
+	// Privileged : la commande modifie l'état du système et nécessite les droits root
+	Privileged
+	// Destructive : la commande supprime ou écrase des données de façon irréversible
+	Destructive
+)
+
+// String retourne le libellé du niveau de risque
+func (l Level) String() string {
+	switch l {
+	case ReadOnly:
+		return "lecture seule"
+	case Modifying:
+		return "modification"
+	case Privileged:
+		return "privilégiée"
+	case Destructive:
+		return "destructive"
+	}
+	return "inconnu"
+}
+
+// Assessment représente l'évaluation du risque d'une ligne de commande
+type Assessment struct {
+	Level Level
+
+	// La ligne contient déjà sudo/doas
+	UsesSudo bool
+
+	// La commande nécessite les droits root pour réussir
+	RequiresRoot bool
+
+	// Commandes simples analysées
+	Commands []Command
+
+	// Chemins lus et écrits par la commande
+	Reads  []string
+	Writes []string
+
+	// Raisons ayant conduit au niveau retenu
+	Reasons []string
+}
+
+// raise élève le niveau de risque si nécessaire et note la raison
+func (a *Assessment) raise(level Level, reason string) {
+	if level > a.Level {
+		a.Level = level
+	}
+	if reason != "" {
+		for _, r := range a.Reasons {
+			if r == reason {
+				return
+			}
+		}
+		a.Reasons = append(a.Reasons, reason)
+	}
+}
+
+// Classify analyse une ligne de commande et évalue son niveau de risque.
+// Une ligne impossible à analyser est considérée comme destructive.
+func Classify(line string) *Assessment {
+	a := &Assessment{}
+
+	commands, err := Parse(line)
+	if err != nil {
+		a.raise(Destructive, "analyse impossible: "+err.Error())
+		return a
+	}
+	a.Commands = commands
+
+	for _, cmd := range commands {
+		a.classifyCommand(cmd)
+	}
+
+	a.Reads = dedupe(a.Reads)
+	a.Writes = dedupe(a.Writes)
+	return a
+}
+
+// readOnlyCommands liste les commandes sans effet sur le système
+var readOnlyCommands = map[string]bool{
+	"ls": true, "ll": true, "cat": true, "less": true, "more": true, "head": true, "tail": true,
+	"grep": true, "egrep": true, "fgrep": true, "rg": true, "ag": true, "pwd": true, "echo": true,
+	"printf": true, "whoami": true, "id": true, "groups": true, "date": true, "cal": true,
+	"uname": true, "df": true, "du": true, "free": true, "uptime": true, "ps": true, "pgrep": true,
+	"top": true, "htop": true, "wc": true, "which": true, "whereis": true, "type": true,
+	"stat": true, "file": true, "env": true, "printenv": true, "hostname": true,
+	"lsblk": true, "lscpu": true, "lsusb": true, "lspci": true, "lsmod": true, "lsof": true,
+	"ss": true, "netstat": true, "ping": true, "dig": true, "nslookup": true, "host": true,
+	"traceroute": true, "dmesg": true, "sort": true, "uniq": true, "cut": true,
+	"tr": true, "diff": true, "cmp": true, "comm": true, "md5sum": true,
+	"sha1sum": true, "sha256sum": true, "basename": true, "dirname": true, "realpath": true,
+	"readlink": true, "tree": true, "man": true, "history": true, "test": true, "[": true,
+	"true": true, "false": true, "sleep": true, "seq": true, "jq": true, "xxd": true, "od": true,
+	"hexdump": true, "strings": true, "column": true, "nproc": true, "getent": true, "last": true,
+	"w": true, "who": true, "locale": true, "ldd": true, "nl": true,
+	"fold": true, "fmt": true, "tac": true, "rev": true, "zcat": true, "zgrep": true, "bat": true,
+	":": true, "cd": true, "sensors": true, "vmstat": true, "iostat": true, "nvidia-smi": true,
+}
+
+// destructiveCommands liste les commandes qui détruisent des données
+var destructiveCommands = map[string]string{
+	"rm":       "suppression de fichiers",
+	"shred":    "effacement irréversible de fichiers",
+	"dd":       "écriture brute sur fichier ou périphérique",
+	"mkfs":     "formatage d'un système de fichiers",
+	"fdisk":    "modification de la table de partitions",
+	"sfdisk":   "modification de la table de partitions",
+	"parted":   "modification de la table de partitions",
+	"wipefs":   "effacement des signatures de système de fichiers",
+	"truncate": "troncature de fichiers",
+	"reboot":   "redémarrage du système",
+	"shutdown": "arrêt du système",
+	"poweroff": "arrêt du système",
+	"halt":     "arrêt du système",
+}
+
+// privilegedCommands liste les commandes qui modifient l'état du système
+var privilegedCommands = map[string]bool{
+	"mount": true, "umount": true, "useradd": true, "userdel": true, "usermod": true,
+	"groupadd": true, "groupdel": true, "passwd": true, "chpasswd": true, "visudo": true,
+	"modprobe": true, "insmod": true, "rmmod": true, "iptables": true, "ip6tables": true,
+	"nft": true, "ufw": true, "firewall-cmd": true, "update-grub": true, "grub-install": true,
+	"swapon": true, "swapoff": true, "chroot": true, "service": true, "update-alternatives": true,
+	"setenforce": true, "losetup": true, "cryptsetup": true,
+}
+
+// packageManagers associe chaque gestionnaire de paquets à ses sous-commandes en lecture seule
+var packageManagers = map[string]map[string]bool{
+	"apt":     {"list": true, "search": true, "show": true, "policy": true, "depends": true, "rdepends": true},
+	"apt-get": {"check": true, "download": true, "source": true, "changelog": true},
+	"apt-cache": {"search": true, "show": true, "policy": true, "depends": true, "rdepends": true,
+		"showpkg": true, "madison": true, "pkgnames": true, "stats": true},
+	"dpkg":    {"-l": true, "--list": true, "-L": true, "--listfiles": true, "-s": true, "--status": true, "-S": true, "--search": true},
+	"yum":     {"list": true, "search": true, "info": true, "provides": true, "repolist": true},
+	"dnf":     {"list": true, "search": true, "info": true, "provides": true, "repolist": true, "repoquery": true},
+	"pacman":  {"-Q": true, "-Qi": true, "-Ql": true, "-Qs": true, "-Ss": true, "-Si": true},
+	"zypper":  {"search": true, "se": true, "info": true, "if": true, "repos": true, "lr": true},
+	"apk":     {"info": true, "search": true, "list": true},
+	"rpm":     {"-q": true, "-qa": true, "-qi": true, "-ql": true, "-qf": true},
+	"snap":    {"list": true, "find": true, "info": true},
+	"flatpak": {"list": true, "search": true, "info": true},
+}
+
+// packageRemoval liste les sous-commandes qui désinstallent des paquets
+var packageRemoval = map[string]bool{
+	"remove": true, "purge": true, "autoremove": true, "erase": true, "del": true, "-R": true,
+	"-Rs": true, "-Rns": true, "-r": true, "-P": true, "--purge": true, "--remove": true, "rm": true,
+}
+
+// readOnlySubcommands liste les sous-commandes sans effet des outils courants
+var readOnlySubcommands = map[string]map[string]bool{
+	"systemctl": {"status": true, "show": true, "cat": true, "list-units": true, "list-unit-files": true,
+		"list-timers": true, "list-sockets": true, "list-dependencies": true, "is-active": true,
+		"is-enabled": true, "is-failed": true, "is-system-running": true, "get-default": true, "help": true},
+	"git": {"status": true, "log": true, "diff": true, "show": true, "branch": true, "remote": true,
+		"blame": true, "grep": true, "ls-files": true, "rev-parse": true, "describe": true,
+		"shortlog": true, "tag": true, "config": true, "reflog": true},
+	"docker": {"ps": true, "images": true, "logs": true, "inspect": true, "info": true, "version": true,
+		"stats": true, "top": true, "port": true, "history": true, "search": true},
+	"podman": {"ps": true, "images": true, "logs": true, "inspect": true, "info": true, "version": true},
+	"kubectl": {"get": true, "describe": true, "logs": true, "top": true, "explain": true,
+		"version": true, "cluster-info": true, "api-resources": true},
+	"ip":          {"a": true, "addr": true, "address": true, "r": true, "route": true, "l": true, "link": true, "n": true, "neigh": true},
+	"crontab":     {"-l": true},
+	"npm":         {"ls": true, "list": true, "view": true, "outdated": true, "search": true},
+	"pip":         {"list": true, "show": true, "freeze": true, "search": true},
+	"pip3":        {"list": true, "show": true, "freeze": true, "search": true},
+	"go":          {"version": true, "env": true, "list": true, "doc": true, "vet": true},
+	"journalctl":  {},
+	"hostnamectl": {"status": true, "show": true, "help": true},
+	"timedatectl": {"status": true, "show": true, "list-timezones": true, "timesync-status": true,
+		"show-timesync": true, "help": true},
+}
+
+// interpreters liste les commandes dont le premier argument est un programme,
+// capable d'exécuter des commandes (system() en awk)
+var interpreters = map[string]bool{
+	"awk": true, "gawk": true, "mawk": true, "nawk": true,
+}
+
+// shells liste les interpréteurs capables d'exécuter du code arbitraire
+var shells = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true, "fish": true,
+	"python": true, "python3": true, "perl": true, "ruby": true, "node": true, "php": true,
+}
+
+// writeCommands liste les commandes dont les arguments (hors options) sont des chemins modifiés
+var writeCommands = map[string]bool{
+	"mkdir": true, "touch": true, "cp": true, "mv": true, "ln": true, "chmod": true, "chown": true,
+	"chgrp": true, "tee": true, "rm": true, "rmdir": true, "shred": true, "truncate": true,
+	"install": true, "unlink": true, "mkfifo": true, "setfacl": true,
+}
+
+// blockDevice reconnaît les périphériques de stockage
+var blockDevice = regexp.MustCompile(`^/dev/(sd[a-z]|nvme\d|hd[a-z]|vd[a-z]|xvd[a-z]|mmcblk\d|md\d|dm-\d|mapper/)`)
+
+// systemDirs liste les répertoires dont la modification nécessite root
+var systemDirs = []string{
+	"/etc", "/usr", "/bin", "/sbin", "/lib", "/lib64", "/boot", "/opt", "/root",
+	"/sys", "/proc", "/srv", "/var/lib", "/var/log", "/var/cache", "/var/spool",
+}
+
+// criticalTargets liste les cibles dont la suppression récursive est catastrophique
+var criticalTargets = map[string]bool{
+	"/": true, "/*": true, "~": true, "~/": true, "~/*": true, "$HOME": true, "${HOME}": true,
+	"$HOME/": true, "$HOME/*": true, "*": true, ".": true, "..": true, "./*": true,
+}
+
+// classifyCommand évalue une commande simple et met à jour l'évaluation
+func (a *Assessment) classifyCommand(cmd Command) {
+	name := path.Base(cmd.Name)
+	args := cmd.Args
+	positional := positionalArgs(args)
+
+	if cmd.Sudo {
+		a.UsesSudo = true
+		a.RequiresRoot = true
+		a.raise(Privileged, "exécution avec sudo")
+	}
+
+	// Redirections : les écritures sur le système ou un disque sont à risque
+	for _, r := range cmd.Redirects {
+		switch r.Op {
+		case "<", "<<<":
+			a.Reads = append(a.Reads, r.Target)
+		case ">&":
+			if isDigits(r.Target) || r.Target == "-" {
+				continue
+			}
+			a.writePath(r.Target, "redirection vers "+r.Target)
+		case ">", "&>", ">|":
+			a.writePath(r.Target, "redirection vers "+r.Target)
+			if nonEmptyFile(r.Target) {
+				a.raise(Destructive, "écrasement du fichier existant "+r.Target)
+			}
+		case ">>", "<>":
+			a.writePath(r.Target, "redirection vers "+r.Target)
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(name, "mkfs"):
+		a.raise(Destructive, "formatage d'un système de fichiers")
+		a.RequiresRoot = true
+		return
+
+	case destructiveCommands[name] != "":
+		a.raise(Destructive, destructiveCommands[name])
+		switch name {
+		case "reboot", "shutdown", "poweroff", "halt", "fdisk", "sfdisk", "parted", "wipefs":
+			a.RequiresRoot = true
+		case "dd":
+			for _, arg := range args {
+				if strings.HasPrefix(arg, "of=") {
+					a.writePath(strings.TrimPrefix(arg, "of="), "")
+				} else if strings.HasPrefix(arg, "if=") {
+					a.Reads = append(a.Reads, strings.TrimPrefix(arg, "if="))
+				}
+			}
+			return
+		case "rm":
+			if hasFlag(args, 'r', "--recursive") {
+				for _, target := range positional {
+					if criticalTargets[target] || criticalTargets[strings.TrimSuffix(target, "/")] {
+						a.raise(Destructive, "suppression récursive de "+target)
+					}
+				}
+			}
+		}
+		for _, p := range positional {
+			a.writePath(p, "")
+		}
+		return
+
+	case privilegedCommands[name]:
+		a.raise(Privileged, name+" modifie la configuration du système")
+		a.RequiresRoot = true
+		return
+
+	case name == "sudo" || name == "doas" || name == "su":
+		// Shell root interactif : sudo -i, sudo -s, doas -s, su
+		a.raise(Privileged, "ouverture d'un shell root")
+		a.RequiresRoot = true
+		return
+
+	case packageManagers[name] != nil:
+		a.classifyPackageManager(name, args)
+		return
+
+	case shells[name]:
+		a.classifyShell(name, cmd, args, positional)
+		return
+
+	case name == "eval":
+		a.classifyNested(strings.Join(args, " "))
+		return
+
+	case name == "find":
+		a.classifyFind(args)
+		return
+
+	case name == "sed":
+		if hasFlag(args, 'i', "--in-place") {
+			a.raise(Modifying, "modification de fichiers sur place")
+			for _, p := range positional[min(1, len(positional)):] {
+				a.writePath(p, "")
+			}
+		} else {
+			a.readPaths(positional)
+		}
+		return
+
+	case name == "curl" || name == "wget":
+		a.classifyDownload(name, args)
+		return
+
+	case name == "kill" || name == "killall" || name == "pkill":
+		a.raise(Modifying, "arrêt de processus")
+		for _, arg := range positional {
+			if arg == "1" || arg == "-1" || arg == "init" || arg == "systemd" {
+				a.raise(Destructive, "arrêt de processus système")
+				a.RequiresRoot = true
+			}
+		}
+		return
+
+	case name == "chmod" || name == "chown" || name == "chgrp":
+		a.raise(Modifying, "modification des permissions")
+		if hasFlag(args, 'R', "--recursive") {
+			for _, target := range positional[min(1, len(positional)):] {
+				if criticalTargets[target] || isSystemPath(target) {
+					a.raise(Destructive, "modification récursive des permissions de "+target)
+				}
+			}
+		}
+		if name != "chmod" {
+			// Changer de propriétaire nécessite root
+			a.RequiresRoot = true
+			a.raise(Privileged, "changement de propriétaire")
+		}
+		for _, p := range positional[min(1, len(positional)):] {
+			a.writePath(p, "")
+		}
+		return
+
+	case name == "crontab":
+		if hasFlag(args, 'r', "") {
+			a.raise(Destructive, "suppression de la crontab")
+			return
+		}
+
+	case interpreters[name]:
+		a.raise(Modifying, "exécution d'un programme "+name)
+		if len(positional) > 1 {
+			a.readPaths(positional[1:])
+		}
+		return
+
+	case name == "sort":
+		// sort -o écrit le résultat dans un fichier
+		for i, arg := range args {
+			switch {
+			case (arg == "-o" || arg == "--output") && i+1 < len(args):
+				a.writePath(args[i+1], "écriture du résultat dans "+args[i+1])
+			case strings.HasPrefix(arg, "--output="):
+				target := strings.TrimPrefix(arg, "--output=")
+				a.writePath(target, "écriture du résultat dans "+target)
+			case strings.HasPrefix(arg, "-o") && len(arg) > 2 && !strings.HasPrefix(arg, "--"):
+				a.writePath(arg[2:], "écriture du résultat dans "+arg[2:])
+			}
+		}
+		a.readPaths(positional)
+		return
+
+	case name == "date":
+		if hasFlag(args, 's', "--set") {
+			a.raise(Privileged, "modification de l'horloge système")
+			a.RequiresRoot = true
+			return
+		}
+
+	case name == "hostname":
+		// Un nom en argument (ou -F fichier) change le nom de la machine
+		if len(positional) > 0 || hasFlag(args, 'F', "--file") {
+			a.raise(Privileged, "modification du nom de la machine")
+			a.RequiresRoot = true
+			return
+		}
+
+	case name == "dmesg":
+		if hasFlag(args, 'C', "--clear") || hasFlag(args, 'c', "--read-clear") ||
+			hasFlag(args, 'D', "--console-off") || hasFlag(args, 'E', "--console-on") || hasFlag(args, 'n', "--console-level") {
+			a.raise(Privileged, "effacement ou réglage du tampon du noyau")
+			a.RequiresRoot = true
+			return
+		}
+	}
+
+	if sub, ok := readOnlySubcommands[name]; ok {
+		a.classifySubcommand(name, sub, args, positional)
+		return
+	}
+
+	if readOnlyCommands[name] {
+		a.readPaths(positional)
+		return
+	}
+
+	if writeCommands[name] {
+		a.raise(Modifying, name+" modifie des fichiers")
+		for _, p := range positional {
+			a.writePath(p, "")
+		}
+		if name == "mv" && len(positional) > 0 && positional[len(positional)-1] == "/dev/null" {
+			a.raise(Destructive, "déplacement vers /dev/null")
+		}
+		return
+	}
+
+	// Commande inconnue : considérée comme modifiante par prudence
+	a.raise(Modifying, "commande non répertoriée: "+name)
+	a.readPaths(positional)
+}
+
+// classifyPackageManager évalue une commande de gestion de paquets
+func (a *Assessment) classifyPackageManager(name string, args []string) {
+	readOnly := packageManagers[name]
+	for _, arg := range args {
+		if readOnly[arg] {
+			return
+		}
+		if packageRemoval[arg] {
+			a.raise(Destructive, "désinstallation de paquets")
+			a.RequiresRoot = name != "flatpak"
+			return
+		}
+		if !strings.HasPrefix(arg, "-") {
+			break
+		}
+	}
+	a.raise(Privileged, "installation ou mise à jour de paquets")
+	a.RequiresRoot = name != "flatpak"
+}
+
+// classifySubcommand évalue les outils dont le risque dépend de la sous-commande
+func (a *Assessment) classifySubcommand(name string, readOnly map[string]bool, args, positional []string) {
+	sub := ""
+	if len(positional) > 0 {
+		sub = positional[0]
+	}
+	if name == "crontab" && len(args) > 0 {
+		sub = args[0]
+	}
+	if name == "journalctl" {
+		for _, arg := range args {
+			if strings.HasPrefix(arg, "--vacuum") || arg == "--rotate" || arg == "--flush" ||
+				arg == "--sync" || arg == "--relinquish-var" || arg == "--setup-keys" {
+				a.raise(Privileged, "maintenance des journaux système")
+				a.RequiresRoot = true
+				return
+			}
+		}
+		return
+	}
+	if name == "ip" && len(positional) > 1 {
+		// ip addr show / ip route list sont en lecture seule, ip link set ... non
+		switch positional[1] {
+		case "show", "list", "ls", "get":
+		default:
+			a.raise(Privileged, "modification de la configuration réseau")
+			a.RequiresRoot = true
+			return
+		}
+	}
+	if name == "git" && a.classifyGitArgs(sub, args, positional) {
+		return
+	}
+	if readOnly[sub] || (sub == "" && name != "crontab") {
+		return
+	}
+
+	switch name {
+	case "systemctl":
+		if hasFlag(args, 0, "--user") {
+			a.raise(Modifying, "gestion d'un service utilisateur")
+			return
+		}
+		a.raise(Privileged, "gestion des services système ("+sub+")")
+		a.RequiresRoot = true
+		if sub == "poweroff" || sub == "reboot" || sub == "halt" {
+			a.raise(Destructive, "arrêt du système")
+		}
+	case "git":
+		switch {
+		case sub == "clean" && hasFlag(args, 'f', "--force"):
+			a.raise(Destructive, "suppression des fichiers non suivis")
+		case sub == "reset" && hasFlag(args, 0, "--hard"):
+			a.raise(Destructive, "abandon des modifications locales")
+		case sub == "push" && (hasFlag(args, 'f', "--force") || hasFlag(args, 0, "--force-with-lease")):
+			a.raise(Destructive, "réécriture de l'historique distant")
+		case sub == "checkout" && containsArg(args, "--"), sub == "restore":
+			a.raise(Destructive, "abandon des modifications locales")
+		default:
+			a.raise(Modifying, "git "+sub)
+		}
+	case "docker", "podman":
+		switch sub {
+		case "rm", "rmi", "prune", "kill":
+			a.raise(Destructive, name+" "+sub)
+		case "system", "volume", "image", "container", "network":
+			if containsArg(args, "prune") || containsArg(args, "rm") {
+				a.raise(Destructive, name+" "+sub+" (suppression)")
+			} else {
+				a.raise(Modifying, name+" "+sub)
+			}
+		default:
+			a.raise(Modifying, name+" "+sub)
+		}
+	case "hostnamectl", "timedatectl":
+		a.raise(Privileged, name+" "+sub+" modifie la configuration du système")
+		a.RequiresRoot = true
+	case "kubectl":
+		if sub == "delete" || sub == "drain" {
+			a.raise(Destructive, "kubectl "+sub)
+		} else {
+			a.raise(Modifying, "kubectl "+sub)
+		}
+	default:
+		a.raise(Modifying, name+" "+sub)
+	}
+}
+
+// classifyGitArgs évalue les sous-commandes git dont l'effet dépend des
+// arguments ; retourne true si la commande a été évaluée
+func (a *Assessment) classifyGitArgs(sub string, args, positional []string) bool {
+	switch sub {
+	case "branch":
+		switch {
+		case hasFlag(args, 'D', "") || hasFlag(args, 'd', "--delete"):
+			a.raise(Destructive, "suppression de branche")
+		case hasFlag(args, 'm', "--move") || hasFlag(args, 'M', "") || hasFlag(args, 'c', "--copy") ||
+			hasFlag(args, 0, "--set-upstream-to") || hasFlag(args, 'u', "--unset-upstream"):
+			a.raise(Modifying, "git branch (modification)")
+		case len(positional) > 1 && !hasFlag(args, 'l', "--list") && !hasFlag(args, 0, "--contains"):
+			a.raise(Modifying, "création de branche")
+		default:
+			return false
+		}
+		return true
+	case "tag":
+		switch {
+		case hasFlag(args, 'd', "--delete"):
+			a.raise(Destructive, "suppression d'étiquette")
+		case len(positional) > 1 && !hasFlag(args, 'l', "--list") && !hasFlag(args, 0, "--contains"):
+			a.raise(Modifying, "création d'étiquette")
+		default:
+			return false
+		}
+		return true
+	case "config":
+		// Seules la lecture et la liste des options laissent le dépôt inchangé
+		for _, arg := range args {
+			if strings.HasPrefix(arg, "--get") || arg == "--list" || arg == "-l" {
+				return false
+			}
+		}
+		a.raise(Modifying, "modification de la configuration git")
+		return true
+	case "remote":
+		if len(positional) > 1 {
+			switch positional[1] {
+			case "show", "get-url", "-v":
+				return false
+			}
+			a.raise(Modifying, "modification des dépôts distants")
+			return true
+		}
+	}
+	return false
+}
+
+// classifyShell évalue l'appel d'un interpréteur
+func (a *Assessment) classifyShell(name string, cmd Command, args, positional []string) {
+	// sh -c "..." : analyser le script fourni
+	for i, arg := range args {
+		if (arg == "-c" || arg == "-e") && i+1 < len(args) {
+			if name == "sh" || name == "bash" || name == "zsh" || name == "dash" || name == "ksh" {
+				a.classifyNested(args[i+1])
+				return
+			}
+			a.raise(Modifying, "exécution de code "+name)
+			return
+		}
+	}
+	if cmd.Piped && len(positional) == 0 {
+		a.raise(Destructive, "exécution par "+name+" d'un script reçu par un pipe")
+		return
+	}
+	a.raise(Modifying, "exécution d'un script "+name)
+	a.readPaths(positional)
+}
+
+// classifyNested évalue une ligne de commande imbriquée (sh -c, eval, find -exec)
+func (a *Assessment) classifyNested(line string) {
+	inner := Classify(line)
+	a.Commands = append(a.Commands, inner.Commands...)
+	a.Reads = append(a.Reads, inner.Reads...)
+	a.Writes = append(a.Writes, inner.Writes...)
+	a.UsesSudo = a.UsesSudo || inner.UsesSudo
+	a.RequiresRoot = a.RequiresRoot || inner.RequiresRoot
+	for _, reason := range inner.Reasons {
+		a.raise(inner.Level, reason)
+	}
+	a.raise(inner.Level, "")
+}
+
+// classifyFind évalue une commande find (actions -delete et -exec)
+func (a *Assessment) classifyFind(args []string) {
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-delete":
+			a.raise(Destructive, "suppression de fichiers par find")
+		case "-exec", "-execdir", "-ok", "-okdir":
+			var inner []string
+			for i++; i < len(args) && args[i] != ";" && args[i] != "+"; i++ {
+				inner = append(inner, args[i])
+			}
+			a.classifyNested(strings.Join(quoteAll(inner), " "))
+		case "-fprint", "-fprintf", "-fls":
+			if i+1 < len(args) {
+				a.writePath(args[i+1], "")
+			}
+		}
+	}
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		a.Reads = append(a.Reads, args[0])
+	}
+}
+
+// classifyDownload évalue curl/wget : lecture seule sauf écriture de fichier
+func (a *Assessment) classifyDownload(name string, args []string) {
+	for i, arg := range args {
+		switch {
+		case name == "curl" && (arg == "-o" || arg == "--output") && i+1 < len(args):
+			a.writePath(args[i+1], "téléchargement vers "+args[i+1])
+		case name == "curl" && (arg == "-O" || arg == "--remote-name"):
+			a.raise(Modifying, "téléchargement d'un fichier")
+		case name == "curl" && (arg == "-X" || arg == "--request") && i+1 < len(args) && args[i+1] != "GET":
+			a.raise(Modifying, "requête HTTP "+args[i+1])
+		case name == "curl" && (arg == "-d" || arg == "--data" || arg == "-F" || arg == "--form"):
+			a.raise(Modifying, "envoi de données HTTP")
+		case name == "wget" && (arg == "-O" || arg == "--output-document") && i+1 < len(args):
+			if args[i+1] != "-" {
+				a.writePath(args[i+1], "téléchargement vers "+args[i+1])
+			}
+			return
+		}
+	}
+	if name == "wget" && !containsArg(args, "--spider") && !containsArg(args, "-O") {
+		a.raise(Modifying, "téléchargement d'un fichier")
+	}
+}
+
+// writePath enregistre un chemin écrit et élève le risque selon sa nature
+func (a *Assessment) writePath(p, reason string) {
+	if p == "" || p == "{}" || p == "/dev/null" || p == "/dev/stdout" || p == "/dev/stderr" || p == "/dev/tty" || strings.HasPrefix(p, "/dev/fd/") {
+		return
+	}
+	a.Writes = append(a.Writes, p)
+
+	switch {
+	case blockDevice.MatchString(p):
+		a.raise(Destructive, "écriture sur le périphérique "+p)
+		a.RequiresRoot = true
+	case isSystemPath(p):
+		a.raise(Privileged, "écriture dans "+p)
+		a.RequiresRoot = true
+	default:
+		a.raise(Modifying, reason)
+	}
+}
+
+// readPaths enregistre les arguments ressemblant à des chemins comme lus
+func (a *Assessment) readPaths(args []string) {
+	for _, arg := range args {
+		if looksLikePath(arg) {
+			a.Reads = append(a.Reads, arg)
+		}
+	}
+}
+
+// nonEmptyFile indique si une cible de redirection est un fichier existant
+// non vide, qu'une redirection > tronquerait
+func nonEmptyFile(p string) bool {
+	if strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			p = filepath.Join(home, p[2:])
+		}
+	}
+	info, err := os.Stat(p)
+	return err == nil && info.Mode().IsRegular() && info.Size() > 0
+}
+
+// isSystemPath indique si un chemin se trouve dans un répertoire système
+func isSystemPath(p string) bool {
+	if !strings.HasPrefix(p, "/") {
+		return false
+	}
+	clean := path.Clean(p)
+	if clean == "/" {
+		return true
+	}
+	for _, dir := range systemDirs {
+		if clean == dir || strings.HasPrefix(clean, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// looksLikePath indique si un argument désigne probablement un fichier
+func looksLikePath(arg string) bool {
+	if strings.HasPrefix(arg, "-") || strings.Contains(arg, "://") || strings.ContainsAny(arg, "`") || strings.Contains(arg, "$(") {
+		return false
+	}
+	return strings.Contains(arg, "/") || strings.HasPrefix(arg, "~") || strings.HasPrefix(arg, ".") ||
+		path.Ext(arg) != ""
+}
+
+// positionalArgs retourne les arguments qui ne sont pas des options
+func positionalArgs(args []string) []string {
+	var positional []string
+	afterDashes := false
+	for _, arg := range args {
+		if !afterDashes && arg == "--" {
+			afterDashes = true
+			continue
+		}
+		if !afterDashes && strings.HasPrefix(arg, "-") && arg != "-" {
+			continue
+		}
+		positional = append(positional, arg)
+	}
+	return positional
+}
+
+// hasFlag indique si une option courte (regroupable, ex: -rf) ou longue est présente
+func hasFlag(args []string, short rune, long string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			return false
+		}
+		if long != "" && (arg == long || strings.HasPrefix(arg, long+"=")) {
+			return true
+		}
+		if short != 0 && len(arg) > 1 && arg[0] == '-' && arg[1] != '-' && strings.ContainsRune(arg[1:], short) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsArg indique si un argument exact est présent
+func containsArg(args []string, want string) bool {
+	for _, arg := range args {
+		if arg == want {
+			return true
+		}
+	}
+	return false
+}
+
+// quoteAll protège chaque mot par des apostrophes pour une nouvelle analyse
+func quoteAll(words []string) []string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		quoted[i] = "'" + strings.ReplaceAll(w, "'", `'\''`) + "'"
+	}
+	return quoted
+}
+
+// dedupe supprime les doublons en conservant l'ordre
+func dedupe(items []string) []string {
+	seen := make(map[string]bool, len(items))
+	var out []string
+	for _, item := range items {
+		if !seen[item] {
+			seen[item] = true
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// min retourne le plus petit de deux entiers
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Elevation décrit l'identité demandée à sudo/doas par -u et -g ; vide, la
+// commande est exécutée en tant que root
+type Elevation struct {
+	User  string
+	Group string
+}
+
+// sudoFlags liste les options de sudo/doas sans effet sur l'identité ni sur la
+// commande exécutée, qui peuvent être retirées sans risque
+var sudoFlags = map[string]bool{
+	"-E": true, "-H": true, "-n": true, "-S": true, "-k": true, "-A": true, "-B": true,
+	"--preserve-env": true, "--set-home": true, "--non-interactive": true, "--stdin": true,
+	"--reset-timestamp": true, "--askpass": true, "--bell": true,
+}
+
+// StripSudo retire les préfixes sudo/doas des commandes d'une ligne,
+// l'élévation étant alors prise en charge par l'exécuteur. Seuls les mots en
+// position de commande sont retirés, jamais le texte entre guillemets ; la
+// ligne est sinon conservée telle quelle. L'utilisateur et le groupe demandés
+// par -u et -g sont retournés ; une option qui changerait le sens de la
+// commande (-i, -s, -C...), des identités différentes selon les commandes et
+// le mélange de sudo -u avec des commandes sans sudo sont refusés.
+func StripSudo(line string) (string, Elevation, error) {
+	tokens, _, err := lex(line)
+	if err != nil {
+		return "", Elevation{}, err
+	}
+
+	var (
+		sb        strings.Builder
+		elevation Elevation
+		found     bool
+		plain     bool
+		elevated  bool
+		last      int
+	)
+	atStart := true
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		if tok.kind == opToken {
+			switch tok.value {
+			case "|", "|&", ";", ";;", "&&", "||", "&", "(", ")":
+				atStart = true
+				elevated = false
+			default:
+				// Redirection : le mot suivant en est la cible
+				i++
+			}
+			continue
+		}
+		if !atStart || isAssignment(tok.value) || tok.value == "{" || tok.value == "!" {
+			continue
+		}
+		atStart = false
+		if tok.value != "sudo" && tok.value != "doas" {
+			// La commande qui suit sudo/doas est élevée ; les autres ne le sont pas
+			if !elevated {
+				plain = true
+			}
+			elevated = false
+			continue
+		}
+
+		current, next, err := sudoOptions(tok.value, tokens[i+1:])
+		if err != nil {
+			return "", Elevation{}, err
+		}
+		if found && current != elevation {
+			return "", Elevation{}, fmt.Errorf("les commandes de la ligne demandent des identités différentes à %s", tok.value)
+		}
+		elevation, found = current, true
+
+		next += i + 1
+		if next >= len(tokens) || tokens[next].kind != wordToken {
+			return "", Elevation{}, fmt.Errorf("commande manquante après %s", tok.value)
+		}
+		sb.WriteString(line[last:tok.start])
+		last = tokens[next].start
+		// La commande élevée peut elle-même commencer par sudo ou une affectation
+		i = next - 1
+		atStart = true
+		elevated = true
+	}
+	// Toute la ligne est exécutée sous l'identité demandée
+	if plain && elevation != (Elevation{}) {
+		return "", Elevation{}, fmt.Errorf("sudo -u/-g ne peut pas être combiné à des commandes exécutées sans sudo")
+	}
+	sb.WriteString(line[last:])
+	return sb.String(), elevation, nil
+}
+
+// sudoOptions lit les options qui suivent sudo/doas et retourne l'identité
+// demandée ainsi que l'indice du premier jeton qui n'est pas une option
+func sudoOptions(name string, tokens []token) (Elevation, int, error) {
+	var e Elevation
+	i := 0
+	for i < len(tokens) && tokens[i].kind == wordToken && strings.HasPrefix(tokens[i].value, "-") {
+		opt := tokens[i].value
+		i++
+		if opt == "--" {
+			break
+		}
+
+		value := ""
+		switch {
+		case opt == "-u" || opt == "--user" || (opt == "-g" || opt == "--group") && name == "sudo":
+			if i >= len(tokens) || tokens[i].kind != wordToken {
+				return e, 0, fmt.Errorf("valeur manquante pour l'option %s de %s", opt, name)
+			}
+			value = tokens[i].value
+			i++
+		case strings.HasPrefix(opt, "--user="), strings.HasPrefix(opt, "--group=") && name == "sudo":
+			value = opt[strings.IndexByte(opt, '=')+1:]
+			opt = opt[:strings.IndexByte(opt, '=')]
+		case strings.HasPrefix(opt, "-u") || strings.HasPrefix(opt, "-g") && name == "sudo":
+			value = opt[2:]
+			opt = opt[:2]
+		case sudoFlags[opt]:
+			continue
+		default:
+			return e, 0, fmt.Errorf("option %s de %s non prise en charge", opt, name)
+		}
+
+		if opt == "-u" || opt == "--user" {
+			// -u root équivaut à l'élévation par défaut
+			if value == "root" || value == "#0" {
+				value = ""
+			}
+			e.User = value
+		} else {
+			e.Group = value
+		}
+	}
+	return e, i, nil
+}
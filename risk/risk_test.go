@@ -0,0 +1,171 @@
+package risk
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStripSudo(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		want      string
+		elevation Elevation
+		err       string // fragment de l'erreur attendue
+	}{
+		{name: "sans sudo", line: "ls -la", want: "ls -la"},
+		{name: "sudo simple", line: "sudo apt-get update", want: "apt-get update"},
+		{name: "sudo -u", line: "sudo -u postgres psql -c 'select 1'", want: "psql -c 'select 1'", elevation: Elevation{User: "postgres"}},
+		{name: "sudo -g", line: "sudo -g adm cat /var/log/syslog", want: "cat /var/log/syslog", elevation: Elevation{Group: "adm"}},
+		{name: "doas -u", line: "doas -u bob ls ~bob", want: "ls ~bob", elevation: Elevation{User: "bob"}},
+		{name: "option collée", line: "sudo -ubob ls", want: "ls", elevation: Elevation{User: "bob"}},
+		{name: "option longue", line: "sudo --user=bob -- ls", want: "ls", elevation: Elevation{User: "bob"}},
+		{name: "sudo -u root", line: "sudo -u root id", want: "id"},
+		{name: "même identité dans un pipe", line: "sudo -u a x | sudo -u a y", want: "x | y", elevation: Elevation{User: "a"}},
+		{name: "sudo puis commande sans sudo", line: "sudo a; b", want: "a; b"},
+		{name: "option sans effet", line: "sudo -E make install", want: "make install"},
+		{name: "guillemets conservés", line: `sudo sh -c "echo 'x' > /etc/motd"`, want: `sh -c "echo 'x' > /etc/motd"`},
+		{name: "sudo en argument", line: "echo sudo -u x", want: "echo sudo -u x"},
+
+		{name: "sudo -u mêlé à une commande sans sudo", line: "sudo -u x a && b", err: "ne peut pas être combiné"},
+		{name: "identités différentes", line: "sudo -u a x; sudo -u b y", err: "identités différentes"},
+		{name: "shell interactif -i", line: "sudo -i", err: "option -i de sudo non prise en charge"},
+		{name: "shell interactif -s", line: "doas -s", err: "option -s de doas non prise en charge"},
+		{name: "commande manquante", line: "sudo -u bob", err: "commande manquante après sudo"},
+		{name: "valeur manquante", line: "sudo -u", err: "valeur manquante"},
+		{name: "guillemet non fermé", line: "sudo echo 'x", err: "apostrophe non fermée"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, elevation, err := StripSudo(tt.line)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("erreur = %v, %q attendu", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("erreur inattendue : %v", err)
+			}
+			if got != tt.want || elevation != tt.elevation {
+				t.Errorf("obtenu %q %+v, attendu %q %+v", got, elevation, tt.want, tt.elevation)
+			}
+		})
+	}
+}
+
+func TestClassifyRootShell(t *testing.T) {
+	// Un shell root ouvert par sudo/doas sans commande ne doit jamais passer
+	// pour une commande en lecture seule
+	for _, line := range []string{"sudo -i", "sudo -s", "sudo", "sudo su", "sudo su -", "doas -s", "sudo bash", "su -", "sudo -u bob"} {
+		t.Run(line, func(t *testing.T) {
+			a := Classify(line)
+			if a.Level < Privileged || !a.RequiresRoot {
+				t.Errorf("niveau %s, root %v : au moins privilégiée attendu", a.Level, a.RequiresRoot)
+			}
+			if strings.HasPrefix(line, "su ") {
+				return
+			}
+			if !a.UsesSudo || len(a.Commands) != 1 || !a.Commands[0].Sudo {
+				t.Errorf("sudo = %v, commandes = %+v", a.UsesSudo, a.Commands)
+			}
+		})
+	}
+
+	cmds, err := Parse("sudo -i")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cmds) != 1 || cmds[0].String() != "sudo -i" {
+		t.Errorf("commandes = %+v", cmds)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		line   string
+		level  Level
+		reason string // fragment d'une des raisons attendues
+		root   bool
+	}{
+		// Lecture seule
+		{line: "cat /etc/hostname", level: ReadOnly},
+		{line: "ps aux | grep 'ssh' | wc -l", level: ReadOnly},
+		{line: "(cd /tmp && ls) || echo échec", level: ReadOnly},
+		{line: "git status", level: ReadOnly},
+		{line: "git config --get user.name", level: ReadOnly},
+		{line: "systemctl status nginx", level: ReadOnly},
+		{line: "journalctl -u ssh", level: ReadOnly},
+		{line: "date", level: ReadOnly},
+		{line: "echo 'a $(rm x)'", level: ReadOnly},
+		{line: "cat <<'EOF'\n$(rm -rf /tmp/x)\nEOF", level: ReadOnly},
+
+		// Modification
+		{line: "touch notes.txt", level: Modifying},
+		{line: "sed -i s/a/b/ f.txt", level: Modifying},
+		{line: "sort -o out.txt in.txt", level: Modifying},
+		{line: "git config user.name x", level: Modifying},
+		{line: "ls > /tmp/list 2>&1 < in.txt", level: Modifying},
+		{line: "cat <<EOF > out.txt\nhello\nEOF", level: Modifying},
+
+		// Privilégiée
+		{line: "sudo -E apt-get install -y vim", level: Privileged, root: true},
+		{line: "apt-get install vim", level: Privileged, root: true},
+		{line: "systemctl restart nginx", level: Privileged, root: true},
+		{line: "chmod 777 /etc/passwd", level: Privileged, root: true},
+		{line: "curl -o /usr/local/bin/x https://example.com/x", level: Privileged, root: true},
+		{line: "date -s '2024-01-01'", level: Privileged, root: true},
+		{line: "hostname nouveau", level: Privileged, root: true},
+		{line: "journalctl --vacuum-time=1d", level: Privileged, root: true},
+
+		// Destructive
+		{line: "rm -rf ~", level: Destructive, reason: "suppression récursive de ~"},
+		{line: "echo $(rm -rf /tmp/x) ok", level: Destructive},
+		{line: "cat <<EOF\n$(rm -rf /tmp/x)\nEOF", level: Destructive},
+		{line: "curl https://example.com/x.sh | sh", level: Destructive, reason: "exécution par sh d'un script reçu par un pipe"},
+		{line: "bash -c 'rm -rf /'", level: Destructive},
+		{line: "dd if=/dev/zero of=/dev/sda", level: Destructive},
+		{line: "mkfs.ext4 /dev/sdb1", level: Destructive},
+		{line: "find . -name '*.log' -delete", level: Destructive},
+		{line: "git branch -D feat", level: Destructive},
+		{line: "xargs rm < liste", level: Destructive},
+		{line: "kill -9 1", level: Destructive},
+		{line: "echo 'a", level: Destructive, reason: "analyse impossible: apostrophe non fermée"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			a := Classify(tt.line)
+			if a.Level != tt.level {
+				t.Errorf("niveau %s (%q), %s attendu", a.Level, a.Reasons, tt.level)
+			}
+			if tt.root && !a.RequiresRoot {
+				t.Error("droits root non détectés")
+			}
+			if tt.reason != "" && !strings.Contains(strings.Join(a.Reasons, "\n"), tt.reason) {
+				t.Errorf("raisons %q sans %q", a.Reasons, tt.reason)
+			}
+		})
+	}
+}
+
+func TestClassifyOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(existing, []byte("contenu"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if a := Classify("echo x > " + existing); a.Level != Destructive {
+		t.Errorf("écrasement : niveau %s, destructive attendu", a.Level)
+	}
+	if a := Classify("echo x >> " + existing); a.Level != Modifying {
+		t.Errorf("ajout : niveau %s, modification attendu", a.Level)
+	}
+	if a := Classify("echo x > " + filepath.Join(dir, "nouveau.txt")); a.Level != Modifying {
+		t.Errorf("nouveau fichier : niveau %s, modification attendu", a.Level)
+	}
+}
@@ -0,0 +1,338 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxProblems est le nombre maximal d'écarts rapportés par une validation
+const maxProblems = 10
+
+// Schema est un sous-ensemble de JSON Schema suffisant pour décrire les réponses
+// attendues du modèle : types, propriétés requises, énumérations, bornes, motifs
+// et combinaisons anyOf/oneOf/allOf. Les mots-clés non pris en charge ($ref,
+// formats...) sont ignorés.
+type Schema struct {
+	Type                 typeList           `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *additional        `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Const                json.RawMessage    `json:"const,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+
+	pattern *regexp.Regexp
+
+	// Valeur de const décodée ; hasConst distingue "const": null de l'absence de const
+	constant interface{}
+	hasConst bool
+}
+
+// typeList accepte "type": "string" comme "type": ["string", "null"]
+type typeList []string
+
+// UnmarshalJSON implémente json.Unmarshaler
+func (t *typeList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = typeList{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("type invalide: %s", string(data))
+	}
+	*t = list
+	return nil
+}
+
+// additional représente additionalProperties : un booléen ou un schéma
+type additional struct {
+	allowed bool
+	schema  *Schema
+}
+
+// UnmarshalJSON implémente json.Unmarshaler
+func (a *additional) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &a.allowed); err == nil {
+		return nil
+	}
+	a.allowed = true
+	a.schema = &Schema{}
+	return json.Unmarshal(data, a.schema)
+}
+
+// ValidationError liste les écarts entre un document et son schéma
+type ValidationError struct {
+	Problems []string
+}
+
+// Error implémente l'interface error
+func (e *ValidationError) Error() string {
+	return strings.Join(e.Problems, "; ")
+}
+
+// Parse lit un schéma JSON et compile ses motifs
+func Parse(data []byte) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("schéma JSON invalide: %w", err)
+	}
+	if err := s.compile("$"); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// compile prépare les expressions régulières et les constantes du schéma et
+// de ses sous-schémas
+func (s *Schema) compile(path string) error {
+	if len(s.Const) > 0 {
+		if err := json.Unmarshal(s.Const, &s.constant); err != nil {
+			return fmt.Errorf("schéma JSON invalide: const de %s: %w", path, err)
+		}
+		s.hasConst = true
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("schéma JSON invalide: motif de %s: %w", path, err)
+		}
+		s.pattern = re
+	}
+
+	var children []*Schema
+	for _, child := range s.Properties {
+		children = append(children, child)
+	}
+	if s.Items != nil {
+		children = append(children, s.Items)
+	}
+	if s.AdditionalProperties != nil && s.AdditionalProperties.schema != nil {
+		children = append(children, s.AdditionalProperties.schema)
+	}
+	children = append(children, s.AnyOf...)
+	children = append(children, s.OneOf...)
+	children = append(children, s.AllOf...)
+	for _, child := range children {
+		if child == nil {
+			continue
+		}
+		if err := child.compile(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate vérifie qu'un document JSON est conforme au schéma. Un document
+// non conforme donne une *ValidationError décrivant chaque écart avec son chemin.
+func (s *Schema) Validate(data []byte) error {
+	var doc interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err := dec.Decode(&doc); err != nil {
+		return &ValidationError{Problems: []string{fmt.Sprintf("JSON invalide: %v", err)}}
+	}
+	if dec.More() {
+		return &ValidationError{Problems: []string{"JSON invalide: contenu après la fin du document"}}
+	}
+
+	var problems []string
+	s.validate(doc, "$", &problems)
+	if len(problems) == 0 {
+		return nil
+	}
+	if len(problems) > maxProblems {
+		problems = append(problems[:maxProblems], fmt.Sprintf("et %d autre(s) écart(s)", len(problems)-maxProblems))
+	}
+	return &ValidationError{Problems: problems}
+}
+
+// validate vérifie une valeur et ajoute les écarts trouvés à problems
+func (s *Schema) validate(v interface{}, path string, problems *[]string) {
+	report := func(format string, args ...interface{}) {
+		*problems = append(*problems, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	if len(s.Type) > 0 && !s.Type.matches(v) {
+		report("type %s attendu, %s obtenu", strings.Join(s.Type, " ou "), typeName(v))
+		return
+	}
+	if len(s.Enum) > 0 && !containsValue(s.Enum, v) {
+		report("valeur %s hors de l'énumération %s", encode(v), encode(s.Enum))
+	}
+	if s.hasConst && !equalValues(s.constant, v) {
+		report("valeur %s attendue", encode(s.constant))
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		s.validateObject(val, path, problems)
+	case []interface{}:
+		if s.MinItems != nil && len(val) < *s.MinItems {
+			report("au moins %d élément(s) attendu(s)", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(val) > *s.MaxItems {
+			report("au plus %d élément(s) attendu(s)", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range val {
+				s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i), problems)
+			}
+		}
+	case string:
+		length := len([]rune(val))
+		if s.MinLength != nil && length < *s.MinLength {
+			report("au moins %d caractère(s) attendu(s)", *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			report("au plus %d caractère(s) attendu(s)", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(val) {
+			report("ne correspond pas au motif %s", s.Pattern)
+		}
+	case float64:
+		if s.Minimum != nil && val < *s.Minimum {
+			report("valeur minimale %g", *s.Minimum)
+		}
+		if s.Maximum != nil && val > *s.Maximum {
+			report("valeur maximale %g", *s.Maximum)
+		}
+	}
+
+	for _, sub := range s.AllOf {
+		sub.validate(v, path, problems)
+	}
+	if len(s.AnyOf) > 0 && countMatches(s.AnyOf, v, path) == 0 {
+		report("ne correspond à aucune des variantes anyOf")
+	}
+	if len(s.OneOf) > 0 {
+		if n := countMatches(s.OneOf, v, path); n != 1 {
+			report("doit correspondre à exactement une variante oneOf (%d trouvée(s))", n)
+		}
+	}
+}
+
+// validateObject vérifie les propriétés d'un objet
+func (s *Schema) validateObject(obj map[string]interface{}, path string, problems *[]string) {
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			*problems = append(*problems, fmt.Sprintf("%s: propriété requise manquante: %s", path, name))
+		}
+	}
+
+	// Parcours dans l'ordre des clés pour des messages stables
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		childPath := path + "." + key
+		if prop, ok := s.Properties[key]; ok {
+			prop.validate(obj[key], childPath, problems)
+			continue
+		}
+		if s.AdditionalProperties == nil {
+			continue
+		}
+		if !s.AdditionalProperties.allowed {
+			*problems = append(*problems, fmt.Sprintf("%s: propriété non autorisée", childPath))
+		} else if s.AdditionalProperties.schema != nil {
+			s.AdditionalProperties.schema.validate(obj[key], childPath, problems)
+		}
+	}
+}
+
+// countMatches compte les variantes auxquelles la valeur est conforme
+func countMatches(variants []*Schema, v interface{}, path string) int {
+	n := 0
+	for _, variant := range variants {
+		var problems []string
+		variant.validate(v, path, &problems)
+		if len(problems) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// matches indique si la valeur est de l'un des types listés
+func (t typeList) matches(v interface{}) bool {
+	for _, name := range t {
+		switch name {
+		case "integer":
+			if f, ok := v.(float64); ok && f == math.Trunc(f) {
+				return true
+			}
+		case "number":
+			if _, ok := v.(float64); ok {
+				return true
+			}
+		default:
+			if typeName(v) == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// typeName retourne le nom JSON Schema du type d'une valeur décodée
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "inconnu"
+}
+
+// containsValue indique si la valeur figure dans la liste
+func containsValue(list []interface{}, v interface{}) bool {
+	for _, item := range list {
+		if equalValues(item, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalValues compare deux valeurs JSON décodées
+func equalValues(a, b interface{}) bool {
+	return encode(a) == encode(b)
+}
+
+// encode sérialise une valeur pour l'afficher ou la comparer (clés triées)
+func encode(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,149 @@
+package schema
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// mustParse lit un schéma de test
+func mustParse(t *testing.T, data string) *Schema {
+	t.Helper()
+	s, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		doc    string
+		want   string // fragment de l'erreur attendue, vide si le document est conforme
+	}{
+		// Unions de types
+		{name: "union chaîne", schema: `{"type": ["string", "null"]}`, doc: `"a"`},
+		{name: "union null", schema: `{"type": ["string", "null"]}`, doc: `null`},
+		{name: "hors union", schema: `{"type": ["string", "null"]}`, doc: `3`, want: "$: type string ou null attendu, number obtenu"},
+		{name: "type simple", schema: `{"type": "boolean"}`, doc: `"true"`, want: "type boolean attendu, string obtenu"},
+
+		// Entiers et nombres
+		{name: "entier", schema: `{"type": "integer"}`, doc: `42`},
+		{name: "entier écrit en décimal", schema: `{"type": "integer"}`, doc: `42.0`},
+		{name: "décimal refusé", schema: `{"type": "integer"}`, doc: `4.2`, want: "type integer attendu, number obtenu"},
+		{name: "nombre", schema: `{"type": "number"}`, doc: `4.2`},
+		{name: "bornes", schema: `{"type": "number", "minimum": 0, "maximum": 1}`, doc: `1.5`, want: "valeur maximale 1"},
+
+		// Propriétés requises et supplémentaires
+		{
+			name:   "propriété requise manquante",
+			schema: `{"type": "object", "properties": {"a": {"type": "string"}}, "required": ["a", "b"]}`,
+			doc:    `{"a": "x"}`,
+			want:   "$: propriété requise manquante: b",
+		},
+		{
+			name:   "propriété non autorisée",
+			schema: `{"type": "object", "properties": {"a": {}}, "additionalProperties": false}`,
+			doc:    `{"a": 1, "z": 2}`,
+			want:   "$.z: propriété non autorisée",
+		},
+		{
+			name:   "propriétés supplémentaires par défaut",
+			schema: `{"type": "object", "properties": {"a": {}}}`,
+			doc:    `{"a": 1, "z": 2}`,
+		},
+		{
+			name:   "schéma des propriétés supplémentaires",
+			schema: `{"type": "object", "additionalProperties": {"type": "integer"}}`,
+			doc:    `{"a": 1, "b": "deux"}`,
+			want:   "$.b: type integer attendu, string obtenu",
+		},
+		{
+			name:   "chemin d'un élément",
+			schema: `{"type": "object", "properties": {"l": {"type": "array", "items": {"type": "string"}}}}`,
+			doc:    `{"l": ["a", 2]}`,
+			want:   "$.l[1]: type string attendu",
+		},
+
+		// Combinaisons
+		{name: "anyOf satisfait", schema: `{"anyOf": [{"type": "string"}, {"type": "integer"}]}`, doc: `3`},
+		{name: "anyOf aucun", schema: `{"anyOf": [{"type": "string"}, {"type": "integer"}]}`, doc: `true`, want: "aucune des variantes anyOf"},
+		{name: "oneOf exactement un", schema: `{"oneOf": [{"type": "integer"}, {"type": "string"}]}`, doc: `3`},
+		{name: "oneOf plusieurs", schema: `{"oneOf": [{"type": "integer"}, {"type": "number"}]}`, doc: `3`, want: "exactement une variante oneOf (2 trouvée(s))"},
+		{name: "oneOf aucun", schema: `{"oneOf": [{"type": "integer"}, {"type": "string"}]}`, doc: `null`, want: "(0 trouvée(s))"},
+		{name: "allOf", schema: `{"allOf": [{"type": "string"}, {"minLength": 3}]}`, doc: `"ab"`, want: "au moins 3 caractère(s)"},
+
+		// Motifs
+		{name: "motif respecté", schema: `{"type": "string", "pattern": "^[a-z]+-[0-9]+$"}`, doc: `"abc-12"`},
+		{name: "motif non respecté", schema: `{"type": "string", "pattern": "^[a-z]+-[0-9]+$"}`, doc: `"abc12"`, want: "ne correspond pas au motif ^[a-z]+-[0-9]+$"},
+		{name: "motif non ancré", schema: `{"pattern": "[0-9]"}`, doc: `"v2"`},
+
+		// Énumérations et constantes
+		{name: "énumération", schema: `{"enum": ["a", 1, null]}`, doc: `null`},
+		{name: "hors énumération", schema: `{"enum": ["a", 1]}`, doc: `"b"`, want: `valeur "b" hors de l'énumération ["a",1]`},
+		{name: "const", schema: `{"const": {"v": 1}}`, doc: `{"v": 1}`},
+		{name: "const différente", schema: `{"const": "oui"}`, doc: `"non"`, want: `valeur "oui" attendue`},
+		{name: "const null respectée", schema: `{"const": null}`, doc: `null`},
+		{name: "const null", schema: `{"const": null}`, doc: `0`, want: "valeur null attendue"},
+
+		// Document lui-même
+		{name: "contenu après le document", schema: `{}`, doc: `{"a": 1} {"b": 2}`, want: "contenu après la fin du document"},
+		{name: "texte après le document", schema: `{}`, doc: `{"a": 1} fin`, want: "contenu après la fin du document"},
+		{name: "espaces après le document", schema: `{}`, doc: "{\"a\": 1}\n  "},
+		{name: "JSON invalide", schema: `{}`, doc: `{"a": }`, want: "JSON invalide"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := mustParse(t, tt.schema).Validate([]byte(tt.doc))
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("erreur inattendue : %v", err)
+				}
+				return
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("*ValidationError attendue, obtenu %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("erreur %q sans %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateProblemLimit(t *testing.T) {
+	s := mustParse(t, `{"type": "array", "items": {"type": "string"}}`)
+	err := s.Validate([]byte(`[1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12]`))
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("*ValidationError attendue, obtenu %v", err)
+	}
+	if len(verr.Problems) != maxProblems+1 || verr.Problems[maxProblems] != "et 2 autre(s) écart(s)" {
+		t.Errorf("écarts = %q", verr.Problems)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		want   string
+	}{
+		{name: "motif invalide", schema: `{"properties": {"a": {"pattern": "(["}}}`, want: "motif"},
+		{name: "type invalide", schema: `{"type": 3}`, want: "type invalide"},
+		{name: "pas un objet", schema: `[]`, want: "schéma JSON invalide"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.schema))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("erreur = %v", err)
+			}
+		})
+	}
+}
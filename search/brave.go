@@ -0,0 +1,121 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// braveEndpoint est l'adresse de l'API Brave Search
+const braveEndpoint = "https://api.search.brave.com/res/v1/web/search"
+
+// BraveSearcher interroge l'API Brave Search
+type BraveSearcher struct {
+	APIKey   string
+	Endpoint string
+}
+
+// NewBraveSearcher crée un moteur Brave Search
+func NewBraveSearcher(apiKey string) *BraveSearcher {
+	return &BraveSearcher{APIKey: apiKey, Endpoint: braveEndpoint}
+}
+
+// Name implémente Searcher
+func (s *BraveSearcher) Name() string {
+	return BackendBrave
+}
+
+// Search implémente Searcher
+func (s *BraveSearcher) Search(ctx context.Context, query string) (*Results, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("count", strconv.Itoa(maxResults))
+
+	status, body, err := get(ctx, s.Endpoint+"?"+params.Encode(), map[string]string{
+		"Accept":               "application/json",
+		"X-Subscription-Token": s.APIKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decodeResponse(status, body, query, DecodeBrave)
+}
+
+// braveResponse représente les parties exploitées d'une réponse Brave Search
+type braveResponse struct {
+	Type  string `json:"type"`
+	Error *struct {
+		Code   string `json:"code"`
+		Detail string `json:"detail"`
+	} `json:"error"`
+	Query struct {
+		Original string `json:"original"`
+	} `json:"query"`
+	Web struct {
+		Results []struct {
+			Title       string `json:"title"`
+			URL         string `json:"url"`
+			Description string `json:"description"`
+			Age         string `json:"age"`
+			Profile     struct {
+				Name string `json:"name"`
+			} `json:"profile"`
+		} `json:"results"`
+	} `json:"web"`
+	Infobox struct {
+		Results []struct {
+			Title       string     `json:"title"`
+			Description string     `json:"description"`
+			LongDesc    string     `json:"long_desc"`
+			URL         string     `json:"url"`
+			Attributes  [][]string `json:"attributes"`
+		} `json:"results"`
+	} `json:"infobox"`
+}
+
+// DecodeBrave décode une réponse de l'API Brave Search
+func DecodeBrave(body []byte) (*Results, error) {
+	var resp braveResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("erreur lors de la désérialisation de la réponse: %w", err)
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("erreur Brave Search: %s (%s)", resp.Error.Detail, resp.Error.Code)
+	}
+
+	results := &Results{Query: resp.Query.Original, Backend: BackendBrave}
+	for _, r := range resp.Web.Results {
+		source := r.Profile.Name
+		if source == "" {
+			source = hostOf(r.URL)
+		}
+		// Les titres et extraits contiennent des balises de mise en évidence
+		results.Items = append(results.Items, Result{
+			Title:   htmlText(r.Title),
+			URL:     r.URL,
+			Snippet: htmlText(r.Description),
+			Source:  source,
+			Date:    r.Age,
+		})
+	}
+	rankItems(results.Items)
+
+	if len(resp.Infobox.Results) > 0 {
+		box := resp.Infobox.Results[0]
+		k := &Knowledge{Title: box.Title, Description: htmlText(box.LongDesc), URL: box.URL, Source: hostOf(box.URL)}
+		if k.Description == "" {
+			k.Description = htmlText(box.Description)
+		}
+		for _, attr := range box.Attributes {
+			if len(attr) == 2 && attr[1] != "" {
+				k.Facts = append(k.Facts, Fact{Label: attr[0], Value: attr[1]})
+			}
+		}
+		if k.Title != "" {
+			results.Knowledge = k
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,125 @@
+package search
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestBraveSearch(t *testing.T) {
+	srv, got := newSearchServer(t, http.StatusOK, "application/json", readFixture(t, "brave.json"))
+
+	s := NewBraveSearcher("brave-test")
+	s.Endpoint = srv.URL + "/res/v1/web/search"
+	results, err := s.Search(context.Background(), "mozilla foundation")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.path != "/res/v1/web/search" || got.query["q"] != "mozilla foundation" || got.query["count"] != "10" {
+		t.Errorf("requête %s %v", got.path, got.query)
+	}
+	if token := got.header.Get("X-Subscription-Token"); token != "brave-test" {
+		t.Errorf("X-Subscription-Token = %q", token)
+	}
+
+	if results.Query != "mozilla foundation" || results.Backend != BackendBrave {
+		t.Errorf("requête %q, moteur %q", results.Query, results.Backend)
+	}
+	// Les balises de mise en évidence et les entités sont retirées
+	want := []Result{
+		{Title: "Mozilla Foundation - Internet for people, not profit", URL: "https://foundation.mozilla.org/fr/",
+			Snippet: "The Mozilla Foundation works to ensure the internet remains a public resource that is open and accessible to us all.",
+			Source:  "Mozilla Foundation", Rank: 1},
+		{Title: "Mozilla Foundation — Wikipédia", URL: "https://fr.wikipedia.org/wiki/Mozilla_Foundation",
+			Snippet: "La Mozilla Foundation est une organisation à but non lucratif créée le 15 juillet 2003.",
+			Source:  "Wikipedia", Date: "3 mars 2024", Rank: 2},
+		{Title: "Mozilla & the open web", URL: "https://www.example.net/mozilla", Source: "example.net", Rank: 3},
+	}
+	if len(results.Items) != len(want) {
+		t.Fatalf("%d résultats, %d attendus", len(results.Items), len(want))
+	}
+	for i, w := range want {
+		if results.Items[i] != w {
+			t.Errorf("résultat %d :\n  obtenu  %+v\n  attendu %+v", i+1, results.Items[i], w)
+		}
+	}
+
+	k := results.Knowledge
+	if k == nil || k.Title != "Mozilla Foundation" || k.Source != "fr.wikipedia.org" {
+		t.Fatalf("fiche = %+v", k)
+	}
+	if k.Description != "La Mozilla Foundation est une organisation à but non lucratif qui soutient le projet Mozilla." {
+		t.Errorf("description = %q", k.Description)
+	}
+	// Les attributs sans valeur sont écartés
+	if len(k.Facts) != 2 || k.Facts[1] != (Fact{Label: "Siège", Value: "Mountain View"}) {
+		t.Errorf("propriétés = %+v", k.Facts)
+	}
+}
+
+func TestDecodeBraveMissingFields(t *testing.T) {
+	// Sans résultat web ni fiche ; la description courte remplace la longue
+	results, err := DecodeBrave([]byte(`{"type": "search", "query": {"original": "x"},
+		"infobox": {"results": [{"title": "X", "description": "Courte <b>description</b>"}]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results.Items) != 0 || results.Knowledge == nil || results.Knowledge.Description != "Courte description" {
+		t.Errorf("résultats = %+v", results)
+	}
+
+	results, err = DecodeBrave([]byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !results.Empty() {
+		t.Errorf("résultats = %+v", results)
+	}
+}
+
+func TestBraveErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   []byte
+		want   []string
+	}{
+		{
+			name:   "clé invalide",
+			status: http.StatusUnprocessableEntity,
+			body:   readFixture(t, "brave_error.json"),
+			want:   []string{"The provided subscription token is invalid.", "SUBSCRIPTION_TOKEN_INVALID", "code: 422"},
+		},
+		{
+			name:   "limite de requêtes",
+			status: http.StatusTooManyRequests,
+			body:   []byte(`{"type": "ErrorResponse", "error": {"status": 429, "code": "RATE_LIMITED", "detail": "Request rate limit exceeded for plan."}}`),
+			want:   []string{"Request rate limit exceeded", "RATE_LIMITED", "code: 429"},
+		},
+		{
+			name:   "passerelle",
+			status: http.StatusBadGateway,
+			body:   []byte("<html>502 Bad Gateway</html>"),
+			want:   []string{"désérialisation", "code: 502"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, _ := newSearchServer(t, tt.status, "application/json", tt.body)
+			s := NewBraveSearcher("brave-test")
+			s.Endpoint = srv.URL
+			_, err := s.Search(context.Background(), "q")
+			if err == nil {
+				t.Fatal("erreur attendue")
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(err.Error(), w) {
+					t.Errorf("erreur %q sans %q", err, w)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package search
+
+import (
+	"asione-agent/types"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxCitationRange limite l'étendue d'une plage de citations ([1-4])
+const maxCitationRange = 20
+
+var (
+	// Citation entre crochets : [1], [1, 3], [2-4]
+	citationPattern = regexp.MustCompile(`\[(\d+(?:\s*(?:,|;|-|–)\s*\d+)*)\]`)
+
+	// Code du texte, où les crochets sont des indices et non des citations
+	fencedCodePattern = regexp.MustCompile("(?s)```.*?```")
+	inlineCodePattern = regexp.MustCompile("`[^`\n]*`")
+)
+
+// Sources numérote les résultats d'une recherche, dans l'ordre où ils sont
+// présentés au modèle
+func Sources(results *Results) []types.Source {
+	if results == nil {
+		return nil
+	}
+	sources := make([]types.Source, len(results.Items))
+	for i, item := range results.Items {
+		sources[i] = types.Source{Index: i + 1, Title: item.Title, URL: item.URL}
+	}
+	return sources
+}
+
+// Citations retourne les numéros cités dans un texte, sans doublon, dans
+// l'ordre de leur première apparition. Le code et les liens markdown sont ignorés.
+func Citations(text string) []int {
+	text = fencedCodePattern.ReplaceAllString(text, "")
+	text = inlineCodePattern.ReplaceAllString(text, "")
+
+	var cited []int
+	seen := make(map[int]bool)
+	add := func(n int) {
+		if !seen[n] {
+			seen[n] = true
+			cited = append(cited, n)
+		}
+	}
+
+	for _, loc := range citationPattern.FindAllStringSubmatchIndex(text, -1) {
+		// [1](https://...) est un lien, pas une citation
+		if loc[1] < len(text) && text[loc[1]] == '(' {
+			continue
+		}
+		for _, group := range strings.FieldsFunc(text[loc[2]:loc[3]], func(r rune) bool { return r == ',' || r == ';' }) {
+			bounds := strings.FieldsFunc(group, func(r rune) bool { return r == '-' || r == '–' })
+			first, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+			if err != nil {
+				continue
+			}
+			last := first
+			if len(bounds) == 2 {
+				if n, err := strconv.Atoi(strings.TrimSpace(bounds[1])); err == nil && n >= first && n-first < maxCitationRange {
+					last = n
+				}
+			}
+			for n := first; n <= last; n++ {
+				add(n)
+			}
+		}
+	}
+	return cited
+}
+
+// CheckCitations vérifie les citations d'une réponse : elle retourne les
+// sources citées, par numéro croissant, et les numéros qui ne correspondent à
+// aucune source
+func CheckCitations(text string, sources []types.Source) (cited []types.Source, unknown []int) {
+	byIndex := make(map[int]types.Source, len(sources))
+	for _, src := range sources {
+		byIndex[src.Index] = src
+	}
+
+	for _, n := range Citations(text) {
+		if src, ok := byIndex[n]; ok {
+			cited = append(cited, src)
+		} else {
+			unknown = append(unknown, n)
+		}
+	}
+	sort.Slice(cited, func(i, j int) bool {
+		return cited[i].Index < cited[j].Index
+	})
+	return cited, unknown
+}
+
+// FormatSources formate la liste des sources citées, affichée après la réponse
+func FormatSources(sources []types.Source) string {
+	var sb strings.Builder
+	sb.WriteString("Sources :\n")
+	for _, src := range sources {
+		if src.Title != "" {
+			sb.WriteString(fmt.Sprintf("  [%d] %s – %s\n", src.Index, src.Title, src.URL))
+		} else {
+			sb.WriteString(fmt.Sprintf("  [%d] %s\n", src.Index, src.URL))
+		}
+	}
+	return sb.String()
+}
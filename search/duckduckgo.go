@@ -0,0 +1,138 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// duckDuckGoEndpoint est l'adresse de la version HTML (sans JavaScript) de DuckDuckGo
+const duckDuckGoEndpoint = "https://html.duckduckgo.com/html/"
+
+// duckDuckGoUserAgent est l'agent utilisateur présenté à DuckDuckGo, qui refuse
+// les clients qui ne ressemblent pas à un navigateur
+const duckDuckGoUserAgent = "Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0"
+
+// DuckDuckGoSearcher extrait les résultats de la page HTML de DuckDuckGo. Il ne
+// nécessite aucune clé, mais dépend de la structure de la page.
+type DuckDuckGoSearcher struct {
+	Endpoint string
+}
+
+// NewDuckDuckGoSearcher crée un moteur DuckDuckGo
+func NewDuckDuckGoSearcher() *DuckDuckGoSearcher {
+	return &DuckDuckGoSearcher{Endpoint: duckDuckGoEndpoint}
+}
+
+// Name implémente Searcher
+func (s *DuckDuckGoSearcher) Name() string {
+	return BackendDuckDuckGo
+}
+
+// Search implémente Searcher
+func (s *DuckDuckGoSearcher) Search(ctx context.Context, query string) (*Results, error) {
+	params := url.Values{}
+	params.Set("q", query)
+
+	status, body, err := get(ctx, s.Endpoint+"?"+params.Encode(), map[string]string{
+		"User-Agent": duckDuckGoUserAgent,
+		"Accept":     "text/html",
+	})
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("erreur de recherche DuckDuckGo: %s (code: %d)", http.StatusText(status), status)
+	}
+
+	results, err := DecodeDuckDuckGo(body)
+	if err != nil {
+		return nil, err
+	}
+	results.Query = query
+	return results, nil
+}
+
+// Motifs de la page de résultats HTML
+var (
+	// Début d'un bloc de résultat (annonces comprises)
+	ddgResultPattern = regexp.MustCompile(`<div[^>]+class="[^"]*\bresult\b[^"]*"`)
+
+	// Lien principal du résultat : <a ... class="result__a" href="...">Titre</a>
+	ddgTitlePattern = regexp.MustCompile(`(?s)<a([^>]*class="[^"]*\bresult__a\b[^"]*"[^>]*)>(.*?)</a>`)
+
+	// Extrait du résultat, dans un lien ou un bloc selon les versions de la page
+	ddgSnippetPattern = regexp.MustCompile(`(?s)class="[^"]*\bresult__snippet\b[^"]*"[^>]*>(.*?)</(?:a|div|td)>`)
+
+	// Attribut href d'une balise
+	hrefPattern = regexp.MustCompile(`href="([^"]*)"`)
+
+	// Page de vérification anti-robot
+	ddgChallengePattern = regexp.MustCompile(`anomaly-modal|challenge-form`)
+)
+
+// DecodeDuckDuckGo extrait les résultats de la page HTML de DuckDuckGo ; les
+// annonces sont ignorées
+func DecodeDuckDuckGo(body []byte) (*Results, error) {
+	page := string(body)
+	if ddgChallengePattern.MatchString(page) {
+		return nil, fmt.Errorf("DuckDuckGo a demandé une vérification anti-robot, réessayez plus tard ou changez de moteur (SEARCH_BACKEND)")
+	}
+
+	results := &Results{Backend: BackendDuckDuckGo}
+	starts := ddgResultPattern.FindAllStringIndex(page, -1)
+	for i, start := range starts {
+		end := len(page)
+		if i+1 < len(starts) {
+			end = starts[i+1][0]
+		}
+		block := page[start[0]:end]
+		if strings.Contains(page[start[0]:start[1]], "result--ad") {
+			continue
+		}
+
+		title := ddgTitlePattern.FindStringSubmatch(block)
+		if title == nil {
+			continue
+		}
+		href := hrefPattern.FindStringSubmatch(title[1])
+		if href == nil {
+			continue
+		}
+		link := ddgTarget(html.UnescapeString(href[1]))
+		if link == "" {
+			continue
+		}
+
+		result := Result{Title: htmlText(title[2]), URL: link, Source: hostOf(link)}
+		if snippet := ddgSnippetPattern.FindStringSubmatch(block); snippet != nil {
+			result.Snippet = htmlText(snippet[1])
+		}
+		results.Items = append(results.Items, result)
+	}
+	rankItems(results.Items)
+	return results, nil
+}
+
+// ddgTarget retourne l'adresse réelle d'un lien de résultat, que DuckDuckGo fait
+// passer par sa redirection //duckduckgo.com/l/?uddg=<adresse>
+func ddgTarget(href string) string {
+	if strings.HasPrefix(href, "//") {
+		href = "https:" + href
+	}
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if target := u.Query().Get("uddg"); target != "" && strings.HasSuffix(u.Hostname(), "duckduckgo.com") {
+		return target
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	return href
+}
@@ -0,0 +1,83 @@
+package search
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDuckDuckGoSearch(t *testing.T) {
+	srv, got := newSearchServer(t, http.StatusOK, "text/html; charset=UTF-8", readFixture(t, "duckduckgo_results.html"))
+
+	s := NewDuckDuckGoSearcher()
+	s.Endpoint = srv.URL + "/html/"
+	results, err := s.Search(context.Background(), "golang context cancel")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.path != "/html/" || got.query["q"] != "golang context cancel" {
+		t.Errorf("requête %s %v", got.path, got.query)
+	}
+	if ua := got.header.Get("User-Agent"); !strings.HasPrefix(ua, "Mozilla/5.0") {
+		t.Errorf("User-Agent = %q", ua)
+	}
+
+	if results.Query != "golang context cancel" || results.Backend != BackendDuckDuckGo {
+		t.Errorf("requête %q, moteur %q", results.Query, results.Backend)
+	}
+	// L'annonce et le lien javascript: sont ignorés ; les redirections de
+	// DuckDuckGo sont remplacées par l'adresse réelle
+	want := []Result{
+		{Title: "context package - context - Go Packages", URL: "https://pkg.go.dev/context",
+			Snippet: "Package context defines the Context type, which carries deadlines, cancellation signals, and other request-scoped values across API boundaries & between processes.",
+			Source:  "pkg.go.dev", Rank: 1},
+		{Title: "Go Concurrency Patterns: Context - The Go Programming Language", URL: "https://go.dev/blog/context",
+			Snippet: "In Go servers, each incoming request is handled in its own goroutine. Request handlers often start additional goroutines…",
+			Source:  "go.dev", Rank: 2},
+		{Title: "How to cancel a context in Go", URL: "https://www.example.org/articles/go-context-cancel",
+			Source: "example.org", Rank: 3},
+	}
+	if len(results.Items) != len(want) {
+		t.Fatalf("%d résultats, %d attendus : %+v", len(results.Items), len(want), results.Items)
+	}
+	for i, w := range want {
+		if results.Items[i] != w {
+			t.Errorf("résultat %d :\n  obtenu  %+v\n  attendu %+v", i+1, results.Items[i], w)
+		}
+	}
+}
+
+func TestDecodeDuckDuckGoEmptyPage(t *testing.T) {
+	results, err := DecodeDuckDuckGo([]byte(`<html><body><div class="no-results">No results.</div></body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !results.Empty() {
+		t.Errorf("résultats = %+v", results.Items)
+	}
+}
+
+func TestDuckDuckGoErrors(t *testing.T) {
+	t.Run("vérification anti-robot", func(t *testing.T) {
+		srv, _ := newSearchServer(t, http.StatusOK, "text/html", readFixture(t, "duckduckgo_challenge.html"))
+		s := NewDuckDuckGoSearcher()
+		s.Endpoint = srv.URL
+		_, err := s.Search(context.Background(), "q")
+		if err == nil || !strings.Contains(err.Error(), "anti-robot") {
+			t.Errorf("erreur = %v", err)
+		}
+	})
+
+	t.Run("statut d'échec", func(t *testing.T) {
+		// DuckDuckGo répond 202 sans résultat lorsqu'il limite les requêtes
+		srv, _ := newSearchServer(t, http.StatusAccepted, "text/html", []byte("<html></html>"))
+		s := NewDuckDuckGoSearcher()
+		s.Endpoint = srv.URL
+		_, err := s.Search(context.Background(), "q")
+		if err == nil || !strings.Contains(err.Error(), "code: 202") {
+			t.Errorf("erreur = %v", err)
+		}
+	})
+}
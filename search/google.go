@@ -0,0 +1,118 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// googleCSEEndpoint est l'adresse de l'API Google Custom Search
+const googleCSEEndpoint = "https://www.googleapis.com/customsearch/v1"
+
+// GoogleCSESearcher interroge l'API Google Custom Search
+type GoogleCSESearcher struct {
+	APIKey   string
+	CX       string
+	Endpoint string
+}
+
+// NewGoogleCSESearcher crée un moteur Google Custom Search
+func NewGoogleCSESearcher(apiKey, cx string) *GoogleCSESearcher {
+	return &GoogleCSESearcher{APIKey: apiKey, CX: cx, Endpoint: googleCSEEndpoint}
+}
+
+// Name implémente Searcher
+func (s *GoogleCSESearcher) Name() string {
+	return BackendGoogleCSE
+}
+
+// Search implémente Searcher
+func (s *GoogleCSESearcher) Search(ctx context.Context, query string) (*Results, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("key", s.APIKey)
+	params.Set("cx", s.CX)
+	params.Set("num", strconv.Itoa(maxResults))
+
+	status, body, err := get(ctx, s.Endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return decodeResponse(status, body, query, DecodeGoogleCSE)
+}
+
+// googleCSEResponse représente les parties exploitées d'une réponse Google Custom Search
+type googleCSEResponse struct {
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+	Queries struct {
+		Request []struct {
+			SearchTerms string `json:"searchTerms"`
+		} `json:"request"`
+	} `json:"queries"`
+	SearchInformation struct {
+		SearchTime   float64 `json:"searchTime"`
+		TotalResults string  `json:"totalResults"`
+	} `json:"searchInformation"`
+	Items []struct {
+		Title       string `json:"title"`
+		Link        string `json:"link"`
+		DisplayLink string `json:"displayLink"`
+		Snippet     string `json:"snippet"`
+		Pagemap     struct {
+			Metatags []map[string]string `json:"metatags"`
+		} `json:"pagemap"`
+	} `json:"items"`
+}
+
+// publishedTags liste les métadonnées de page qui portent une date de publication
+var publishedTags = []string{"article:published_time", "og:updated_time", "date", "dc.date"}
+
+// DecodeGoogleCSE décode une réponse de l'API Google Custom Search
+func DecodeGoogleCSE(body []byte) (*Results, error) {
+	var resp googleCSEResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("erreur lors de la désérialisation de la réponse: %w", err)
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("erreur Google Custom Search: %s (code: %d)", resp.Error.Message, resp.Error.Code)
+	}
+
+	results := &Results{
+		Backend:    BackendGoogleCSE,
+		SearchTime: resp.SearchInformation.SearchTime,
+	}
+	if len(resp.Queries.Request) > 0 {
+		results.Query = resp.Queries.Request[0].SearchTerms
+	}
+	// totalResults est transmis sous forme de chaîne
+	if total, err := strconv.ParseInt(resp.SearchInformation.TotalResults, 10, 64); err == nil {
+		results.TotalResults = total
+	}
+
+	for _, item := range resp.Items {
+		result := Result{
+			Title:   item.Title,
+			URL:     item.Link,
+			Snippet: item.Snippet,
+			Source:  item.DisplayLink,
+		}
+		if result.Source == "" {
+			result.Source = hostOf(item.Link)
+		}
+		for _, tags := range item.Pagemap.Metatags {
+			for _, name := range publishedTags {
+				if date := tags[name]; date != "" && result.Date == "" {
+					result.Date = date
+				}
+			}
+		}
+		results.Items = append(results.Items, result)
+	}
+	rankItems(results.Items)
+	return results, nil
+}
@@ -0,0 +1,105 @@
+package search
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeGoogleCSE(t *testing.T) {
+	results, err := DecodeGoogleCSE(readFixture(t, "google_cse.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if results.Query != "golang context cancel" || results.Backend != BackendGoogleCSE {
+		t.Errorf("requête %q, moteur %q", results.Query, results.Backend)
+	}
+	// totalResults est une chaîne dans la réponse
+	if results.TotalResults != 2340000 || results.SearchTime != 0.312 {
+		t.Errorf("total %d, durée %v", results.TotalResults, results.SearchTime)
+	}
+	if results.Answer != nil || results.Knowledge != nil {
+		t.Errorf("ni réponse directe ni fiche attendues")
+	}
+
+	want := []Result{
+		{Title: "context package - context - Go Packages", URL: "https://pkg.go.dev/context",
+			Snippet: "Package context defines the Context type, which carries deadlines, cancellation signals...",
+			Source:  "pkg.go.dev", Rank: 1},
+		// La première métadonnée de date rencontrée est retenue
+		{Title: "Go Concurrency Patterns: Context", URL: "https://go.dev/blog/context",
+			Snippet: "In Go servers, each incoming request is handled in its own goroutine.",
+			Source:  "go.dev", Date: "2014-07-29T00:00:00Z", Rank: 2},
+		// Sans displayLink ni pagemap
+		{Title: "How to cancel a context in Go", URL: "https://www.example.org/articles/go-context-cancel",
+			Source: "example.org", Rank: 3},
+	}
+	if len(results.Items) != len(want) {
+		t.Fatalf("%d résultats, %d attendus", len(results.Items), len(want))
+	}
+	for i, w := range want {
+		if results.Items[i] != w {
+			t.Errorf("résultat %d :\n  obtenu  %+v\n  attendu %+v", i+1, results.Items[i], w)
+		}
+	}
+}
+
+func TestDecodeGoogleCSEMissingFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		check func(t *testing.T, r *Results)
+	}{
+		{
+			// Une recherche sans résultat n'a pas de champ items
+			name: "aucun résultat",
+			body: `{"queries": {"request": [{"searchTerms": "xqzvj"}]}, "searchInformation": {"searchTime": 0.1, "totalResults": "0"}}`,
+			check: func(t *testing.T, r *Results) {
+				if !r.Empty() || r.Query != "xqzvj" || r.TotalResults != 0 {
+					t.Errorf("résultats = %+v", r)
+				}
+			},
+		},
+		{
+			name: "réponse vide",
+			body: `{}`,
+			check: func(t *testing.T, r *Results) {
+				if !r.Empty() || r.Query != "" || r.SearchTime != 0 {
+					t.Errorf("résultats = %+v", r)
+				}
+			},
+		},
+		{
+			name: "total illisible",
+			body: `{"searchInformation": {"totalResults": "environ 2 millions"}, "items": [{"title": "A", "link": "https://a.example/x"}]}`,
+			check: func(t *testing.T, r *Results) {
+				if r.TotalResults != 0 || len(r.Items) != 1 || r.Items[0].Source != "a.example" {
+					t.Errorf("résultats = %+v", r)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := DecodeGoogleCSE([]byte(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, results)
+		})
+	}
+}
+
+func TestDecodeGoogleCSEError(t *testing.T) {
+	_, err := DecodeGoogleCSE(readFixture(t, "google_cse_error.json"))
+	if err == nil || !strings.Contains(err.Error(), "are blocked") || !strings.Contains(err.Error(), "403") {
+		t.Errorf("erreur = %v", err)
+	}
+
+	// Le message du moteur est repris même avec un statut HTTP d'échec
+	_, err = decodeResponse(403, readFixture(t, "google_cse_error.json"), "q", DecodeGoogleCSE)
+	if err == nil || !strings.Contains(err.Error(), "are blocked") {
+		t.Errorf("erreur = %v", err)
+	}
+}
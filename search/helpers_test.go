@@ -0,0 +1,46 @@
+package search
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// readFixture lit une réponse enregistrée dans testdata
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// request conserve la dernière requête reçue par le serveur de test
+type request struct {
+	path   string
+	query  map[string]string
+	header http.Header
+}
+
+// newSearchServer démarre un serveur qui enregistre la requête reçue puis répond
+// avec le statut, le type de contenu et le corps donnés
+func newSearchServer(t *testing.T, status int, contentType string, body []byte) (*httptest.Server, *request) {
+	t.Helper()
+	got := &request{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.path = r.URL.Path
+		got.query = make(map[string]string)
+		for name, values := range r.URL.Query() {
+			got.query[name] = values[0]
+		}
+		got.header = r.Header.Clone()
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(status)
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, got
+}
@@ -0,0 +1,175 @@
+package search
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// maxFormattedResults est le nombre de résultats repris dans le texte formaté
+const maxFormattedResults = 5
+
+// Result représente un résultat de recherche, quel que soit le moteur qui l'a fourni
+type Result struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Snippet string `json:"snippet,omitempty"`
+
+	// Site ou publication d'origine (ex. "Wikipédia", "lemonde.fr")
+	Source string `json:"source,omitempty"`
+
+	// Date de publication telle qu'indiquée par le moteur (format libre)
+	Date string `json:"date,omitempty"`
+
+	// Position dans la page de résultats (à partir de 1)
+	Rank int `json:"rank"`
+}
+
+// Answer représente une réponse directe mise en avant par le moteur (answer box)
+type Answer struct {
+	Title  string `json:"title,omitempty"`
+	Text   string `json:"text"`
+	URL    string `json:"url,omitempty"`
+	Source string `json:"source,omitempty"`
+}
+
+// Fact représente une propriété d'une fiche de connaissances (ex. "Fondation : 1998")
+type Fact struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// Knowledge représente la fiche de synthèse d'une entité (knowledge graph)
+type Knowledge struct {
+	Title       string `json:"title"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+	Source      string `json:"source,omitempty"`
+	URL         string `json:"url,omitempty"`
+	Facts       []Fact `json:"facts,omitempty"`
+}
+
+// Results représente la réponse normalisée d'une recherche
+type Results struct {
+	Query   string `json:"query"`
+	Backend string `json:"backend"`
+
+	// Réponse directe et fiche de synthèse, lorsque le moteur en fournit
+	Answer    *Answer    `json:"answer,omitempty"`
+	Knowledge *Knowledge `json:"knowledge,omitempty"`
+
+	Items []Result `json:"items"`
+
+	// Nombre total de résultats annoncé et durée de la recherche en secondes (0 si inconnus)
+	TotalResults int64   `json:"total_results,omitempty"`
+	SearchTime   float64 `json:"search_time,omitempty"`
+}
+
+// Empty indique si la recherche n'a rien donné d'exploitable
+func (r *Results) Empty() bool {
+	return r == nil || (len(r.Items) == 0 && r.Answer == nil && r.Knowledge == nil)
+}
+
+// FormatSearchResults formate les résultats de recherche en texte
+func FormatSearchResults(results *Results) string {
+	if results.Empty() {
+		return "Aucun résultat trouvé pour cette recherche."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Résultats de recherche:\n")
+	if results.SearchTime > 0 {
+		sb.WriteString(fmt.Sprintf("Temps de recherche: %.2f secondes\n", results.SearchTime))
+	}
+	if results.TotalResults > 0 {
+		sb.WriteString(fmt.Sprintf("Résultats totaux: %d\n", results.TotalResults))
+	}
+	sb.WriteString("\n")
+
+	if a := results.Answer; a != nil {
+		sb.WriteString("Réponse directe")
+		if a.Title != "" {
+			sb.WriteString(" (" + a.Title + ")")
+		}
+		sb.WriteString(fmt.Sprintf(":\n   %s\n", a.Text))
+		if a.URL != "" {
+			sb.WriteString(fmt.Sprintf("   [%s]\n", a.URL))
+		}
+		sb.WriteString("\n")
+	}
+
+	if k := results.Knowledge; k != nil {
+		sb.WriteString("Fiche: " + k.Title)
+		if k.Type != "" {
+			sb.WriteString(" (" + k.Type + ")")
+		}
+		sb.WriteString("\n")
+		if k.Description != "" {
+			sb.WriteString(fmt.Sprintf("   %s\n", k.Description))
+		}
+		for _, f := range k.Facts {
+			sb.WriteString(fmt.Sprintf("   %s : %s\n", f.Label, f.Value))
+		}
+		if k.URL != "" {
+			sb.WriteString(fmt.Sprintf("   [%s]\n", k.URL))
+		}
+		sb.WriteString("\n")
+	}
+
+	for i, item := range results.Items {
+		if i >= maxFormattedResults {
+			break
+		}
+		// Numérotés comme les sources, pour que le modèle puisse les citer
+		sb.WriteString(fmt.Sprintf("[%d] %s\n", i+1, item.Title))
+		if meta := joinNonEmpty(" – ", item.Source, item.Date); meta != "" {
+			sb.WriteString(fmt.Sprintf("   %s\n", meta))
+		}
+		if item.Snippet != "" {
+			sb.WriteString(fmt.Sprintf("   %s\n", item.Snippet))
+		}
+		sb.WriteString(fmt.Sprintf("   [%s]\n\n", item.URL))
+	}
+
+	return sb.String()
+}
+
+// joinNonEmpty joint les valeurs non vides
+func joinNonEmpty(sep string, values ...string) string {
+	var kept []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			kept = append(kept, v)
+		}
+	}
+	return strings.Join(kept, sep)
+}
+
+// hostOf retourne le nom d'hôte d'une adresse, sans le préfixe www.
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(u.Hostname(), "www.")
+}
+
+// rankItems numérote les résultats dont le rang est inconnu, dans l'ordre reçu
+func rankItems(items []Result) {
+	for i := range items {
+		if items[i].Rank == 0 {
+			items[i].Rank = i + 1
+		}
+	}
+}
+
+// tagPattern reconnaît une balise HTML
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// htmlText retourne le texte d'un fragment HTML : balises retirées, entités
+// décodées et espaces normalisés
+func htmlText(fragment string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(tagPattern.ReplaceAllString(fragment, ""))), " ")
+}
@@ -2,157 +2,144 @@ package search
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
 	"strings"
+	"time"
 )
 
-// WebSearcher gère les recherches sur Internet
-type WebSearcher struct {
-	apiKey string
-	engine string // "google", "bing", etc.
-}
+// Searcher est un moteur de recherche sur Internet
+type Searcher interface {
+	// Name retourne le nom du moteur
+	Name() string
 
-// NewWebSearcher crée un nouveau WebSearcher
-func NewWebSearcher(apiKey, engine string) *WebSearcher {
-	if engine == "" {
-		engine = "google"
-	}
-	return &WebSearcher{
-		apiKey: apiKey,
-		engine: engine,
-	}
+	// Search effectue une recherche et retourne les résultats normalisés
+	Search(ctx context.Context, query string) (*Results, error)
 }
 
-// Search effectue une recherche sur Internet
-func (w *WebSearcher) Search(ctx context.Context, query string) (*SearchResults, error) {
-	// Vérification des dépendances
-	if w.apiKey == "" {
-		return nil, fmt.Errorf("clé API de recherche non configurée")
-	}
+// Noms des moteurs de recherche pris en charge
+const (
+	BackendSerpAPI    = "serpapi"
+	BackendGoogleCSE  = "google"
+	BackendSearXNG    = "searxng"
+	BackendBrave      = "brave"
+	BackendDuckDuckGo = "duckduckgo"
+)
 
-	// Utilisation de SerpAPI qui supporte plusieurs moteurs de recherche
-	params := url.Values{}
-	params.Set("q", query)
-	params.Set("api_key", w.apiKey)
-	params.Set("engine", w.engine) // Utiliser le moteur configuré
-	params.Set("num", "10")
+// maxResults est le nombre de résultats demandé aux moteurs
+const maxResults = 10
 
-	// Construction de l'URL (SerpAPI)
-	endpoint := "https://serpapi.com/search?" + params.Encode()
+// maxResponseSize limite la taille d'une page de résultats lue
+const maxResponseSize = 5 * 1024 * 1024
 
-	// Création et envoi de la requête
-	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
-	if err != nil {
-		return nil, fmt.Errorf("erreur lors de la création de la requête: %w", err)
-	}
+// httpClient est le client HTTP partagé par les moteurs
+var httpClient = &http.Client{Timeout: 30 * time.Second}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("erreur lors de l'envoi de la requête: %w", err)
-	}
-	defer resp.Body.Close()
+// Config décrit le moteur de recherche à utiliser
+type Config struct {
+	// Moteur : serpapi, google, searxng, brave ou duckduckgo
+	Backend string
 
-	// Lecture de la réponse
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("erreur lors de la lecture de la réponse: %w", err)
-	}
+	// Clé d'API (SerpAPI, Google, Brave)
+	APIKey string
 
-	// Vérification du statut
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("erreur de recherche: %s (code: %d)", resp.Status, resp.StatusCode)
-	}
+	// Moteur interrogé par SerpAPI ("google" par défaut)
+	Engine string
 
-	// Désérialisation de la réponse
-	var searchResp SearchResults
-	if err := json.Unmarshal(body, &searchResp); err != nil {
-		return nil, fmt.Errorf("erreur lors de la désérialisation de la réponse: %w", err)
-	}
+	// Identifiant du moteur Google Custom Search (cx)
+	CX string
 
-	return &searchResp, nil
+	// Adresse du service, pour remplacer celle par défaut ; obligatoire pour
+	// SearXNG, dont l'instance est auto-hébergée
+	Endpoint string
 }
 
-// SearchResults représente la réponse de la recherche
-type SearchResults struct {
-	Kind string `json:"kind"`
-	URL  struct {
-		Type     string `json:"type"`
-		Template string `json:"template"`
-	} `json:"url"`
-	Queries struct {
-		NextPage []struct {
-			Title           string `json:"title"`
-			TotalResults    string `json:"totalResults"`
-			SearchTerms     string `json:"searchTerms"`
-			Count           int    `json:"count"`
-			SearchTime      string `json:"searchTime"`
-			InputEncoding   string `json:"inputEncoding"`
-			OutputEncoding  string `json:"outputEncoding"`
-			Safe            string `json:"safe"`
-			Cx              string `json:"cx"`
-			SearchType      string `json:"searchType"`
-			StartIndex      int    `json:"startIndex"`
-			SearchIntervals []int  `json:"searchIntervals"`
-		} `json:"nextPage"`
-		Request []struct {
-			Title        string `json:"title"`
-			TotalResults string `json:"totalResults"`
-			SearchTerms  string `json:"searchTerms"`
-			Count        int    `json:"count"`
-			SearchType   string `json:"searchType"`
-			Safe         string `json:"safe"`
-			Cx           string `json:"cx"`
-		} `json:"request"`
-	} `json:"queries"`
-	Context struct {
-		Title string `json:"title"`
-	} `json:"context"`
-	SearchInformation struct {
-		SearchTime            float64 `json:"searchTime"`
-		FormattedSearchTime   string  `json:"formattedSearchTime"`
-		TotalResults          string  `json:"totalResults"`
-		FormattedTotalResults string  `json:"formattedTotalResults"`
-	} `json:"searchInformation"`
-	Items []SearchItem `json:"items"`
+// New crée le moteur de recherche décrit par la configuration
+func New(cfg Config) (Searcher, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Backend)) {
+	case BackendSerpAPI:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("clé API de recherche non configurée pour SerpAPI")
+		}
+		s := NewSerpAPISearcher(cfg.APIKey, cfg.Engine)
+		if cfg.Endpoint != "" {
+			s.Endpoint = cfg.Endpoint
+		}
+		return s, nil
+	case BackendGoogleCSE:
+		if cfg.APIKey == "" || cfg.CX == "" {
+			return nil, fmt.Errorf("clé API et identifiant de moteur (cx) requis pour Google Custom Search")
+		}
+		s := NewGoogleCSESearcher(cfg.APIKey, cfg.CX)
+		if cfg.Endpoint != "" {
+			s.Endpoint = cfg.Endpoint
+		}
+		return s, nil
+	case BackendSearXNG:
+		if cfg.Endpoint == "" {
+			return nil, fmt.Errorf("adresse de l'instance SearXNG non configurée")
+		}
+		return NewSearXNGSearcher(cfg.Endpoint), nil
+	case BackendBrave:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("clé API de recherche non configurée pour Brave Search")
+		}
+		s := NewBraveSearcher(cfg.APIKey)
+		if cfg.Endpoint != "" {
+			s.Endpoint = cfg.Endpoint
+		}
+		return s, nil
+	case BackendDuckDuckGo:
+		s := NewDuckDuckGoSearcher()
+		if cfg.Endpoint != "" {
+			s.Endpoint = cfg.Endpoint
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("moteur de recherche inconnu: %s (%s, %s, %s, %s, %s)", cfg.Backend,
+			BackendSerpAPI, BackendGoogleCSE, BackendSearXNG, BackendBrave, BackendDuckDuckGo)
+	}
 }
 
-// SearchItem représente un résultat de recherche
-type SearchItem struct {
-	Kind          string `json:"kind"`
-	Title         string `json:"title"`
-	HTMLTitle     string `json:"htmlTitle"`
-	Link          string `json:"link"`
-	DisplayLink   string `json:"displayLink"`
-	Snippet       string `json:"snippet"`
-	HTMLSnippet   string `json:"htmlSnippet"`
-	CacheID       string `json:"cacheId"`
-	CachedPageURL string `json:"cachedPageUrl"`
-	FormattedURL  string `json:"formattedUrl"`
-}
+// get envoie une requête GET et retourne le code de statut et le corps de la réponse
+func get(ctx context.Context, endpoint string, headers map[string]string) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return 0, nil, fmt.Errorf("erreur lors de la création de la requête: %w", err)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
 
-// FormatSearchResults formate les résultats de recherche en texte
-func FormatSearchResults(results *SearchResults) string {
-	if results == nil || len(results.Items) == 0 {
-		return "Aucun résultat trouvé pour cette recherche."
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("erreur lors de l'envoi de la requête: %w", err)
 	}
+	defer resp.Body.Close()
 
-	var sb strings.Builder
-	sb.WriteString("Résultats de recherche:\n")
-	sb.WriteString(fmt.Sprintf("Temps de recherche: %.2f secondes\n", results.SearchInformation.SearchTime))
-	sb.WriteString(fmt.Sprintf("Résultats totaux: %s\n\n", results.SearchInformation.FormattedTotalResults))
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return 0, nil, fmt.Errorf("erreur lors de la lecture de la réponse: %w", err)
+	}
+	return resp.StatusCode, body, nil
+}
 
-	for i, item := range results.Items {
-		if i >= 5 { // Limiter à 5 résultats
-			break
+// decodeResponse décode une réponse JSON ; les erreurs décrites par le moteur
+// sont plus explicites que le seul code de statut
+func decodeResponse(status int, body []byte, query string, decode func([]byte) (*Results, error)) (*Results, error) {
+	results, err := decode(body)
+	if err != nil {
+		if status != http.StatusOK {
+			return nil, fmt.Errorf("%v (code: %d)", err, status)
 		}
-		sb.WriteString(fmt.Sprintf("%d. %s\n", i+1, item.Title))
-		sb.WriteString(fmt.Sprintf("   %s\n", item.Snippet))
-		sb.WriteString(fmt.Sprintf("   [%s]\n\n", item.Link))
+		return nil, err
 	}
-
-	return sb.String()
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("erreur de recherche: %s (code: %d)", http.StatusText(status), status)
+	}
+	if results.Query == "" {
+		results.Query = query
+	}
+	return results, nil
 }
@@ -0,0 +1,122 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// SearXNGSearcher interroge une instance SearXNG auto-hébergée (format JSON,
+// qui doit être autorisé dans la section search.formats de sa configuration)
+type SearXNGSearcher struct {
+	Endpoint string
+}
+
+// NewSearXNGSearcher crée un moteur SearXNG ; baseURL est l'adresse de l'instance
+func NewSearXNGSearcher(baseURL string) *SearXNGSearcher {
+	return &SearXNGSearcher{Endpoint: strings.TrimSuffix(baseURL, "/")}
+}
+
+// Name implémente Searcher
+func (s *SearXNGSearcher) Name() string {
+	return BackendSearXNG
+}
+
+// Search implémente Searcher
+func (s *SearXNGSearcher) Search(ctx context.Context, query string) (*Results, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("format", "json")
+
+	status, body, err := get(ctx, s.Endpoint+"/search?"+params.Encode(), map[string]string{"Accept": "application/json"})
+	if err != nil {
+		return nil, err
+	}
+	if status == 403 {
+		return nil, fmt.Errorf("l'instance SearXNG refuse le format JSON (activez-le dans search.formats)")
+	}
+	return decodeResponse(status, body, query, DecodeSearXNG)
+}
+
+// searXNGResponse représente une réponse JSON de SearXNG
+type searXNGResponse struct {
+	Query           string          `json:"query"`
+	NumberOfResults float64         `json:"number_of_results"`
+	Answers         json.RawMessage `json:"answers"`
+	Infoboxes       []struct {
+		Infobox    string `json:"infobox"`
+		Content    string `json:"content"`
+		Engine     string `json:"engine"`
+		Attributes []struct {
+			Label string `json:"label"`
+			Value string `json:"value"`
+		} `json:"attributes"`
+		URLs []struct {
+			Title string `json:"title"`
+			URL   string `json:"url"`
+		} `json:"urls"`
+	} `json:"infoboxes"`
+	Results []struct {
+		Title         string `json:"title"`
+		URL           string `json:"url"`
+		Content       string `json:"content"`
+		Engine        string `json:"engine"`
+		PublishedDate string `json:"publishedDate"`
+	} `json:"results"`
+}
+
+// DecodeSearXNG décode une réponse JSON de SearXNG
+func DecodeSearXNG(body []byte) (*Results, error) {
+	var resp searXNGResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("erreur lors de la désérialisation de la réponse: %w", err)
+	}
+
+	results := &Results{
+		Query:        resp.Query,
+		Backend:      BackendSearXNG,
+		TotalResults: int64(resp.NumberOfResults),
+	}
+	for _, r := range resp.Results {
+		results.Items = append(results.Items, Result{
+			Title:   r.Title,
+			URL:     r.URL,
+			Snippet: r.Content,
+			Source:  hostOf(r.URL),
+			Date:    strings.SplitN(r.PublishedDate, "T", 2)[0],
+		})
+	}
+	rankItems(results.Items)
+
+	// Les réponses sont des chaînes ou, selon la version, des objets {answer, url}
+	var answers []json.RawMessage
+	if json.Unmarshal(resp.Answers, &answers) == nil && len(answers) > 0 {
+		var text string
+		var answer struct {
+			Answer string `json:"answer"`
+			URL    string `json:"url"`
+		}
+		if json.Unmarshal(answers[0], &text) == nil && text != "" {
+			results.Answer = &Answer{Text: text}
+		} else if json.Unmarshal(answers[0], &answer) == nil && answer.Answer != "" {
+			results.Answer = &Answer{Text: answer.Answer, URL: answer.URL, Source: hostOf(answer.URL)}
+		}
+	}
+
+	if len(resp.Infoboxes) > 0 {
+		box := resp.Infoboxes[0]
+		k := &Knowledge{Title: box.Infobox, Description: box.Content, Source: box.Engine}
+		for _, attr := range box.Attributes {
+			k.Facts = append(k.Facts, Fact{Label: attr.Label, Value: attr.Value})
+		}
+		if len(box.URLs) > 0 {
+			k.URL = box.URLs[0].URL
+		}
+		if k.Title != "" {
+			results.Knowledge = k
+		}
+	}
+	return results, nil
+}
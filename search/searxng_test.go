@@ -0,0 +1,105 @@
+package search
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSearXNGSearch(t *testing.T) {
+	srv, got := newSearchServer(t, http.StatusOK, "application/json", readFixture(t, "searxng.json"))
+
+	// La barre finale de l'adresse de l'instance est ignorée
+	results, err := NewSearXNGSearcher(srv.URL+"/").Search(context.Background(), "tour eiffel")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.path != "/search" || got.query["q"] != "tour eiffel" || got.query["format"] != "json" {
+		t.Errorf("requête %s %v", got.path, got.query)
+	}
+	if accept := got.header.Get("Accept"); accept != "application/json" {
+		t.Errorf("Accept = %q", accept)
+	}
+
+	if results.Query != "tour eiffel" || results.Backend != BackendSearXNG || results.TotalResults != 412000 {
+		t.Errorf("résultats = %+v", results)
+	}
+	want := []Result{
+		{Title: "Tour Eiffel — Wikipédia", URL: "https://fr.wikipedia.org/wiki/Tour_Eiffel",
+			Snippet: "La tour Eiffel est une tour de fer puddlé de 330 m de hauteur située à Paris.",
+			Source:  "fr.wikipedia.org", Rank: 1},
+		// Seul le jour de la date de publication est conservé
+		{Title: "Site officiel de la tour Eiffel", URL: "https://www.toureiffel.paris/fr",
+			Snippet: "Billets, horaires et informations pratiques.", Source: "toureiffel.paris", Date: "2024-04-18", Rank: 2},
+		{Title: "Histoire de la tour", URL: "https://www.example.com/eiffel", Source: "example.com", Rank: 3},
+	}
+	if len(results.Items) != len(want) {
+		t.Fatalf("%d résultats, %d attendus", len(results.Items), len(want))
+	}
+	for i, w := range want {
+		if results.Items[i] != w {
+			t.Errorf("résultat %d :\n  obtenu  %+v\n  attendu %+v", i+1, results.Items[i], w)
+		}
+	}
+
+	wantAnswer := Answer{Text: "330 m", URL: "https://fr.wikipedia.org/wiki/Tour_Eiffel", Source: "fr.wikipedia.org"}
+	if results.Answer == nil || *results.Answer != wantAnswer {
+		t.Errorf("réponse directe = %+v", results.Answer)
+	}
+
+	k := results.Knowledge
+	if k == nil || k.Title != "Tour Eiffel" || k.Source != "wikidata" || k.URL != "https://www.toureiffel.paris/" {
+		t.Fatalf("fiche = %+v", k)
+	}
+	if len(k.Facts) != 2 || k.Facts[0] != (Fact{Label: "Hauteur", Value: "330 m"}) {
+		t.Errorf("propriétés = %+v", k.Facts)
+	}
+}
+
+func TestDecodeSearXNGAnswerFormats(t *testing.T) {
+	// Les anciennes versions renvoient les réponses sous forme de chaînes
+	results, err := DecodeSearXNG([]byte(`{"query": "2+2", "answers": ["4"], "results": []}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results.Answer == nil || results.Answer.Text != "4" || results.Answer.URL != "" {
+		t.Errorf("réponse directe = %+v", results.Answer)
+	}
+
+	// Champs absents : ni réponse ni fiche, et une fiche sans titre est ignorée
+	results, err = DecodeSearXNG([]byte(`{"results": [{"url": "https://a.example"}], "infoboxes": [{"content": "sans titre"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results.Answer != nil || results.Knowledge != nil || len(results.Items) != 1 || results.Items[0].Rank != 1 {
+		t.Errorf("résultats = %+v", results)
+	}
+}
+
+func TestSearXNGErrors(t *testing.T) {
+	t.Run("format JSON désactivé", func(t *testing.T) {
+		srv, _ := newSearchServer(t, http.StatusForbidden, "text/html", []byte("<html><body>403 Forbidden</body></html>"))
+		_, err := NewSearXNGSearcher(srv.URL).Search(context.Background(), "q")
+		if err == nil || !strings.Contains(err.Error(), "search.formats") {
+			t.Errorf("erreur = %v", err)
+		}
+	})
+
+	t.Run("limite de requêtes", func(t *testing.T) {
+		srv, _ := newSearchServer(t, http.StatusTooManyRequests, "text/plain", []byte("Too Many Requests"))
+		_, err := NewSearXNGSearcher(srv.URL).Search(context.Background(), "q")
+		if err == nil || !strings.Contains(err.Error(), "code: 429") {
+			t.Errorf("erreur = %v", err)
+		}
+	})
+
+	t.Run("erreur serveur avec corps JSON", func(t *testing.T) {
+		srv, _ := newSearchServer(t, http.StatusInternalServerError, "application/json", []byte(`{"results": []}`))
+		_, err := NewSearXNGSearcher(srv.URL).Search(context.Background(), "q")
+		if err == nil || !strings.Contains(err.Error(), "code: 500") {
+			t.Errorf("erreur = %v", err)
+		}
+	})
+}
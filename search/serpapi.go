@@ -0,0 +1,246 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// serpAPIEndpoint est l'adresse de l'API SerpAPI
+const serpAPIEndpoint = "https://serpapi.com/search"
+
+// SerpAPISearcher interroge SerpAPI, qui relaie plusieurs moteurs (Google, Bing...)
+type SerpAPISearcher struct {
+	APIKey   string
+	Engine   string
+	Endpoint string
+}
+
+// NewSerpAPISearcher crée un moteur SerpAPI ; engine vaut "google" par défaut
+func NewSerpAPISearcher(apiKey, engine string) *SerpAPISearcher {
+	if engine == "" {
+		engine = "google"
+	}
+	return &SerpAPISearcher{APIKey: apiKey, Engine: engine, Endpoint: serpAPIEndpoint}
+}
+
+// Name implémente Searcher
+func (s *SerpAPISearcher) Name() string {
+	return BackendSerpAPI
+}
+
+// Search implémente Searcher
+func (s *SerpAPISearcher) Search(ctx context.Context, query string) (*Results, error) {
+	if s.APIKey == "" {
+		return nil, fmt.Errorf("clé API de recherche non configurée")
+	}
+
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("api_key", s.APIKey)
+	params.Set("engine", s.Engine)
+	params.Set("num", strconv.Itoa(maxResults))
+
+	status, body, err := get(ctx, s.Endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return decodeResponse(status, body, query, DecodeSerpAPI)
+}
+
+// serpAPIResponse représente les parties exploitées d'une réponse SerpAPI
+type serpAPIResponse struct {
+	Error             string `json:"error"`
+	SearchInformation struct {
+		TotalResults       int64   `json:"total_results"`
+		TimeTakenDisplayed float64 `json:"time_taken_displayed"`
+	} `json:"search_information"`
+	SearchMetadata struct {
+		TotalTimeTaken float64 `json:"total_time_taken"`
+	} `json:"search_metadata"`
+	SearchParameters struct {
+		Q string `json:"q"`
+	} `json:"search_parameters"`
+	AnswerBox      *serpAPIAnswerBox          `json:"answer_box"`
+	KnowledgeGraph map[string]json.RawMessage `json:"knowledge_graph"`
+	OrganicResults []struct {
+		Position      int    `json:"position"`
+		Title         string `json:"title"`
+		Link          string `json:"link"`
+		DisplayedLink string `json:"displayed_link"`
+		Snippet       string `json:"snippet"`
+		Source        string `json:"source"`
+		Date          string `json:"date"`
+	} `json:"organic_results"`
+}
+
+// serpAPIAnswerBox représente l'encadré de réponse directe, dont la forme varie
+// selon son type (définition, calcul, extrait de page, liste...)
+type serpAPIAnswerBox struct {
+	Type                    string          `json:"type"`
+	Title                   string          `json:"title"`
+	Answer                  string          `json:"answer"`
+	Result                  string          `json:"result"`
+	Snippet                 string          `json:"snippet"`
+	SnippetHighlightedWords []string        `json:"snippet_highlighted_words"`
+	List                    []string        `json:"list"`
+	Definitions             []string        `json:"definitions"`
+	Link                    string          `json:"link"`
+	DisplayedLink           string          `json:"displayed_link"`
+	Source                  json.RawMessage `json:"source"`
+}
+
+// noResultsMessage est le début du message d'erreur de SerpAPI pour une recherche sans résultat
+const noResultsMessage = "hasn't returned any results"
+
+// DecodeSerpAPI décode une réponse SerpAPI (résultats naturels, réponse directe
+// et fiche de connaissances)
+func DecodeSerpAPI(body []byte) (*Results, error) {
+	var resp serpAPIResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("erreur lors de la désérialisation de la réponse: %w", err)
+	}
+
+	results := &Results{
+		Query:        resp.SearchParameters.Q,
+		Backend:      BackendSerpAPI,
+		TotalResults: resp.SearchInformation.TotalResults,
+		SearchTime:   resp.SearchInformation.TimeTakenDisplayed,
+	}
+	if resp.Error != "" {
+		// Une recherche sans résultat n'est pas une erreur
+		if strings.Contains(resp.Error, noResultsMessage) {
+			return results, nil
+		}
+		return nil, fmt.Errorf("erreur SerpAPI: %s", resp.Error)
+	}
+	if results.SearchTime == 0 {
+		results.SearchTime = resp.SearchMetadata.TotalTimeTaken
+	}
+
+	for _, r := range resp.OrganicResults {
+		source := r.Source
+		if source == "" {
+			source = hostOf(r.Link)
+		}
+		results.Items = append(results.Items, Result{
+			Title:   r.Title,
+			URL:     r.Link,
+			Snippet: r.Snippet,
+			Source:  source,
+			Date:    r.Date,
+			Rank:    r.Position,
+		})
+	}
+	rankItems(results.Items)
+
+	results.Answer = resp.AnswerBox.answer()
+	results.Knowledge = decodeKnowledgeGraph(resp.KnowledgeGraph)
+	return results, nil
+}
+
+// answer normalise l'encadré de réponse directe ; nil s'il ne contient aucun texte
+func (b *serpAPIAnswerBox) answer() *Answer {
+	if b == nil {
+		return nil
+	}
+
+	var text string
+	switch {
+	case b.Answer != "":
+		text = b.Answer
+	case b.Result != "":
+		text = b.Result
+	case b.Snippet != "":
+		text = b.Snippet
+	case len(b.Definitions) > 0:
+		text = strings.Join(b.Definitions, " ; ")
+	case len(b.List) > 0:
+		text = strings.Join(b.List, " ; ")
+	}
+	if text == "" && len(b.SnippetHighlightedWords) > 0 {
+		text = strings.Join(b.SnippetHighlightedWords, ", ")
+	}
+	if text == "" {
+		return nil
+	}
+
+	source := hostOf(b.Link)
+	var named struct {
+		Name string `json:"name"`
+	}
+	if json.Unmarshal(b.Source, &named) == nil && named.Name != "" {
+		source = named.Name
+	}
+	return &Answer{Title: b.Title, Text: text, URL: b.Link, Source: source}
+}
+
+// knowledgeSkipped liste les champs de la fiche de connaissances qui ne sont pas
+// des propriétés de l'entité (identifiants, images, liens techniques)
+var knowledgeSkipped = map[string]bool{
+	"title": true, "type": true, "description": true, "source": true, "website": true,
+	"kgmid": true, "knowledge_graph_search_link": true, "serpapi_knowledge_graph_search_link": true,
+	"entity_type": true, "image": true, "thumbnail": true, "header_images": true,
+}
+
+// decodeKnowledgeGraph normalise la fiche de connaissances. Les propriétés de
+// l'entité (fondation, siège, etc.) sont les champs textuels restants.
+func decodeKnowledgeGraph(kg map[string]json.RawMessage) *Knowledge {
+	if len(kg) == 0 {
+		return nil
+	}
+
+	str := func(key string) string {
+		var s string
+		json.Unmarshal(kg[key], &s)
+		return s
+	}
+
+	k := &Knowledge{
+		Title:       str("title"),
+		Type:        str("type"),
+		Description: str("description"),
+		URL:         str("website"),
+	}
+	var source struct {
+		Name string `json:"name"`
+		Link string `json:"link"`
+	}
+	if json.Unmarshal(kg["source"], &source) == nil {
+		k.Source = source.Name
+		if k.URL == "" {
+			k.URL = source.Link
+		}
+	}
+	if k.Title == "" {
+		return nil
+	}
+
+	keys := make([]string, 0, len(kg))
+	for key := range kg {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if knowledgeSkipped[key] || strings.HasSuffix(key, "link") || strings.HasSuffix(key, "links") {
+			continue
+		}
+		if value := str(key); value != "" {
+			k.Facts = append(k.Facts, Fact{Label: factLabel(key), Value: value})
+		}
+	}
+	return k
+}
+
+// factLabel transforme une clé ("date_of_birth") en libellé ("Date of birth")
+func factLabel(key string) string {
+	label := strings.ReplaceAll(key, "_", " ")
+	if label == "" {
+		return label
+	}
+	return strings.ToUpper(label[:1]) + label[1:]
+}
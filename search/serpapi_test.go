@@ -0,0 +1,227 @@
+package search
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeSerpAPIOrganic(t *testing.T) {
+	results, err := DecodeSerpAPI(readFixture(t, "serpapi_organic.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if results.Query != "golang context cancel" || results.Backend != BackendSerpAPI {
+		t.Errorf("requête %q, moteur %q", results.Query, results.Backend)
+	}
+	if results.TotalResults != 2340000 || results.SearchTime != 0.38 {
+		t.Errorf("total %d, durée %v", results.TotalResults, results.SearchTime)
+	}
+	if results.Answer != nil || results.Knowledge != nil {
+		t.Errorf("ni réponse directe ni fiche attendues : %+v %+v", results.Answer, results.Knowledge)
+	}
+	if len(results.Items) != 3 {
+		t.Fatalf("%d résultats, 3 attendus", len(results.Items))
+	}
+
+	want := []Result{
+		{Title: "context package - context - Go Packages", URL: "https://pkg.go.dev/context",
+			Snippet: "Package context defines the Context type, which carries deadlines, cancellation signals, and other request-scoped values.",
+			Source:  "Go Packages", Rank: 1},
+		// Sans source, le nom d'hôte en tient lieu
+		{Title: "Go Concurrency Patterns: Context - The Go Programming Language", URL: "https://go.dev/blog/context",
+			Snippet: "In Go servers, each incoming request is handled in its own goroutine.",
+			Source:  "go.dev", Date: "29 juil. 2014", Rank: 2},
+		{Title: "How to cancel a context in Go", URL: "https://www.example.org/articles/go-context-cancel",
+			Source: "example.org", Rank: 3},
+	}
+	for i, w := range want {
+		if results.Items[i] != w {
+			t.Errorf("résultat %d :\n  obtenu  %+v\n  attendu %+v", i+1, results.Items[i], w)
+		}
+	}
+}
+
+func TestDecodeSerpAPIAnswerBox(t *testing.T) {
+	results, err := DecodeSerpAPI(readFixture(t, "serpapi_answer_box.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Answer{
+		Title:  "Tour Eiffel — Wikipédia",
+		Text:   "330 m",
+		URL:    "https://fr.wikipedia.org/wiki/Tour_Eiffel",
+		Source: "Wikipédia",
+	}
+	if results.Answer == nil || *results.Answer != want {
+		t.Errorf("réponse directe :\n  obtenue  %+v\n  attendue %+v", results.Answer, want)
+	}
+	if len(results.Items) != 1 {
+		t.Errorf("%d résultats, 1 attendu", len(results.Items))
+	}
+	// Sans durée affichée, la durée totale de la recherche est retenue
+	if results.SearchTime != 0.91 {
+		t.Errorf("durée = %v", results.SearchTime)
+	}
+}
+
+func TestSerpAPIAnswerVariants(t *testing.T) {
+	tests := []struct {
+		name string
+		box  string
+		want *Answer
+	}{
+		{
+			name: "extrait seul",
+			box:  `{"title": "Définition", "snippet": "Un goroutine est un fil d'exécution léger.", "link": "https://www.example.com/go"}`,
+			want: &Answer{Title: "Définition", Text: "Un goroutine est un fil d'exécution léger.", URL: "https://www.example.com/go", Source: "example.com"},
+		},
+		{
+			name: "définitions",
+			box:  `{"type": "dictionary_results", "definitions": ["premier sens", "second sens"]}`,
+			want: &Answer{Text: "premier sens ; second sens"},
+		},
+		{
+			name: "liste",
+			box:  `{"list": ["un", "deux"], "source": "Exemple"}`,
+			want: &Answer{Text: "un ; deux"},
+		},
+		{
+			name: "mots surlignés seuls",
+			box:  `{"snippet_highlighted_words": ["1889", "Gustave Eiffel"]}`,
+			want: &Answer{Text: "1889, Gustave Eiffel"},
+		},
+		{
+			name: "encadré sans texte",
+			box:  `{"type": "weather_result", "link": "https://meteo.example.com"}`,
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := DecodeSerpAPI([]byte(`{"answer_box": ` + tt.box + `}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch {
+			case tt.want == nil && results.Answer != nil:
+				t.Errorf("aucune réponse attendue, obtenu %+v", results.Answer)
+			case tt.want != nil && (results.Answer == nil || *results.Answer != *tt.want):
+				t.Errorf("obtenu %+v, attendu %+v", results.Answer, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeSerpAPIKnowledgeGraph(t *testing.T) {
+	results, err := DecodeSerpAPI(readFixture(t, "serpapi_knowledge_graph.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k := results.Knowledge
+	if k == nil {
+		t.Fatal("fiche de connaissances manquante")
+	}
+	if k.Title != "Mozilla Foundation" || k.Type != "Organisation à but non lucratif" || k.Source != "Wikipédia" {
+		t.Errorf("fiche = %+v", k)
+	}
+	if k.URL != "https://foundation.mozilla.org/" {
+		t.Errorf("adresse = %q (le site officiel prime sur la source)", k.URL)
+	}
+	if !strings.HasPrefix(k.Description, "La Mozilla Foundation") {
+		t.Errorf("description = %q", k.Description)
+	}
+
+	// Identifiants, liens, images et valeurs non textuelles sont écartés ; les
+	// propriétés sont triées par clé
+	want := []Fact{
+		{Label: "Founded", Value: "15 juillet 2003"},
+		{Label: "Headquarters", Value: "Mountain View, Californie, États-Unis"},
+	}
+	if len(k.Facts) != len(want) {
+		t.Fatalf("propriétés = %+v", k.Facts)
+	}
+	for i := range want {
+		if k.Facts[i] != want[i] {
+			t.Errorf("propriété %d = %+v, %+v attendue", i, k.Facts[i], want[i])
+		}
+	}
+}
+
+func TestDecodeSerpAPIMissingFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		check func(t *testing.T, r *Results)
+	}{
+		{
+			name: "réponse vide",
+			body: `{}`,
+			check: func(t *testing.T, r *Results) {
+				if !r.Empty() || r.Query != "" || r.TotalResults != 0 {
+					t.Errorf("résultats = %+v", r)
+				}
+			},
+		},
+		{
+			name: "résultat sans position ni lien",
+			body: `{"organic_results": [{"title": "Sans lien"}, {"title": "Second", "link": "https://b.example"}]}`,
+			check: func(t *testing.T, r *Results) {
+				if len(r.Items) != 2 || r.Items[0].Rank != 1 || r.Items[0].Source != "" ||
+					r.Items[1].Rank != 2 || r.Items[1].Source != "b.example" {
+					t.Errorf("résultats = %+v", r.Items)
+				}
+			},
+		},
+		{
+			name: "fiche sans titre",
+			body: `{"knowledge_graph": {"type": "Ville", "description": "Une ville."}}`,
+			check: func(t *testing.T, r *Results) {
+				if r.Knowledge != nil {
+					t.Errorf("fiche inattendue : %+v", r.Knowledge)
+				}
+			},
+		},
+		{
+			name: "fiche sans site officiel",
+			body: `{"knowledge_graph": {"title": "Lyon", "source": {"name": "Wikipédia", "link": "https://fr.wikipedia.org/wiki/Lyon"}}}`,
+			check: func(t *testing.T, r *Results) {
+				if r.Knowledge == nil || r.Knowledge.URL != "https://fr.wikipedia.org/wiki/Lyon" || len(r.Knowledge.Facts) != 0 {
+					t.Errorf("fiche = %+v", r.Knowledge)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := DecodeSerpAPI([]byte(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, results)
+		})
+	}
+}
+
+func TestDecodeSerpAPIErrors(t *testing.T) {
+	// Une recherche sans résultat n'est pas une erreur
+	results, err := DecodeSerpAPI(readFixture(t, "serpapi_no_results.json"))
+	if err != nil {
+		t.Fatalf("erreur inattendue : %v", err)
+	}
+	if !results.Empty() || results.Query != "xqzvj wplkq brmtf" {
+		t.Errorf("résultats = %+v", results)
+	}
+
+	if _, err := DecodeSerpAPI([]byte(`{"error": "Invalid API key. Your API key should be here: https://serpapi.com/manage-api-key"}`)); err == nil ||
+		!strings.Contains(err.Error(), "Invalid API key") {
+		t.Errorf("erreur = %v", err)
+	}
+	if _, err := DecodeSerpAPI([]byte(`<html>Bad gateway</html>`)); err == nil {
+		t.Error("une réponse qui n'est pas du JSON doit être une erreur")
+	}
+}
@@ -0,0 +1,194 @@
+package session
+
+import (
+	"asione-agent/types"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Session représente l'état persistant d'une session de l'agent
+type Session struct {
+	ID            string    `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	ConfirmPolicy string    `json:"confirm_policy,omitempty"`
+
+	// Titre de la session (première demande de l'utilisateur)
+	Title string `json:"title,omitempty"`
+
+	// Modèle utilisé lors du dernier échange
+	Model string `json:"model,omitempty"`
+
+	// Historique complet de la conversation
+	Messages []types.Message `json:"messages,omitempty"`
+}
+
+// New crée une nouvelle session avec un identifiant unique
+func New() *Session {
+	now := time.Now()
+	return &Session{
+		ID:        newID(now),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// Store gère le stockage des sessions sur disque (un fichier JSON par session)
+type Store struct {
+	dir string
+}
+
+// DefaultDir retourne le répertoire de stockage par défaut des sessions
+func DefaultDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = "/home/user"
+	}
+	return filepath.Join(homeDir, ".cline", "sessions")
+}
+
+// NewStore crée un nouveau stockage de sessions dans le répertoire donné
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("impossible de créer le répertoire des sessions: %w", err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Save enregistre la session ; l'écriture passe par un fichier temporaire
+// pour ne jamais laisser de session à moitié écrite en cas d'interruption
+func (s *Store) Save(sess *Session) error {
+	sess.UpdatedAt = time.Now()
+
+	data, err := json.MarshalIndent(sess, "", "  ")
+	if err != nil {
+		return fmt.Errorf("erreur lors de la sérialisation de la session: %w", err)
+	}
+
+	tmp := s.path(sess.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("erreur lors de l'écriture de la session: %w", err)
+	}
+	if err := os.Rename(tmp, s.path(sess.ID)); err != nil {
+		return fmt.Errorf("erreur lors de l'écriture de la session: %w", err)
+	}
+	return nil
+}
+
+// Load charge une session par son identifiant
+func (s *Store) Load(id string) (*Session, error) {
+	if !validID(id) {
+		return nil, fmt.Errorf("identifiant de session invalide: %s", id)
+	}
+	data, err := os.ReadFile(s.path(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("session introuvable: %s", id)
+		}
+		return nil, fmt.Errorf("erreur lors de la lecture de la session: %w", err)
+	}
+
+	var sess Session
+	if err := json.Unmarshal(data, &sess); err != nil {
+		return nil, fmt.Errorf("erreur lors de la désérialisation de la session: %w", err)
+	}
+	return &sess, nil
+}
+
+// List retourne les sessions enregistrées, de la plus récente à la plus ancienne.
+// Les fichiers illisibles sont ignorés.
+func (s *Store) List() ([]*Session, error) {
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("erreur lors de la lecture des sessions: %w", err)
+	}
+
+	sessions := make([]*Session, 0, len(files))
+	for _, file := range files {
+		sess, err := s.Load(strings.TrimSuffix(filepath.Base(file), ".json"))
+		if err != nil {
+			continue
+		}
+		sessions = append(sessions, sess)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].UpdatedAt.After(sessions[j].UpdatedAt)
+	})
+	return sessions, nil
+}
+
+// Find retourne la session désignée par son identifiant complet, par un préfixe
+// non ambigu de celui-ci, ou par "last" pour la plus récente
+func (s *Store) Find(ref string) (*Session, error) {
+	if validID(ref) {
+		if sess, err := s.Load(ref); err == nil {
+			return sess, nil
+		}
+	}
+
+	sessions, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	if ref == "last" {
+		if len(sessions) == 0 {
+			return nil, fmt.Errorf("aucune session enregistrée")
+		}
+		return sessions[0], nil
+	}
+
+	var found *Session
+	for _, sess := range sessions {
+		if strings.HasPrefix(sess.ID, ref) {
+			if found != nil {
+				return nil, fmt.Errorf("identifiant ambigu: %s", ref)
+			}
+			found = sess
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("session introuvable: %s", ref)
+	}
+	return found, nil
+}
+
+// Delete supprime une session
+func (s *Store) Delete(id string) error {
+	if !validID(id) {
+		return fmt.Errorf("identifiant de session invalide: %s", id)
+	}
+	if err := os.Remove(s.path(id)); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("session introuvable: %s", id)
+		}
+		return fmt.Errorf("erreur lors de la suppression de la session: %w", err)
+	}
+	return nil
+}
+
+// validID vérifie qu'un identifiant ne peut pas désigner un fichier hors du répertoire
+func validID(id string) bool {
+	return id != "" && id != "." && id != ".." && !strings.ContainsAny(id, `/\`)
+}
+
+// path retourne le chemin du fichier d'une session
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// newID génère un identifiant de session lisible et trié chronologiquement
+func newID(t time.Time) string {
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return t.Format("20060102-150405")
+	}
+	return t.Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
@@ -0,0 +1,223 @@
+package settings
+
+import (
+	"asione-agent/api"
+	"asione-agent/types"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Noms des profils prédéfinis
+const (
+	ProfilePrecise  = "precise"
+	ProfileBalanced = "balanced"
+	ProfileCreative = "creative"
+
+	// DefaultProfile reprend le comportement historique (température 0.7)
+	DefaultProfile = ProfileBalanced
+)
+
+// Profile est un jeu nommé de paramètres de génération
+type Profile struct {
+	Description string `json:"description,omitempty"`
+	api.ChatOptions
+}
+
+// builtinProfiles sont les profils disponibles sans configuration
+var builtinProfiles = map[string]Profile{
+	ProfilePrecise: {
+		Description: "réponses déterministes, pour les commandes shell",
+		ChatOptions: api.ChatOptions{Temperature: api.Float(0), TopP: api.Float(1)},
+	},
+	ProfileBalanced: {
+		Description: "usage général",
+		ChatOptions: api.ChatOptions{Temperature: api.Float(0.7)},
+	},
+	ProfileCreative: {
+		Description: "rédaction et idées, réponses plus variées",
+		ChatOptions: api.ChatOptions{Temperature: api.Float(1.0), TopP: api.Float(0.95), PresencePenalty: api.Float(0.3)},
+	},
+}
+
+// Params liste les paramètres modifiables d'un profil
+var Params = []string{
+	"temperature", "top_p", "max_tokens", "stop", "seed",
+	"presence_penalty", "frequency_penalty", "response_format",
+}
+
+// Lookup retourne le profil de ce nom : sa version personnalisée si elle existe,
+// sinon le profil prédéfini
+func (s *Settings) Lookup(name string) (Profile, bool) {
+	if p, ok := s.Profiles[name]; ok {
+		return p, true
+	}
+	p, ok := builtinProfiles[name]
+	return p, ok
+}
+
+// Customized indique si le profil a été personnalisé
+func (s *Settings) Customized(name string) bool {
+	_, ok := s.Profiles[name]
+	return ok
+}
+
+// Names retourne les noms de tous les profils disponibles, triés
+func (s *Settings) Names() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for name := range builtinProfiles {
+		seen[name] = true
+		names = append(names, name)
+	}
+	for name := range s.Profiles {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Active retourne le nom et les paramètres du profil actif
+func (s *Settings) Active() (string, Profile) {
+	p, ok := s.Lookup(s.Profile)
+	if !ok {
+		return DefaultProfile, builtinProfiles[DefaultProfile]
+	}
+	return s.Profile, p
+}
+
+// Use active un profil
+func (s *Settings) Use(name string) error {
+	if _, ok := s.Lookup(name); !ok {
+		return fmt.Errorf("profil inconnu: %s (%s)", name, strings.Join(s.Names(), ", "))
+	}
+	s.Profile = name
+	return nil
+}
+
+// SetParam modifie un paramètre d'un profil ; "off" rétablit la valeur par défaut
+// du fournisseur. Le profil modifié est enregistré comme profil personnalisé.
+func (s *Settings) SetParam(name, param, value string) error {
+	p, ok := s.Lookup(name)
+	if !ok {
+		return fmt.Errorf("profil inconnu: %s", name)
+	}
+
+	value = strings.TrimSpace(value)
+	clear := value == "off" || value == "default"
+	var err error
+	switch param {
+	case "temperature":
+		p.Temperature, err = parseFloat(value, clear, 0, 2)
+	case "top_p":
+		p.TopP, err = parseFloat(value, clear, 0, 1)
+	case "presence_penalty":
+		p.PresencePenalty, err = parseFloat(value, clear, -2, 2)
+	case "frequency_penalty":
+		p.FrequencyPenalty, err = parseFloat(value, clear, -2, 2)
+	case "max_tokens":
+		p.MaxTokens = 0
+		if !clear {
+			p.MaxTokens, err = strconv.Atoi(value)
+			if err == nil && p.MaxTokens <= 0 {
+				err = fmt.Errorf("doit être positif")
+			}
+		}
+	case "seed":
+		p.Seed = nil
+		if !clear {
+			var seed int
+			seed, err = strconv.Atoi(value)
+			p.Seed = api.Int(seed)
+		}
+	case "stop":
+		p.Stop = nil
+		if !clear {
+			for _, stop := range strings.Split(value, ",") {
+				if stop = strings.TrimSpace(stop); stop != "" {
+					p.Stop = append(p.Stop, stop)
+				}
+			}
+		}
+	case "response_format":
+		p.ResponseFormat = nil
+		if !clear {
+			if value != "text" && value != "json_object" {
+				err = fmt.Errorf("text ou json_object attendu")
+			}
+			p.ResponseFormat = &types.ResponseFormat{Type: value}
+		}
+	default:
+		return fmt.Errorf("paramètre inconnu: %s (%s)", param, strings.Join(Params, ", "))
+	}
+	if err != nil {
+		return fmt.Errorf("valeur invalide pour %s: %s (%v)", param, value, err)
+	}
+
+	if s.Profiles == nil {
+		s.Profiles = make(map[string]Profile)
+	}
+	s.Profiles[name] = p
+	return nil
+}
+
+// Reset supprime la personnalisation d'un profil. Un profil uniquement
+// personnalisé disparaît ; s'il était actif, le profil par défaut le remplace.
+func (s *Settings) Reset(name string) error {
+	if !s.Customized(name) {
+		return fmt.Errorf("le profil %s n'est pas personnalisé", name)
+	}
+	delete(s.Profiles, name)
+	if _, ok := s.Lookup(s.Profile); !ok {
+		s.Profile = DefaultProfile
+	}
+	return nil
+}
+
+// Summary décrit les paramètres renseignés d'un profil
+func (p Profile) Summary() string {
+	var parts []string
+	addFloat := func(name string, v *float64) {
+		if v != nil {
+			parts = append(parts, fmt.Sprintf("%s %g", name, *v))
+		}
+	}
+	addFloat("temperature", p.Temperature)
+	addFloat("top_p", p.TopP)
+	if p.MaxTokens > 0 {
+		parts = append(parts, fmt.Sprintf("max_tokens %d", p.MaxTokens))
+	}
+	if len(p.Stop) > 0 {
+		parts = append(parts, fmt.Sprintf("stop %q", p.Stop))
+	}
+	if p.Seed != nil {
+		parts = append(parts, fmt.Sprintf("seed %d", *p.Seed))
+	}
+	addFloat("presence_penalty", p.PresencePenalty)
+	addFloat("frequency_penalty", p.FrequencyPenalty)
+	if p.ResponseFormat != nil {
+		parts = append(parts, "response_format "+p.ResponseFormat.Type)
+	}
+	if len(parts) == 0 {
+		return "valeurs par défaut du fournisseur"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// parseFloat lit un paramètre flottant borné ; clear donne une valeur absente
+func parseFloat(value string, clear bool, min, max float64) (*float64, error) {
+	if clear {
+		return nil, nil
+	}
+	v, err := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+	if err != nil {
+		return nil, err
+	}
+	if v < min || v > max {
+		return nil, fmt.Errorf("entre %g et %g", min, max)
+	}
+	return api.Float(v), nil
+}
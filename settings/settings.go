@@ -0,0 +1,75 @@
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Settings représente les préférences persistantes de l'agent
+type Settings struct {
+	path string
+
+	// Profil de génération actif
+	Profile string `json:"profile,omitempty"`
+
+	// Profils personnalisés ou modifiés (remplacent les profils prédéfinis de même nom)
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+}
+
+// DefaultPath retourne le chemin par défaut du fichier de préférences
+func DefaultPath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = "/home/user"
+	}
+	return filepath.Join(homeDir, ".cline", "settings.json")
+}
+
+// Load charge les préférences depuis un fichier ; un fichier absent donne les
+// préférences par défaut
+func Load(path string) (*Settings, error) {
+	s := &Settings{path: path, Profile: DefaultProfile}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return s, fmt.Errorf("erreur lors de la lecture des préférences: %w", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return s, fmt.Errorf("erreur lors de la désérialisation des préférences: %w", err)
+	}
+	if _, ok := s.Lookup(s.Profile); !ok {
+		s.Profile = DefaultProfile
+	}
+	return s, nil
+}
+
+// Path retourne le chemin du fichier de préférences
+func (s *Settings) Path() string {
+	return s.path
+}
+
+// Save enregistre les préférences ; l'écriture passe par un fichier temporaire
+func (s *Settings) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("impossible de créer le répertoire des préférences: %w", err)
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("erreur lors de la sérialisation des préférences: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("erreur lors de l'écriture des préférences: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("erreur lors de l'écriture des préférences: %w", err)
+	}
+	return nil
+}
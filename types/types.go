@@ -1,9 +1,152 @@
 package types
 
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
 // Message représente un message dans une conversation
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	Name       string     `json:"name,omitempty"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+
+	// Contenu multimodal (texte et images). Lorsqu'il est renseigné, il est
+	// transmis à la place de Content, qui n'en garde que le texte.
+	Parts []ContentPart `json:"-"`
+
+	// Sources numérotées auxquelles renvoient les citations [n] d'une réponse
+	// fondée sur une recherche. Conservées dans l'historique de la session,
+	// elles ne sont jamais transmises au modèle.
+	Sources []Source `json:"sources,omitempty"`
+}
+
+// Source représente une source numérotée citée dans une réponse
+type Source struct {
+	Index int    `json:"index"`
+	Title string `json:"title,omitempty"`
+	URL   string `json:"url"`
+}
+
+// Types de parties de contenu
+const (
+	PartText  = "text"
+	PartImage = "image_url"
+)
+
+// ContentPart représente une partie d'un message multimodal
+type ContentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+// ImageURL représente une image, par son adresse ou en data URL base64
+type ImageURL struct {
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// NewMultipartMessage crée un message composé de plusieurs parties ; Content
+// reçoit le texte des parties textuelles
+func NewMultipartMessage(role string, parts []ContentPart) Message {
+	return Message{Role: role, Content: PartsText(parts), Parts: parts}
+}
+
+// PartsText retourne le texte des parties textuelles, séparées par une ligne vide
+func PartsText(parts []ContentPart) string {
+	var texts []string
+	for _, part := range parts {
+		if part.Type == PartText && part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+// Images retourne les images d'un message
+func (m Message) Images() []ImageURL {
+	var images []ImageURL
+	for _, part := range m.Parts {
+		if part.Type == PartImage && part.ImageURL != nil {
+			images = append(images, *part.ImageURL)
+		}
+	}
+	return images
+}
+
+// messageJSON est la forme sérialisée d'un Message, sans ses méthodes
+type messageJSON Message
+
+// MarshalJSON implémente json.Marshaler : un message multimodal est sérialisé
+// avec un tableau de parties dans "content", les autres avec une simple chaîne
+func (m Message) MarshalJSON() ([]byte, error) {
+	if len(m.Parts) == 0 {
+		return json.Marshal(messageJSON(m))
+	}
+	return json.Marshal(struct {
+		messageJSON
+		Content []ContentPart `json:"content"`
+	}{messageJSON(m), m.Parts})
+}
+
+// UnmarshalJSON implémente json.Unmarshaler : "content" peut être une chaîne,
+// null ou un tableau de parties
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		messageJSON
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*m = Message(raw.messageJSON)
+
+	content := bytes.TrimSpace(raw.Content)
+	switch {
+	case len(content) == 0 || string(content) == "null":
+	case content[0] == '[':
+		if err := json.Unmarshal(content, &m.Parts); err != nil {
+			return err
+		}
+		m.Content = PartsText(m.Parts)
+	default:
+		if err := json.Unmarshal(content, &m.Content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ToolCall représente un appel d'outil demandé par le modèle
+type ToolCall struct {
+	// Index n'est renseigné que dans les fragments de streaming
+	Index    *int         `json:"index,omitempty"`
+	ID       string       `json:"id,omitempty"`
+	Type     string       `json:"type,omitempty"`
+	Function FunctionCall `json:"function"`
+}
+
+// FunctionCall représente le nom et les arguments (JSON) d'une fonction appelée
+type FunctionCall struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments"`
+}
+
+// Tool représente un outil déclaré au modèle
+type Tool struct {
+	Type     string             `json:"type"`
+	Function FunctionDefinition `json:"function"`
+}
+
+// FunctionDefinition décrit une fonction appelable par le modèle (paramètres en JSON Schema)
+type FunctionDefinition struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
 }
 
 // ChatRequest représente la requête pour une complétion de chat
@@ -11,7 +154,40 @@ type ChatRequest struct {
 	Model       string    `json:"model"`
 	Messages    []Message `json:"messages"`
 	MaxTokens   int       `json:"max_tokens,omitempty"`
-	Temperature float64   `json:"temperature,omitempty"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	Stream      bool      `json:"stream,omitempty"`
+	Tools       []Tool    `json:"tools,omitempty"`
+	ToolChoice  string    `json:"tool_choice,omitempty"`
+
+	// Paramètres de génération facultatifs (nil = valeur par défaut du fournisseur)
+	TopP             *float64        `json:"top_p,omitempty"`
+	Stop             []string        `json:"stop,omitempty"`
+	Seed             *int            `json:"seed,omitempty"`
+	PresencePenalty  *float64        `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64        `json:"frequency_penalty,omitempty"`
+	ResponseFormat   *ResponseFormat `json:"response_format,omitempty"`
+
+	// Options du streaming (décompte des tokens en fin de flux)
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+}
+
+// ResponseFormat représente le format de réponse imposé au modèle
+// ("text", "json_object" ou "json_schema")
+type ResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *JSONSchemaFormat `json:"json_schema,omitempty"`
+}
+
+// JSONSchemaFormat représente le schéma imposé à une réponse "json_schema"
+type JSONSchemaFormat struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema,omitempty"`
+	Strict bool            `json:"strict,omitempty"`
+}
+
+// StreamOptions représente les options d'une requête en streaming
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 // Choice représente une réponse d'API
@@ -38,6 +214,23 @@ type ChatResponse struct {
 	Usage   Usage    `json:"usage"`
 }
 
+// StreamChoice représente un choix partiel reçu en mode streaming
+type StreamChoice struct {
+	Index        int     `json:"index"`
+	Delta        Message `json:"delta"`
+	FinishReason string  `json:"finish_reason"`
+}
+
+// ChatStreamChunk représente un fragment (événement "data:") d'une réponse en streaming
+type ChatStreamChunk struct {
+	ID      string         `json:"id"`
+	Object  string         `json:"object"`
+	Created int64          `json:"created"`
+	Model   string         `json:"model"`
+	Choices []StreamChoice `json:"choices"`
+	Usage   *Usage         `json:"usage,omitempty"`
+}
+
 // Model représente un modèle AI disponible
 type Model struct {
 	ID      string `json:"id"`
@@ -0,0 +1,196 @@
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Record représente la consommation d'un appel au modèle
+type Record struct {
+	Timestamp        time.Time `json:"timestamp"`
+	SessionID        string    `json:"session_id"`
+	Model            string    `json:"model"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	Cost             float64   `json:"cost"`
+
+	// Le fournisseur n'a pas indiqué sa consommation : les tokens sont estimés
+	Estimated bool `json:"estimated,omitempty"`
+}
+
+// Totals cumule la consommation d'un ensemble d'appels
+type Totals struct {
+	Calls            int
+	PromptTokens     int
+	CompletionTokens int
+	Cost             float64
+	Estimated        bool
+}
+
+// Tokens retourne le nombre total de tokens
+func (t Totals) Tokens() int {
+	return t.PromptTokens + t.CompletionTokens
+}
+
+// Add ajoute un appel au cumul
+func (t *Totals) Add(r Record) {
+	t.Calls++
+	t.PromptTokens += r.PromptTokens
+	t.CompletionTokens += r.CompletionTokens
+	t.Cost += r.Cost
+	t.Estimated = t.Estimated || r.Estimated
+}
+
+// Ledger tient le registre de consommation, enregistré au format JSON Lines
+type Ledger struct {
+	path    string
+	records []Record
+	mu      sync.Mutex
+}
+
+// DefaultPath retourne le chemin par défaut du registre de consommation
+func DefaultPath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = "/home/user"
+	}
+	return filepath.Join(homeDir, ".cline", "usage.jsonl")
+}
+
+// DefaultPricesPath retourne le chemin par défaut du fichier de tarifs
+func DefaultPricesPath() string {
+	return filepath.Join(filepath.Dir(DefaultPath()), "prices.json")
+}
+
+// OpenLedger charge le registre de consommation, en le créant si nécessaire
+func OpenLedger(path string) (*Ledger, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("impossible de créer le répertoire du registre: %w", err)
+	}
+
+	l := &Ledger{path: path}
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return l, nil
+		}
+		return nil, fmt.Errorf("erreur lors de la lecture du registre: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err == nil {
+			l.records = append(l.records, r)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("erreur lors de la lecture du registre: %w", err)
+	}
+	return l, nil
+}
+
+// Add enregistre un appel dans le registre
+func (l *Ledger) Add(r Record) error {
+	if r.Timestamp.IsZero() {
+		r.Timestamp = time.Now()
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("erreur lors de la sérialisation de la consommation: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("impossible d'ouvrir le registre: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("erreur lors de l'écriture du registre: %w", err)
+	}
+
+	l.records = append(l.records, r)
+	return nil
+}
+
+// Total cumule les appels retenus par le filtre (tous si filter est nil)
+func (l *Ledger) Total(filter func(Record) bool) Totals {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var t Totals
+	for _, r := range l.records {
+		if filter == nil || filter(r) {
+			t.Add(r)
+		}
+	}
+	return t
+}
+
+// Session cumule la consommation d'une session
+func (l *Ledger) Session(id string) Totals {
+	return l.Total(func(r Record) bool { return r.SessionID == id })
+}
+
+// Day cumule la consommation d'une journée
+func (l *Ledger) Day(day time.Time) Totals {
+	key := day.Format("2006-01-02")
+	return l.Total(func(r Record) bool { return r.Timestamp.Local().Format("2006-01-02") == key })
+}
+
+// Group représente un cumul identifié par une clé (modèle ou jour)
+type Group struct {
+	Key string
+	Totals
+}
+
+// ByModel cumule la consommation par modèle, du plus coûteux au moins coûteux
+func (l *Ledger) ByModel() []Group {
+	groups := l.groupBy(func(r Record) string { return r.Model })
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Cost != groups[j].Cost {
+			return groups[i].Cost > groups[j].Cost
+		}
+		return groups[i].Tokens() > groups[j].Tokens()
+	})
+	return groups
+}
+
+// ByDay cumule la consommation par jour, du plus récent au plus ancien
+func (l *Ledger) ByDay() []Group {
+	groups := l.groupBy(func(r Record) string { return r.Timestamp.Local().Format("2006-01-02") })
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Key > groups[j].Key
+	})
+	return groups
+}
+
+// groupBy cumule la consommation selon la clé retournée par key
+func (l *Ledger) groupBy(key func(Record) string) []Group {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	index := make(map[string]int)
+	var groups []Group
+	for _, r := range l.records {
+		k := key(r)
+		i, ok := index[k]
+		if !ok {
+			i = len(groups)
+			index[k] = i
+			groups = append(groups, Group{Key: k})
+		}
+		groups[i].Add(r)
+	}
+	return groups
+}
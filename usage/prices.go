@@ -0,0 +1,109 @@
+package usage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Price représente le tarif d'un modèle, en dollars par million de tokens
+type Price struct {
+	Prompt     float64 `json:"prompt"`
+	Completion float64 `json:"completion"`
+}
+
+// defaultPrices liste les tarifs publics connus. La clé est un fragment du nom
+// du modèle ; le fragment le plus long correspondant l'emporte.
+var defaultPrices = map[string]Price{
+	"gpt-4o":            {Prompt: 2.5, Completion: 10},
+	"gpt-4o-mini":       {Prompt: 0.15, Completion: 0.6},
+	"gpt-4.1":           {Prompt: 2, Completion: 8},
+	"gpt-4.1-mini":      {Prompt: 0.4, Completion: 1.6},
+	"gpt-4.1-nano":      {Prompt: 0.1, Completion: 0.4},
+	"gpt-4-turbo":       {Prompt: 10, Completion: 30},
+	"gpt-3.5-turbo":     {Prompt: 0.5, Completion: 1.5},
+	"claude-3-5-haiku":  {Prompt: 0.8, Completion: 4},
+	"claude-3-5-sonnet": {Prompt: 3, Completion: 15},
+	"claude-sonnet":     {Prompt: 3, Completion: 15},
+	"claude-opus":       {Prompt: 15, Completion: 75},
+}
+
+// PriceTable associe les modèles à leur tarif
+type PriceTable struct {
+	prices map[string]Price
+}
+
+// NewPriceTable crée une table de tarifs contenant les tarifs par défaut
+func NewPriceTable() *PriceTable {
+	prices := make(map[string]Price, len(defaultPrices))
+	for model, price := range defaultPrices {
+		prices[model] = price
+	}
+	return &PriceTable{prices: prices}
+}
+
+// LoadFile complète la table avec un fichier JSON de la forme
+// {"modèle": {"prompt": 0.5, "completion": 1.5}}. Un fichier absent n'est pas une erreur.
+func (t *PriceTable) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("erreur lors de la lecture des tarifs: %w", err)
+	}
+
+	var prices map[string]Price
+	if err := json.Unmarshal(data, &prices); err != nil {
+		return fmt.Errorf("erreur lors de la désérialisation des tarifs: %w", err)
+	}
+	for model, price := range prices {
+		t.Set(model, price)
+	}
+	return nil
+}
+
+// Set définit le tarif d'un modèle
+func (t *PriceTable) Set(model string, price Price) {
+	t.prices[strings.ToLower(model)] = price
+}
+
+// Lookup retourne le tarif d'un modèle, s'il est connu
+func (t *PriceTable) Lookup(model string) (Price, bool) {
+	model = strings.ToLower(model)
+	if price, ok := t.prices[model]; ok {
+		return price, true
+	}
+
+	best := ""
+	for pattern := range t.prices {
+		if strings.Contains(model, pattern) && len(pattern) > len(best) {
+			best = pattern
+		}
+	}
+	if best == "" {
+		return Price{}, false
+	}
+	return t.prices[best], true
+}
+
+// Cost calcule le coût d'un appel ; known est faux si le tarif du modèle est inconnu
+func (t *PriceTable) Cost(model string, promptTokens, completionTokens int) (cost float64, known bool) {
+	price, ok := t.Lookup(model)
+	if !ok {
+		return 0, false
+	}
+	return (float64(promptTokens)*price.Prompt + float64(completionTokens)*price.Completion) / 1e6, true
+}
+
+// Models retourne les modèles de la table, triés par nom
+func (t *PriceTable) Models() []string {
+	models := make([]string, 0, len(t.prices))
+	for model := range t.prices {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	return models
+}
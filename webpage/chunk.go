@@ -0,0 +1,282 @@
+package webpage
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DefaultChunkSize est la taille visée d'un extrait, en caractères
+const DefaultChunkSize = 1200
+
+// Chunk est un extrait du texte d'une page
+type Chunk struct {
+	// Indice de la page d'origine et position de l'extrait dans la page
+	Source   int
+	Position int
+
+	// Titre de la section dont l'extrait est tiré
+	Heading string
+
+	Text string
+
+	// Pertinence par rapport à la requête (calculée par Select)
+	Score float64
+}
+
+// Split découpe le texte markdown d'une page en extraits d'environ size
+// caractères, sans couper les paragraphes sauf s'ils dépassent cette taille
+func Split(source int, content string, size int) []Chunk {
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+
+	var chunks []Chunk
+	var current strings.Builder
+	heading, currentHeading := "", ""
+	add := func() {
+		if text := strings.TrimSpace(current.String()); text != "" {
+			chunks = append(chunks, Chunk{Source: source, Position: len(chunks), Heading: currentHeading, Text: text})
+		}
+		current.Reset()
+	}
+
+	for _, part := range splitBlocks(content) {
+		isHeading := strings.HasPrefix(part, "#") && strings.HasPrefix(strings.TrimLeft(part, "#"), " ")
+		if isHeading {
+			heading = strings.TrimSpace(strings.TrimLeft(part, "#"))
+		}
+		// Une nouvelle section commence de préférence un nouvel extrait
+		if current.Len() > 0 && (current.Len()+len(part) > size || isHeading && current.Len() > size/2) {
+			add()
+		}
+		if current.Len() == 0 {
+			currentHeading = heading
+		}
+		for len(part) > size {
+			cut := cutPoint(part, size)
+			current.WriteString(part[:cut])
+			add()
+			currentHeading = heading
+			part = strings.TrimSpace(part[cut:])
+		}
+		if current.Len() > 0 {
+			current.WriteString("\n\n")
+		}
+		current.WriteString(part)
+	}
+	add()
+	return chunks
+}
+
+// splitBlocks découpe un texte markdown en paragraphes ; les blocs de code
+// sont conservés entiers
+func splitBlocks(content string) []string {
+	var blocks []string
+	var code []string
+	for _, part := range strings.Split(content, "\n\n") {
+		if code != nil {
+			code = append(code, part)
+			if strings.Count(part, "```")%2 == 1 {
+				blocks = append(blocks, strings.Join(code, "\n\n"))
+				code = nil
+			}
+			continue
+		}
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		if strings.Count(part, "```")%2 == 1 {
+			code = []string{part}
+			continue
+		}
+		blocks = append(blocks, part)
+	}
+	if code != nil {
+		blocks = append(blocks, strings.Join(code, "\n\n"))
+	}
+	return blocks
+}
+
+// cutPoint retourne la position où couper un texte trop long : la dernière fin
+// de ligne ou de phrase, à défaut le dernier espace, avant size
+func cutPoint(text string, size int) int {
+	for _, sep := range []string{"\n", ". ", " "} {
+		if i := strings.LastIndex(text[:size], sep); i > size/2 {
+			return i + len(sep)
+		}
+	}
+	// Ne pas couper au milieu d'un caractère
+	for size > 0 && !isRuneStart(text[size]) {
+		size--
+	}
+	return size
+}
+
+// isRuneStart indique si un octet commence un caractère UTF-8
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// Paramètres du classement BM25
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Select retourne les extraits les plus pertinents pour la requête, au plus max
+// au total et perSource par page, dans l'ordre des pages puis du texte. Les
+// extraits qui ne contiennent aucun terme de la requête sont écartés ; si aucun
+// n'en contient, les premiers extraits de chaque page sont retenus.
+func Select(query string, chunks []Chunk, max, perSource int) []Chunk {
+	terms := Terms(query)
+	docs := make([][]string, len(chunks))
+	totalLen := 0
+	for i, c := range chunks {
+		docs[i] = Terms(c.Heading + " " + c.Text)
+		totalLen += len(docs[i])
+	}
+	avgLen := 1.0
+	if len(chunks) > 0 && totalLen > 0 {
+		avgLen = float64(totalLen) / float64(len(chunks))
+	}
+
+	// Fréquence documentaire de chaque terme de la requête
+	df := make(map[string]int)
+	for _, doc := range docs {
+		seen := make(map[string]bool)
+		for _, t := range doc {
+			seen[t] = true
+		}
+		for _, t := range terms {
+			if seen[t] {
+				df[t]++
+			}
+		}
+	}
+
+	var scored, unmatched []Chunk
+	for i, c := range chunks {
+		tf := make(map[string]int)
+		for _, t := range docs[i] {
+			tf[t]++
+		}
+		score := 0.0
+		matched := false
+		for _, t := range terms {
+			if tf[t] == 0 {
+				continue
+			}
+			matched = true
+			idf := math.Log(1 + (float64(len(chunks)-df[t])+0.5)/(float64(df[t])+0.5))
+			norm := float64(tf[t]) * (bm25K1 + 1) /
+				(float64(tf[t]) + bm25K1*(1-bm25B+bm25B*float64(len(docs[i]))/avgLen))
+			score += idf * norm
+		}
+		// À pertinence égale, le début d'une page la résume mieux
+		score += 0.1 / float64(c.Position+1)
+		c.Score = score
+		if matched || len(terms) == 0 {
+			scored = append(scored, c)
+		} else {
+			unmatched = append(unmatched, c)
+		}
+	}
+	// Les extraits sans rapport avec la requête ne servent que si aucun ne s'y rapporte
+	if len(scored) == 0 {
+		scored = unmatched
+	}
+
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].Score > scored[j].Score
+	})
+
+	var selected []Chunk
+	perPage := make(map[int]int)
+	for _, c := range scored {
+		if len(selected) >= max {
+			break
+		}
+		if perSource > 0 && perPage[c.Source] >= perSource {
+			continue
+		}
+		perPage[c.Source]++
+		selected = append(selected, c)
+	}
+
+	sort.SliceStable(selected, func(i, j int) bool {
+		if selected[i].Source != selected[j].Source {
+			return selected[i].Source < selected[j].Source
+		}
+		return selected[i].Position < selected[j].Position
+	})
+	return selected
+}
+
+// stopWords liste les mots vides ignorés par le classement (français et anglais)
+var stopWords = map[string]bool{
+	"le": true, "la": true, "les": true, "un": true, "une": true, "des": true, "du": true,
+	"de": true, "et": true, "ou": true, "en": true, "au": true, "aux": true, "ce": true,
+	"ces": true, "est": true, "sont": true, "pour": true, "par": true, "sur": true,
+	"dans": true, "avec": true, "que": true, "qui": true, "quoi": true, "comment": true,
+	"quel": true, "quelle": true, "quels": true, "quelles": true, "pas": true, "plus": true,
+	"il": true, "elle": true, "je": true, "tu": true, "nous": true, "vous": true, "mon": true,
+	"ma": true, "mes": true, "son": true, "sa": true, "ses": true, "the": true, "and": true,
+	"or": true, "of": true, "to": true, "in": true, "on": true, "for": true, "with": true,
+	"is": true, "are": true, "what": true, "how": true, "an": true, "it": true, "this": true,
+	"that": true, "be": true, "by": true, "from": true, "as": true, "at": true,
+}
+
+// accents associe les lettres accentuées à leur forme simple
+var accents = strings.NewReplacer(
+	"à", "a", "â", "a", "ä", "a", "é", "e", "è", "e", "ê", "e", "ë", "e",
+	"î", "i", "ï", "i", "ô", "o", "ö", "o", "ù", "u", "û", "u", "ü", "u",
+	"ç", "c", "ÿ", "y", "œ", "oe",
+)
+
+// Terms découpe un texte en termes normalisés : minuscules sans accents, mots
+// vides retirés et pluriels simples ramenés au singulier
+func Terms(text string) []string {
+	text = accents.Replace(strings.ToLower(text))
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(words))
+	for _, w := range words {
+		if len(w) < 2 || stopWords[w] {
+			continue
+		}
+		if len(w) > 3 && (strings.HasSuffix(w, "s") || strings.HasSuffix(w, "x")) && !strings.HasSuffix(w, "ss") {
+			w = w[:len(w)-1]
+		}
+		terms = append(terms, w)
+	}
+	return terms
+}
+
+// FormatExcerpts formate les extraits retenus en citant leur source : chaque
+// extrait est précédé du numéro de la page, de son titre et de son adresse
+func FormatExcerpts(pages []*Page, chunks []Chunk) string {
+	if len(chunks) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Extraits des pages consultées:\n")
+	for _, c := range chunks {
+		page := pages[c.Source]
+		if page.Title != "" {
+			sb.WriteString(fmt.Sprintf("\n[%d] %s (%s)", c.Source+1, page.Title, page.URL))
+		} else {
+			sb.WriteString(fmt.Sprintf("\n[%d] %s", c.Source+1, page.URL))
+		}
+		if c.Heading != "" && c.Heading != page.Title && !strings.HasPrefix(c.Text, "#") {
+			sb.WriteString(" – " + c.Heading)
+		}
+		sb.WriteString("\n" + c.Text + "\n")
+	}
+	return sb.String()
+}
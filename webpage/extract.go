@@ -0,0 +1,576 @@
+package webpage
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// minScopedContent est la taille en dessous de laquelle le contenu trouvé dans
+// <main> ou <article> est jugé incomplet ; toute la page est alors reprise
+const minScopedContent = 250
+
+// maxLinkDensity est la part maximale de texte de liens d'un bloc conservé
+// (au-delà, il s'agit d'un menu ou d'une liste de liens)
+const maxLinkDensity = 0.5
+
+// Éléments dont le contenu n'est jamais du texte lisible
+var skippedTags = map[string]bool{
+	"title": true, "script": true, "style": true, "noscript": true, "template": true,
+	"svg": true, "canvas": true, "iframe": true, "object": true, "embed": true,
+	"form": true, "button": true, "select": true, "textarea": true,
+	"nav": true, "footer": true, "aside": true, "menu": true, "dialog": true,
+}
+
+// Éléments sans balise fermante
+var voidTags = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true,
+	"img": true, "input": true, "link": true, "meta": true, "source": true,
+	"track": true, "wbr": true,
+}
+
+// Éléments dont le contenu brut s'étend jusqu'à la balise fermante
+var rawTextTags = map[string]bool{
+	"script": true, "style": true, "textarea": true, "title": true,
+}
+
+// Éléments qui délimitent un bloc de texte
+var blockTags = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "main": true,
+	"header": true, "ul": true, "ol": true, "dl": true, "dt": true, "dd": true,
+	"table": true, "thead": true, "tbody": true, "figure": true, "figcaption": true,
+	"details": true, "summary": true, "address": true, "hr": true, "body": true,
+}
+
+// Rôles ARIA des zones de navigation et d'habillage du site
+var skippedRoles = map[string]bool{
+	"navigation": true, "banner": true, "contentinfo": true, "complementary": true,
+	"search": true, "dialog": true, "menu": true, "menubar": true, "alert": true,
+}
+
+var (
+	// Classes et identifiants des zones d'habillage (menus, bandeaux, partage...)
+	boilerplatePattern = regexp.MustCompile(`(?i)\b(nav|navbar|navigation|menu|footer|sidebar|breadcrumbs?|cookies?|consent|banner|share|sharing|social|comments?|related|newsletter|subscribe|popup|modal|advert|ads|promo|sponsored|toolbar|skip-link)\b`)
+
+	// Classes et identifiants qui désignent probablement le contenu principal
+	contentPattern = regexp.MustCompile(`(?i)article|body|content|main|post|entry|text`)
+
+	// Attributs d'une balise
+	attrPattern = regexp.MustCompile(`([a-zA-Z_:][-a-zA-Z0-9_:.]*)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'=<>` + "`" + `]+)))?`)
+
+	// Langage d'un bloc de code (class="language-go", "lang-go")
+	languagePattern = regexp.MustCompile(`(?:^|\s)(?:language|lang)-([\w+#-]+)`)
+)
+
+// Extract retourne le titre et le texte principal d'une page HTML, en markdown.
+// Les zones d'habillage (navigation, pied de page, bandeaux...) sont écartées,
+// ainsi que les blocs composés surtout de liens ; si la page balise son
+// contenu principal (<main>, <article>), seul celui-ci est repris.
+func Extract(page string) (title, content string) {
+	tokens := tokenize(page)
+	for i, tok := range tokens {
+		if tok.kind == startToken && tok.name == "title" && i+1 < len(tokens) && tokens[i+1].kind == textToken {
+			title = collapse(html.UnescapeString(tokens[i+1].text))
+			break
+		}
+	}
+
+	content = render(extractBlocks(tokens, true))
+	if len(content) < minScopedContent {
+		if whole := render(extractBlocks(tokens, false)); len(whole) > len(content) {
+			content = whole
+		}
+	}
+	return title, content
+}
+
+// Types de jetons HTML
+const (
+	textToken = iota
+	startToken
+	endToken
+)
+
+// token est un élément de la page : texte, balise ouvrante ou fermante
+type token struct {
+	kind  int
+	name  string
+	attrs map[string]string
+	text  string
+}
+
+// tokenize découpe une page HTML en jetons ; les commentaires et déclarations
+// sont ignorés
+func tokenize(page string) []token {
+	var tokens []token
+	for i := 0; i < len(page); {
+		if page[i] != '<' {
+			end := strings.IndexByte(page[i:], '<')
+			if end < 0 {
+				end = len(page) - i
+			}
+			tokens = append(tokens, token{kind: textToken, text: page[i : i+end]})
+			i += end
+			continue
+		}
+
+		rest := page[i:]
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest, "-->")
+			if end < 0 {
+				return tokens
+			}
+			i += end + 3
+			continue
+		case strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?"):
+			end := strings.IndexByte(rest, '>')
+			if end < 0 {
+				return tokens
+			}
+			i += end + 1
+			continue
+		}
+
+		closing := strings.HasPrefix(rest, "</")
+		nameStart := 1
+		if closing {
+			nameStart = 2
+		}
+		nameEnd := nameStart
+		for nameEnd < len(rest) && isNameChar(rest[nameEnd]) {
+			nameEnd++
+		}
+		if nameEnd == nameStart {
+			// « < » isolé : du texte
+			tokens = append(tokens, token{kind: textToken, text: "<"})
+			i++
+			continue
+		}
+		end := tagEnd(rest)
+		if end < 0 {
+			return tokens
+		}
+		name := strings.ToLower(rest[nameStart:nameEnd])
+		i += end + 1
+
+		if closing {
+			tokens = append(tokens, token{kind: endToken, name: name})
+			continue
+		}
+		tokens = append(tokens, token{kind: startToken, name: name, attrs: parseAttrs(rest[nameEnd:end])})
+
+		// Le contenu des éléments de texte brut n'est pas du HTML
+		if rawTextTags[name] {
+			closeAt := strings.Index(strings.ToLower(page[i:]), "</"+name)
+			if closeAt < 0 {
+				closeAt = len(page) - i
+			}
+			tokens = append(tokens, token{kind: textToken, text: page[i : i+closeAt]})
+			i += closeAt
+		}
+	}
+	return tokens
+}
+
+// isNameChar indique si un octet peut faire partie d'un nom de balise
+func isNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == ':'
+}
+
+// tagEnd retourne la position du « > » qui ferme une balise, en tenant compte
+// des valeurs d'attributs entre guillemets
+func tagEnd(tag string) int {
+	var quote byte
+	for i := 1; i < len(tag); i++ {
+		c := tag[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return i
+		}
+	}
+	return -1
+}
+
+// parseAttrs décode les attributs d'une balise
+func parseAttrs(raw string) map[string]string {
+	raw = strings.TrimSuffix(strings.TrimSpace(raw), "/")
+	if raw == "" {
+		return nil
+	}
+	attrs := make(map[string]string)
+	for _, m := range attrPattern.FindAllStringSubmatch(raw, -1) {
+		attrs[strings.ToLower(m[1])] = html.UnescapeString(m[2] + m[3] + m[4])
+	}
+	return attrs
+}
+
+// Types de blocs du texte extrait
+const (
+	paragraphBlock = iota
+	headingBlock
+	itemBlock
+	codeBlock
+	quoteBlock
+	rowBlock
+)
+
+// block est un bloc de texte extrait de la page
+type block struct {
+	kind  int
+	level int // niveau de titre ou profondeur de liste
+	label string
+	lang  string
+
+	text     strings.Builder
+	linkText int
+}
+
+// extractor parcourt les jetons et en tire les blocs de texte lisible
+type extractor struct {
+	blocks []*block
+	cur    *block
+
+	// Élément écarté en cours et profondeur d'imbrication des éléments de même nom
+	skipName  string
+	skipDepth int
+
+	// Élément de contenu principal en cours (si scoped)
+	scopeName  string
+	scopeDepth int
+	scoped     bool
+
+	links  int
+	pre    int
+	quotes int
+	cells  int
+
+	// Compteurs des listes ouvertes (-1 pour une liste non numérotée)
+	lists []int
+}
+
+// extractBlocks extrait les blocs de texte ; si scoped, seuls ceux des éléments
+// de contenu principal sont retenus
+func extractBlocks(tokens []token, scoped bool) []*block {
+	e := &extractor{scoped: scoped}
+	for _, tok := range tokens {
+		e.handle(tok)
+	}
+	e.flush()
+	return e.blocks
+}
+
+// handle traite un jeton
+func (e *extractor) handle(tok token) {
+	if e.skipName != "" {
+		if tok.name == e.skipName && !voidTags[tok.name] {
+			if tok.kind == startToken {
+				e.skipDepth++
+			} else if tok.kind == endToken {
+				e.skipDepth--
+				if e.skipDepth == 0 {
+					e.skipName = ""
+				}
+			}
+		}
+		return
+	}
+
+	switch tok.kind {
+	case textToken:
+		e.text(tok.text)
+	case startToken:
+		if isSkipped(tok) {
+			if !voidTags[tok.name] {
+				e.skipName, e.skipDepth = tok.name, 1
+			}
+			return
+		}
+		e.start(tok)
+	case endToken:
+		e.end(tok.name)
+	}
+}
+
+// isSkipped indique si un élément fait partie de l'habillage de la page
+func isSkipped(tok token) bool {
+	if skippedTags[tok.name] {
+		return true
+	}
+	if _, hidden := tok.attrs["hidden"]; hidden || tok.attrs["aria-hidden"] == "true" {
+		return true
+	}
+	if skippedRoles[tok.attrs["role"]] {
+		return true
+	}
+	if isScope(tok) || tok.name == "html" || tok.name == "body" {
+		return false
+	}
+	marker := tok.attrs["class"] + " " + tok.attrs["id"]
+	return boilerplatePattern.MatchString(marker) && !contentPattern.MatchString(marker)
+}
+
+// isScope indique si un élément balise le contenu principal de la page
+func isScope(tok token) bool {
+	return tok.name == "main" || tok.name == "article" || tok.attrs["role"] == "main"
+}
+
+// inScope indique si le texte courant doit être retenu
+func (e *extractor) inScope() bool {
+	return !e.scoped || e.scopeName != ""
+}
+
+// start traite une balise ouvrante
+func (e *extractor) start(tok token) {
+	if e.scoped && !voidTags[tok.name] {
+		if e.scopeName == "" && isScope(tok) {
+			e.scopeName, e.scopeDepth = tok.name, 1
+		} else if tok.name == e.scopeName {
+			e.scopeDepth++
+		}
+	}
+
+	switch tok.name {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		e.begin(headingBlock, int(tok.name[1]-'0'))
+	case "li":
+		e.begin(itemBlock, len(e.lists))
+		if n := len(e.lists); n > 0 && e.lists[n-1] >= 0 {
+			e.lists[n-1]++
+			e.cur.label = strconv.Itoa(e.lists[n-1]) + "."
+		}
+	case "ul", "ol":
+		e.flush()
+		if tok.name == "ol" {
+			e.lists = append(e.lists, 0)
+		} else {
+			e.lists = append(e.lists, -1)
+		}
+	case "pre":
+		e.begin(codeBlock, 0)
+		e.pre++
+		e.cur.lang = language(tok.attrs["class"])
+	case "code":
+		if e.pre > 0 {
+			if e.cur != nil && e.cur.lang == "" {
+				e.cur.lang = language(tok.attrs["class"])
+			}
+		} else {
+			e.text("`")
+		}
+	case "blockquote":
+		e.flush()
+		e.quotes++
+	case "tr":
+		e.begin(rowBlock, 0)
+		e.cells = 0
+	case "td", "th":
+		if e.cells > 0 {
+			e.text(" | ")
+		}
+		e.cells++
+	case "a":
+		e.links++
+	case "br":
+		if e.pre > 0 {
+			e.text("\n")
+		} else {
+			e.text(" ")
+		}
+	default:
+		// Un paragraphe au début d'un élément de liste en fait partie
+		if blockTags[tok.name] && e.started() {
+			e.flush()
+		}
+	}
+}
+
+// end traite une balise fermante
+func (e *extractor) end(name string) {
+	switch name {
+	case "h1", "h2", "h3", "h4", "h5", "h6", "li", "tr":
+		e.flush()
+	case "ul", "ol":
+		e.flush()
+		if n := len(e.lists); n > 0 {
+			e.lists = e.lists[:n-1]
+		}
+	case "pre":
+		e.flush()
+		if e.pre > 0 {
+			e.pre--
+		}
+	case "code":
+		if e.pre == 0 {
+			e.text("`")
+		}
+	case "blockquote":
+		e.flush()
+		if e.quotes > 0 {
+			e.quotes--
+		}
+	case "a":
+		if e.links > 0 {
+			e.links--
+		}
+	default:
+		if blockTags[name] {
+			e.flush()
+		}
+	}
+
+	if name == e.scopeName {
+		e.scopeDepth--
+		if e.scopeDepth == 0 {
+			e.flush()
+			e.scopeName = ""
+		}
+	}
+}
+
+// started indique si le bloc courant contient déjà du texte
+func (e *extractor) started() bool {
+	return e.cur != nil && strings.TrimSpace(e.cur.text.String()) != ""
+}
+
+// begin commence un nouveau bloc
+func (e *extractor) begin(kind, level int) {
+	e.flush()
+	e.cur = &block{kind: kind, level: level}
+}
+
+// text ajoute du texte au bloc courant
+func (e *extractor) text(raw string) {
+	if !e.inScope() {
+		return
+	}
+	if e.cur == nil {
+		kind := paragraphBlock
+		if e.quotes > 0 {
+			kind = quoteBlock
+		}
+		e.cur = &block{kind: kind}
+	}
+	text := html.UnescapeString(raw)
+	e.cur.text.WriteString(text)
+	if e.links > 0 {
+		e.cur.linkText += len(collapse(text))
+	}
+}
+
+// flush termine le bloc courant et le conserve s'il est lisible
+func (e *extractor) flush() {
+	b := e.cur
+	e.cur = nil
+	if b == nil {
+		return
+	}
+
+	if b.kind == codeBlock {
+		if strings.TrimSpace(b.text.String()) != "" {
+			e.blocks = append(e.blocks, b)
+		}
+		return
+	}
+
+	text := collapse(b.text.String())
+	if text == "" || text == "``" {
+		return
+	}
+	// Les menus et listes de liens ne sont pas du contenu ; les titres sont
+	// souvent des liens vers leur propre ancre
+	if b.kind != headingBlock && float64(b.linkText) > maxLinkDensity*float64(len(text)) {
+		return
+	}
+	// Les fragments isolés (« Partager », « Lire la suite ») non plus
+	if b.kind == paragraphBlock && len(strings.Fields(text)) < 3 {
+		return
+	}
+	if b.kind == headingBlock {
+		// Liens d'ancre ajoutés aux titres par les générateurs de documentation
+		text = strings.TrimRight(text, " ¶#§")
+	}
+	b.text.Reset()
+	b.text.WriteString(text)
+	e.blocks = append(e.blocks, b)
+}
+
+// render assemble les blocs en markdown ; les titres sans contenu sont omis
+func render(blocks []*block) string {
+	var sb strings.Builder
+	prev := -1
+	for i, b := range blocks {
+		if b.kind == headingBlock && !hasContent(blocks[i+1:], b.level) {
+			continue
+		}
+
+		var line string
+		text := b.text.String()
+		switch b.kind {
+		case headingBlock:
+			line = strings.Repeat("#", b.level) + " " + text
+		case itemBlock:
+			label := b.label
+			if label == "" {
+				label = "-"
+			}
+			indent := 0
+			if b.level > 1 {
+				indent = b.level - 1
+			}
+			line = strings.Repeat("  ", indent) + label + " " + text
+		case codeBlock:
+			line = "```" + b.lang + "\n" + strings.Trim(text, "\n") + "\n```"
+		case quoteBlock:
+			line = "> " + text
+		default:
+			line = text
+		}
+
+		if sb.Len() > 0 {
+			// Les éléments d'une liste et les lignes d'un tableau se suivent sans ligne vide
+			if (b.kind == itemBlock || b.kind == rowBlock) && prev == b.kind {
+				sb.WriteString("\n")
+			} else {
+				sb.WriteString("\n\n")
+			}
+		}
+		sb.WriteString(line)
+		prev = b.kind
+	}
+	return sb.String()
+}
+
+// hasContent indique si un titre de niveau level est suivi de contenu avant le
+// prochain titre de même niveau ou de niveau supérieur
+func hasContent(blocks []*block, level int) bool {
+	for _, b := range blocks {
+		if b.kind != headingBlock {
+			return true
+		}
+		if b.level <= level {
+			return false
+		}
+	}
+	return false
+}
+
+// language retourne le langage indiqué par la classe d'un bloc de code
+func language(class string) string {
+	if m := languagePattern.FindStringSubmatch(class); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// collapse normalise les espaces d'un texte
+func collapse(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
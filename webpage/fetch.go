@@ -0,0 +1,302 @@
+package webpage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
+)
+
+// Valeurs par défaut du téléchargement des pages
+const (
+	// DefaultTimeout limite la durée du téléchargement d'une page
+	DefaultTimeout = 10 * time.Second
+
+	// DefaultMaxSize limite la taille lue d'une page ; au-delà, la page est tronquée
+	DefaultMaxSize = 2 * 1024 * 1024
+
+	// DefaultConcurrency est le nombre de pages téléchargées en parallèle
+	DefaultConcurrency = 4
+)
+
+// UserAgent identifie l'agent auprès des sites consultés, pour que leurs
+// administrateurs puissent le reconnaître (et le filtrer dans robots.txt)
+const UserAgent = "ASIONE-Agent/1.0 (+https://github.com/ai-terminal-assistant/ai-terminal-assistant)"
+
+// maxRedirects est le nombre maximal de redirections suivies
+const maxRedirects = 10
+
+// blockedNetworks liste les plages d'adresses internes que les pages ne peuvent
+// pas désigner : réseau local, boucle locale, adresses de lien local (dont le
+// service de métadonnées des hébergeurs, 169.254.169.254) et réseaux privés
+var blockedNetworks = parseNetworks(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"::1/128",
+	"fc00::/7",
+	"fe80::/10",
+)
+
+// parseNetworks analyse une liste de plages d'adresses
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// isBlockedIP indique si l'adresse est interne
+func isBlockedIP(ip net.IP) bool {
+	if ip.IsUnspecified() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsMulticast() {
+		return true
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// transport est le transport utilisé par défaut pour télécharger les pages :
+// son dialer refuse les adresses internes
+var transport = newTransport()
+
+// newTransport crée un transport qui vérifie l'adresse effectivement contactée,
+// après résolution DNS : la vérification couvre ainsi les redirections, les
+// nouvelles tentatives et une résolution qui change entre deux requêtes. Aucun
+// proxy n'est utilisé, car c'est alors lui qui serait contacté et vérifié.
+func newTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   dialControl,
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = nil
+	t.DialContext = dialer.DialContext
+	return t
+}
+
+// dialControl refuse une connexion vers une adresse interne
+func dialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("adresse invalide: %s", address)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || isBlockedIP(ip) {
+		return fmt.Errorf("adresse interne refusée: %s", host)
+	}
+	return nil
+}
+
+// checkRedirect limite le nombre de redirections et les restreint à http et https
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("trop de redirections (%d)", len(via))
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return fmt.Errorf("redirection non prise en charge: %s", req.URL)
+	}
+	return nil
+}
+
+// Page représente une page téléchargée et son contenu lisible
+type Page struct {
+	// Adresse demandée et adresse finale après redirections
+	URL      string
+	FinalURL string
+
+	Title string
+
+	// Texte principal de la page, en markdown
+	Content string
+
+	// Taille lue en octets et troncature à la taille maximale
+	Size      int
+	Truncated bool
+
+	// Erreur de téléchargement ou d'extraction (Content est alors vide)
+	Err error
+}
+
+// Fetcher télécharge des pages et en extrait le texte principal
+type Fetcher struct {
+	// Client HTTP ; sans Transport, celui du paquet, qui refuse les adresses internes
+	Client *http.Client
+
+	UserAgent   string
+	MaxSize     int
+	Timeout     time.Duration
+	Concurrency int
+}
+
+// NewFetcher crée un Fetcher avec les valeurs par défaut
+func NewFetcher() *Fetcher {
+	return &Fetcher{
+		Client:      &http.Client{},
+		UserAgent:   UserAgent,
+		MaxSize:     DefaultMaxSize,
+		Timeout:     DefaultTimeout,
+		Concurrency: DefaultConcurrency,
+	}
+}
+
+// FetchAll télécharge les pages en parallèle ; le résultat suit l'ordre des
+// adresses et chaque page porte sa propre erreur éventuelle
+func (f *Fetcher) FetchAll(ctx context.Context, urls []string) []*Page {
+	pages := make([]*Page, len(urls))
+	concurrency := f.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	slots := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		wg.Add(1)
+		go func(i int, u string) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			page, err := f.Fetch(ctx, u)
+			if err != nil {
+				page = &Page{URL: u, Err: err}
+			}
+			pages[i] = page
+		}(i, u)
+	}
+	wg.Wait()
+	return pages
+}
+
+// Fetch télécharge une page et en extrait le texte principal. Les adresses
+// internes (réseau local, boucle locale, lien local) sont refusées, y compris
+// à la suite d'une redirection.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Page, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("adresse non prise en charge: %s", rawURL)
+	}
+
+	if f.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("erreur lors de la création de la requête: %w", err)
+	}
+	req.Header.Set("User-Agent", f.UserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,text/plain;q=0.9,*/*;q=0.1")
+
+	// Copie du client pour vérifier les redirections sans modifier celui fourni
+	client := http.Client{}
+	if f.Client != nil {
+		client = *f.Client
+	}
+	if client.Transport == nil {
+		client.Transport = transport
+	}
+	client.CheckRedirect = checkRedirect
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("erreur lors du téléchargement: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("erreur HTTP: %s (code: %d)", http.StatusText(resp.StatusCode), resp.StatusCode)
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "" {
+		mediaType = "text/html"
+	}
+	isHTML := mediaType == "text/html" || mediaType == "application/xhtml+xml"
+	if !isHTML && mediaType != "text/plain" && mediaType != "text/markdown" {
+		return nil, fmt.Errorf("type de contenu non pris en charge: %s", mediaType)
+	}
+
+	maxSize := f.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxSize)+1))
+	if err != nil {
+		return nil, fmt.Errorf("erreur lors de la lecture de la page: %w", err)
+	}
+
+	page := &Page{URL: rawURL, FinalURL: resp.Request.URL.String(), Size: len(body)}
+	if len(body) > maxSize {
+		body = body[:maxSize]
+		page.Size = maxSize
+		page.Truncated = true
+	}
+
+	text := decodeText(body, params["charset"])
+	if isHTML {
+		page.Title, page.Content = Extract(text)
+	} else {
+		page.Content = strings.TrimSpace(text)
+	}
+	if page.Content == "" {
+		return nil, fmt.Errorf("aucun texte lisible dans la page")
+	}
+	return page, nil
+}
+
+// metaCharsetPattern reconnaît le jeu de caractères déclaré dans l'en-tête d'une page
+var metaCharsetPattern = regexp.MustCompile(`(?i)<meta[^>]+charset=["']?([-\w]+)`)
+
+// decodeText convertit le corps d'une page en UTF-8. Seuls les jeux Latin-1
+// sont convertis ; les autres octets invalides sont remplacés.
+func decodeText(body []byte, charset string) string {
+	if charset == "" {
+		head := body
+		if len(head) > 2048 {
+			head = head[:2048]
+		}
+		if m := metaCharsetPattern.FindSubmatch(head); m != nil {
+			charset = string(m[1])
+		}
+	}
+
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "iso-8859-15", "latin1", "windows-1252", "cp1252":
+		if !utf8.Valid(body) {
+			runes := make([]rune, len(body))
+			for i, b := range body {
+				runes[i] = rune(b)
+			}
+			return string(runes)
+		}
+	}
+	return string(bytes.ToValidUTF8(body, []byte("�")))
+}
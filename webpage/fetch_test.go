@@ -0,0 +1,143 @@
+package webpage
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestIsBlockedIP(t *testing.T) {
+	tests := []struct {
+		ip      string
+		blocked bool
+	}{
+		{"127.0.0.1", true},
+		{"127.8.9.10", true},
+		{"::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"0.0.0.0", true},
+		{"::", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"172.31.255.255", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.64.0.1", true},
+		{"fd00::1", true},
+		{"fe80::1", true},
+		{"224.0.0.1", true},
+		{"172.32.0.1", false},
+		{"93.184.216.34", false},
+		{"8.8.8.8", false},
+		{"2606:4700:4700::1111", false},
+	}
+
+	for _, tt := range tests {
+		if got := isBlockedIP(net.ParseIP(tt.ip)); got != tt.blocked {
+			t.Errorf("isBlockedIP(%s) = %v, %v attendu", tt.ip, got, tt.blocked)
+		}
+	}
+}
+
+func TestFetchRejectsInternalAddresses(t *testing.T) {
+	// Le serveur de test écoute sur la boucle locale : il ne doit jamais être contacté
+	contacted := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contacted = true
+		w.Write([]byte("<html><body><p>secret</p></body></html>"))
+	}))
+	t.Cleanup(srv.Close)
+
+	urls := []string{
+		srv.URL,
+		"http://169.254.169.254/latest/meta-data/",
+		"http://10.0.0.1/",
+		"http://[::1]:8080/",
+		"http://localhost/",
+	}
+	for _, u := range urls {
+		_, err := NewFetcher().Fetch(context.Background(), u)
+		if err == nil || !strings.Contains(err.Error(), "adresse interne refusée") {
+			t.Errorf("%s : erreur = %v", u, err)
+		}
+	}
+	if contacted {
+		t.Error("le serveur local a été contacté")
+	}
+}
+
+func TestDialControl(t *testing.T) {
+	// Le dialer vérifie l'adresse résolue, quel que soit le nom demandé
+	for _, address := range []string{"127.0.0.1:80", "[::1]:443", "169.254.169.254:80", "10.0.0.1:8080", "[::ffff:192.168.1.1]:80"} {
+		if err := dialControl("tcp", address, nil); err == nil || !strings.Contains(err.Error(), "adresse interne refusée") {
+			t.Errorf("%s : erreur = %v", address, err)
+		}
+	}
+	for _, address := range []string{"93.184.216.34:443", "[2606:4700:4700::1111]:80"} {
+		if err := dialControl("tcp", address, nil); err != nil {
+			t.Errorf("%s : erreur inattendue %v", address, err)
+		}
+	}
+}
+
+func TestFetchRedirectToInternalAddress(t *testing.T) {
+	// La redirection est suivie, mais la connexion vers l'adresse interne est refusée
+	// par le dialer. Le premier serveur est joint par un transport sans vérification.
+	contacted := false
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contacted = true
+	}))
+	t.Cleanup(internal.Close)
+	redirecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL, http.StatusFound)
+	}))
+	t.Cleanup(redirecting.Close)
+
+	f := NewFetcher()
+	f.Client = &http.Client{Transport: &redirectTransport{first: redirecting.URL}}
+	_, err := f.Fetch(context.Background(), redirecting.URL)
+	if err == nil || !strings.Contains(err.Error(), "adresse interne refusée") {
+		t.Errorf("erreur = %v", err)
+	}
+	if contacted {
+		t.Error("le serveur interne a été contacté")
+	}
+}
+
+// redirectTransport envoie la première requête sans vérification, puis les
+// suivantes par le transport du paquet
+type redirectTransport struct {
+	first string
+}
+
+func (rt *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if strings.TrimSuffix(req.URL.String(), "/") == rt.first {
+		return http.DefaultTransport.RoundTrip(req)
+	}
+	return transport.RoundTrip(req)
+}
+
+func TestCheckRedirect(t *testing.T) {
+	redirect := func(rawURL string) *http.Request {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return (&http.Request{URL: u}).WithContext(context.Background())
+	}
+	via := []*http.Request{redirect("https://93.184.216.34/")}
+
+	if err := checkRedirect(redirect("file:///etc/passwd"), via); err == nil {
+		t.Error("redirection vers file: acceptée")
+	}
+	if err := checkRedirect(redirect("https://93.184.216.34/page"), via); err != nil {
+		t.Errorf("redirection publique refusée : %v", err)
+	}
+	if err := checkRedirect(redirect("https://93.184.216.34/page"), make([]*http.Request, maxRedirects)); err == nil {
+		t.Error("nombre de redirections non limité")
+	}
+}